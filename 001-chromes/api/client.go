@@ -0,0 +1,205 @@
+package api
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "net/url"
+
+    "chromes/config"
+)
+
+// Client 是控制接口的客户端，供启动器、编辑器插件和脚本等其他程序控制正在运行的管理器。
+// 可以被多个 goroutine 同时使用。
+type Client struct {
+    http *http.Client
+}
+
+// NewClient 创建连接到 socketPath 的客户端，socketPath 为空时使用 SocketPath()。
+// 创建时不会建立连接，管理器没有运行时各个方法返回连接错误。
+func NewClient(socketPath string) *Client {
+    if socketPath == "" {
+        socketPath = SocketPath()
+    }
+    transport := &http.Transport{
+        DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+            var dialer net.Dialer
+            return dialer.DialContext(ctx, "unix", socketPath)
+        },
+    }
+    return &Client{http: &http.Client{Transport: transport}}
+}
+
+// do 发送请求，把 body 编码为 JSON 作为请求体（nil 时没有请求体），并把响应解码到 result（nil 时忽略）。
+func (c *Client) do(ctx context.Context, method string, path string, body any, result any) error {
+    var reader io.Reader
+    if body != nil {
+        data, err := json.Marshal(body)
+        if err != nil {
+            return err
+        }
+        reader = bytes.NewReader(data)
+    }
+    req, err := http.NewRequestWithContext(ctx, method, "http://chromes"+path, reader)
+    if err != nil {
+        return err
+    }
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    resp, err := c.http.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if err := checkResponse(resp); err != nil {
+        return err
+    }
+    if result == nil || resp.StatusCode == http.StatusNoContent {
+        return nil
+    }
+    if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+        return fmt.Errorf("failed to decode response of %s %s: %w", method, path, err)
+    }
+    return nil
+}
+
+// checkResponse 把错误响应转换为 *Error。
+func checkResponse(resp *http.Response) error {
+    if resp.StatusCode < 300 {
+        return nil
+    }
+    apiErr := &Error{}
+    if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Code == "" {
+        apiErr = &Error{Code: CodeInternal, Message: resp.Status}
+    }
+    apiErr.Status = resp.StatusCode
+    return apiErr
+}
+
+// profilePath 返回配置的资源路径，ref 是配置 ID 或唯一的配置名称。
+func profilePath(ref string, action string) string {
+    path := "/v1/profiles/" + url.PathEscape(ref)
+    if action != "" {
+        path += "/" + action
+    }
+    return path
+}
+
+// Profiles 返回所有配置及其状态，包括默认实例。
+func (c *Client) Profiles(ctx context.Context) ([]*Profile, error) {
+    var profiles []*Profile
+    err := c.do(ctx, http.MethodGet, "/v1/profiles", nil, &profiles)
+    return profiles, err
+}
+
+// Profile 返回一个配置及其状态。
+func (c *Client) Profile(ctx context.Context, ref string) (*Profile, error) {
+    profile := &Profile{}
+    err := c.do(ctx, http.MethodGet, profilePath(ref, ""), nil, profile)
+    return profile, err
+}
+
+// AddProfile 新增配置，ID 由管理器生成。
+func (c *Client) AddProfile(ctx context.Context, cfg *config.ChromeConfig) (*Profile, error) {
+    profile := &Profile{}
+    err := c.do(ctx, http.MethodPost, "/v1/profiles", cfg, profile)
+    return profile, err
+}
+
+// UpdateProfile 用 cfg 替换配置，cfg.Workspaces 为 nil 时保留已保存的工作区。
+// moveData 为 true 且数据目录发生变化时把现有的数据目录移动到新位置，要求实例没有运行。
+func (c *Client) UpdateProfile(ctx context.Context, ref string, cfg *config.ChromeConfig, moveData bool) (*Profile, error) {
+    path := profilePath(ref, "")
+    if moveData {
+        path += "?move=1"
+    }
+    profile := &Profile{}
+    err := c.do(ctx, http.MethodPut, path, cfg, profile)
+    return profile, err
+}
+
+// RemoveProfile 删除配置，数据目录保留在磁盘上。
+func (c *Client) RemoveProfile(ctx context.Context, ref string) error {
+    return c.do(ctx, http.MethodDelete, profilePath(ref, ""), nil, nil)
+}
+
+// Start 启动实例。
+func (c *Client) Start(ctx context.Context, ref string, req StartRequest) (*Profile, error) {
+    profile := &Profile{}
+    err := c.do(ctx, http.MethodPost, profilePath(ref, "start"), req, profile)
+    return profile, err
+}
+
+// Stop 停止实例，等待浏览器及其子进程退出。
+func (c *Client) Stop(ctx context.Context, ref string) (*StopResponse, error) {
+    resp := &StopResponse{}
+    err := c.do(ctx, http.MethodPost, profilePath(ref, "stop"), nil, resp)
+    return resp, err
+}
+
+// Restart 停止（如果正在运行）并重新启动实例。
+func (c *Client) Restart(ctx context.Context, ref string, req StartRequest) (*Profile, error) {
+    profile := &Profile{}
+    err := c.do(ctx, http.MethodPost, profilePath(ref, "restart"), req, profile)
+    return profile, err
+}
+
+// Open 在实例中打开网址，实例没有运行时以这些网址启动它；没有网址时启动实例或打开一个新窗口。
+func (c *Client) Open(ctx context.Context, ref string, urls ...string) (*Profile, error) {
+    profile := &Profile{}
+    err := c.do(ctx, http.MethodPost, profilePath(ref, "open"), &OpenRequest{URLs: urls}, profile)
+    return profile, err
+}
+
+// Route 按链接路由在对应的配置中打开网址。
+func (c *Client) Route(ctx context.Context, req RouteRequest) (*RouteResponse, error) {
+    resp := &RouteResponse{}
+    err := c.do(ctx, http.MethodPost, "/v1/open", req, resp)
+    return resp, err
+}
+
+// ShowWindow 把管理器的窗口显示到前台。
+func (c *Client) ShowWindow(ctx context.Context) error {
+    return c.do(ctx, http.MethodPost, "/v1/window/show", nil, nil)
+}
+
+// Events 订阅所有实例的状态变化，订阅建立后返回。事件通道在 ctx 结束或连接断开后关闭。
+func (c *Client) Events(ctx context.Context) (<-chan Event, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://chromes/v1/events", nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := c.http.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    if err := checkResponse(resp); err != nil {
+        resp.Body.Close()
+        return nil, err
+    }
+
+    events := make(chan Event)
+    go func() {
+        defer close(events)
+        defer resp.Body.Close()
+        scanner := bufio.NewScanner(resp.Body)
+        for scanner.Scan() {
+            var ev Event
+            if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+                continue
+            }
+            select {
+            case events <- ev:
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+    return events, nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package api
+
+import "os"
+
+// lockFile 在不支持文件锁的系统上总是成功，此时只依靠 socket 避免同时运行两个管理器。
+func lockFile(file *os.File) error {
+    return nil
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package api
+
+import (
+    "errors"
+    "os"
+    "syscall"
+)
+
+// lockFile 以不阻塞的方式对文件加排他的 flock。
+func lockFile(file *os.File) error {
+    err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+    if errors.Is(err, syscall.EWOULDBLOCK) {
+        return ErrLocked
+    }
+    return err
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package api
+
+import (
+    "os"
+    "syscall"
+    "unsafe"
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+const (
+    lockfileFailImmediately = 0x1
+    lockfileExclusiveLock   = 0x2
+
+    errorLockViolation syscall.Errno = 33 // ERROR_LOCK_VIOLATION
+)
+
+// lockFile 以不阻塞的方式对文件的第一个字节加排他锁（LockFileEx）。
+func lockFile(file *os.File) error {
+    var overlapped syscall.Overlapped
+    r, _, err := procLockFileEx.Call(file.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+    if r != 0 {
+        return nil
+    }
+    if err == errorLockViolation {
+        return ErrLocked
+    }
+    return err
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+    "fmt"
+    "time"
+
+    "chromes/chrome"
+    "chromes/config"
+)
+
+// 控制接口是 Unix domain socket 上的 HTTP/1.1 接口，请求和响应都是 JSON：
+//
+//	GET    /v1/profiles               列出所有配置及其状态 -> []Profile
+//	POST   /v1/profiles               新增配置（config.ChromeConfig）-> Profile
+//	GET    /v1/profiles/{id}          查询一个配置 -> Profile
+//	PUT    /v1/profiles/{id}?move=1   替换配置（config.ChromeConfig），move 时移动数据目录 -> Profile
+//	DELETE /v1/profiles/{id}          删除配置
+//	POST   /v1/profiles/{id}/start    启动实例（StartRequest，可省略）-> Profile
+//	POST   /v1/profiles/{id}/stop     停止实例并等待退出 -> StopResponse
+//	POST   /v1/profiles/{id}/restart  停止（如果正在运行）并重新启动实例 -> Profile
+//	POST   /v1/profiles/{id}/open     在实例中打开网址（OpenRequest），实例没有运行时以这些网址启动，没有网址时打开新窗口 -> Profile
+//	POST   /v1/open                   按链接路由在对应的配置中打开网址（RouteRequest）-> RouteResponse
+//	GET    /v1/events                 状态变化事件流，每行一个 Event，直到连接关闭
+//	POST   /v1/window/show            把管理器的窗口显示到前台
+//
+// {id} 可以是配置 ID，也可以是唯一的配置名称。出错时返回对应的 HTTP 状态码和 Error。
+
+// Profile 是一个配置及其运行状态。
+type Profile struct {
+    config.ChromeConfig
+    Default        bool   `json:"default"`                   // 是否为默认实例
+    State          string `json:"state"`                     // 生命周期状态，取值见 chrome.State 的 String
+    PID            int    `json:"pid,omitempty"`             // 持有用户数据目录的浏览器主进程号
+    Headless       bool   `json:"headless,omitempty"`        // 是否以无界面模式运行
+    RestartPending bool   `json:"restart_pending,omitempty"` // 浏览器已退出，按配置的重启策略计划了自动重启
+}
+
+// newProfile 返回实例当前的配置和状态。
+func newProfile(instance *chrome.Instance) *Profile {
+    cfg := instance.Config()
+    return &Profile{
+        ChromeConfig:   *cfg,
+        Default:        cfg.IsDefault,
+        State:          instance.State().String(),
+        PID:            instance.PID(),
+        Headless:       instance.Headless(),
+        RestartPending: instance.RestartStatus().Pending(),
+    }
+}
+
+// StartRequest 是启动实例的选项。
+type StartRequest struct {
+    Headless       bool `json:"headless,omitempty"`         // 以无界面模式启动，见 chrome.Instance.StartHeadless
+    CleanStaleLock bool `json:"clean_stale_lock,omitempty"` // 数据目录中残留着已失效的 SingletonLock 时清理后启动
+}
+
+// OpenRequest 是在实例中打开的网址。
+type OpenRequest struct {
+    URLs []string `json:"urls"`
+}
+
+// RouteRequest 是按链接路由打开的网址。
+type RouteRequest struct {
+    URLs   []string `json:"urls"`
+    Source string   `json:"source,omitempty"` // 打开网址的来源程序，用于匹配 config.MatchSource 规则，未知时为空
+}
+
+// Route 是一个网址的路由结果。
+type Route struct {
+    URL     string `json:"url"`
+    Profile string `json:"profile,omitempty"` // 打开网址的配置 ID，交给用户选择时为空
+    Rule    int    `json:"rule"`              // 匹配的规则序号（从 0 开始），使用后备配置或交给用户选择时为 -1
+    Chooser bool   `json:"chooser,omitempty"` // 是否交给用户在管理器的窗口中选择配置
+    Error   string `json:"error,omitempty"`   // 在配置中打开网址失败的原因，成功时为空
+}
+
+// RouteResponse 是按链接路由打开网址的结果，Routes 与请求中的网址一一对应。
+// 某个配置打开网址失败不影响其他配置，失败记录在对应的 Route.Error 中。
+type RouteResponse struct {
+    Routes []Route `json:"routes"`
+}
+
+// StopResponse 是停止实例的结果。
+type StopResponse struct {
+    Profile *Profile `json:"profile"`
+    Result  string   `json:"result"` // 最终生效的步骤，取值见 chrome.StopResult 的 String
+}
+
+// Event 是一次状态变化，对应 chrome.Event。
+type Event struct {
+    ID       string    `json:"id"` // 配置 ID
+    From     string    `json:"from"`
+    To       string    `json:"to"`
+    Time     time.Time `json:"time"`
+    PID      int       `json:"pid,omitempty"`
+    ExitCode int       `json:"exit_code"` // 没有退出码时为 -1
+    Detail   string    `json:"detail,omitempty"`
+    Error    string    `json:"error,omitempty"`
+}
+
+// newEvent 把 chrome.Event 转换为接口中的表示。
+func newEvent(ev chrome.Event) Event {
+    event := Event{
+        ID:       ev.ID,
+        From:     ev.From.String(),
+        To:       ev.To.String(),
+        Time:     ev.Time,
+        PID:      ev.PID,
+        ExitCode: ev.ExitCode,
+        Detail:   ev.Detail,
+    }
+    if ev.Err != nil {
+        event.Error = ev.Err.Error()
+    }
+    return event
+}
+
+// 错误的类别（Error.Code）。
+const (
+    CodeInvalid  = "invalid"   // 请求无效，例如配置校验失败（400）
+    CodeNotFound = "not_found" // 找不到配置（404）
+    CodeConflict = "conflict"  // 实例的状态不允许此操作，例如启动已在运行的实例（409）
+    CodeInternal = "internal"  // 其他错误，例如浏览器启动失败（500）
+)
+
+// Error 是接口返回的错误。
+type Error struct {
+    Status  int    `json:"-"`     // HTTP 状态码
+    Code    string `json:"code"`  // 错误的类别
+    Message string `json:"error"` // 错误信息
+}
+
+func (e *Error) Error() string {
+    return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
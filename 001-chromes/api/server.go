@@ -0,0 +1,445 @@
+package api
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "net"
+    "net/http"
+    "strconv"
+    "sync"
+
+    "chromes/chrome"
+    "chromes/config"
+)
+
+// Server 提供控制接口，所有操作都通过与图形界面相同的 config 函数和 chrome.Manager 进行。
+type Server struct {
+    manager *chrome.Manager
+    hooks   Hooks
+    mux     *http.ServeMux
+    mu      sync.Mutex // 串行化配置的修改
+}
+
+// Hooks 是控制接口通知图形界面的回调，都可以为 nil。回调在处理请求的 goroutine 中调用，不能阻塞。
+type Hooks struct {
+    Changed    func()                             // 配置通过接口修改并重新载入后调用，例如刷新列表
+    ShowWindow func()                             // 收到显示窗口的请求时调用，为 nil 时该请求返回 501
+    Choose     func(urls []string, source string) // 网址需要由用户选择配置时调用，为 nil 时使用后备配置
+}
+
+// NewServer 创建控制接口。
+func NewServer(manager *chrome.Manager, hooks Hooks) *Server {
+    s := &Server{manager: manager, hooks: hooks, mux: http.NewServeMux()}
+    s.mux.HandleFunc("GET /v1/profiles", s.listProfiles)
+    s.mux.HandleFunc("POST /v1/profiles", s.addProfile)
+    s.mux.HandleFunc("GET /v1/profiles/{id}", s.getProfile)
+    s.mux.HandleFunc("PUT /v1/profiles/{id}", s.updateProfile)
+    s.mux.HandleFunc("DELETE /v1/profiles/{id}", s.removeProfile)
+    s.mux.HandleFunc("POST /v1/profiles/{id}/start", s.start)
+    s.mux.HandleFunc("POST /v1/profiles/{id}/stop", s.stop)
+    s.mux.HandleFunc("POST /v1/profiles/{id}/restart", s.restart)
+    s.mux.HandleFunc("POST /v1/profiles/{id}/open", s.open)
+    s.mux.HandleFunc("POST /v1/open", s.route)
+    s.mux.HandleFunc("GET /v1/events", s.events)
+    s.mux.HandleFunc("POST /v1/window/show", s.showWindow)
+    return s
+}
+
+// Serve 在 listener 上处理请求，直到 listener 被关闭。
+func (s *Server) Serve(listener net.Listener) error {
+    return (&http.Server{Handler: s.mux}).Serve(listener)
+}
+
+// ServeHTTP 处理一个请求，便于把控制接口挂到其他 http.Server 上。
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    s.mux.ServeHTTP(w, r)
+}
+
+// writeJSON 以 JSON 写入响应。
+func writeJSON(w http.ResponseWriter, status int, v any) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    if err := json.NewEncoder(w).Encode(v); err != nil {
+        log.Printf("[api] write response failed. err=%v", err)
+    }
+}
+
+// writeError 写入错误响应，err 不是 *Error 时视为内部错误。
+func writeError(w http.ResponseWriter, err error) {
+    var apiErr *Error
+    if !errors.As(err, &apiErr) {
+        apiErr = &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: err.Error()}
+    }
+    writeJSON(w, apiErr.Status, apiErr)
+}
+
+// errorf 返回指定类别的错误。
+func errorf(status int, code string, format string, args ...any) *Error {
+    return &Error{Status: status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// decode 解析请求体中的 JSON，allowEmpty 时空请求体保持 v 不变。
+func decode(r *http.Request, v any, allowEmpty bool) error {
+    if allowEmpty && r.ContentLength == 0 {
+        return nil
+    }
+    if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+        return errorf(http.StatusBadRequest, CodeInvalid, "invalid request body: %v", err)
+    }
+    return nil
+}
+
+// instance 按路径中的 {id} 查找实例：先按配置 ID，再按唯一的配置名称。
+func (s *Server) instance(r *http.Request) (*chrome.Instance, error) {
+    id := r.PathValue("id")
+    if instance := s.manager.Get(id); instance != nil {
+        return instance, nil
+    }
+    var match *chrome.Instance
+    for _, instance := range s.manager.Instances() {
+        if instance.Config().Name != id {
+            continue
+        }
+        if match != nil {
+            return nil, errorf(http.StatusBadRequest, CodeInvalid, "more than one profile is named %s, use the id", id)
+        }
+        match = instance
+    }
+    if match == nil {
+        return nil, errorf(http.StatusNotFound, CodeNotFound, "profile %s not found", id)
+    }
+    return match, nil
+}
+
+// modify 在最新的配置列表上执行 update 并重新载入实例，然后通知 Hooks.Changed。
+// update 返回的错误视为请求无效（例如名称重复、端口冲突）。
+func (s *Server) modify(update func(configs []*config.ChromeConfig) error) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    configs, err := config.LoadConfigs()
+    if err != nil {
+        return fmt.Errorf("failed to load configs: %w", err)
+    }
+    if err := update(configs); err != nil {
+        return errorf(http.StatusBadRequest, CodeInvalid, "%v", err)
+    }
+    if configs, err = config.LoadConfigs(); err != nil {
+        return fmt.Errorf("failed to reload configs: %w", err)
+    }
+    s.manager.Reconcile(configs)
+    if s.hooks.Changed != nil {
+        s.hooks.Changed()
+    }
+    return nil
+}
+
+func (s *Server) listProfiles(w http.ResponseWriter, r *http.Request) {
+    instances := s.manager.Instances()
+    profiles := make([]*Profile, 0, len(instances))
+    for _, instance := range instances {
+        profiles = append(profiles, newProfile(instance))
+    }
+    writeJSON(w, http.StatusOK, profiles)
+}
+
+func (s *Server) getProfile(w http.ResponseWriter, r *http.Request) {
+    instance, err := s.instance(r)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, newProfile(instance))
+}
+
+func (s *Server) addProfile(w http.ResponseWriter, r *http.Request) {
+    cfg := &config.ChromeConfig{}
+    if err := decode(r, cfg, false); err != nil {
+        writeError(w, err)
+        return
+    }
+    cfg.ID = "" // 由 AddConfig 生成
+    err := s.modify(func(configs []*config.ChromeConfig) error {
+        _, err := config.AddConfig(cfg, configs)
+        return err
+    })
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    log.Printf("[api] profile added. config=%v", cfg)
+    writeJSON(w, http.StatusCreated, newProfile(s.manager.Get(cfg.ID)))
+}
+
+func (s *Server) updateProfile(w http.ResponseWriter, r *http.Request) {
+    instance, err := s.instance(r)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    old := instance.Config()
+    updated := &config.ChromeConfig{}
+    if err := decode(r, updated, false); err != nil {
+        writeError(w, err)
+        return
+    }
+    if updated.Workspaces == nil {
+        updated.Workspaces = old.Workspaces // 省略时保留已保存的工作区
+    }
+    move, _ := strconv.ParseBool(r.URL.Query().Get("move"))
+    moveData := move && updated.UserDataDir != old.UserDataDir
+    if moveData && instance.IsRunning() {
+        writeError(w, errorf(http.StatusConflict, CodeConflict, "chrome instance %s is running, stop it before moving its data directory", old))
+        return
+    }
+    err = s.modify(func(configs []*config.ChromeConfig) error {
+        _, err := config.UpdateConfig(old.ID, updated, moveData, configs)
+        return err
+    })
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    log.Printf("[api] profile updated. config=%v, move=%v", updated, moveData)
+    writeJSON(w, http.StatusOK, newProfile(s.manager.Get(old.ID)))
+}
+
+func (s *Server) removeProfile(w http.ResponseWriter, r *http.Request) {
+    instance, err := s.instance(r)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    cfg := instance.Config()
+    if instance.IsRunning() {
+        writeError(w, errorf(http.StatusConflict, CodeConflict, "chrome instance %s is running, stop it before removing the profile", cfg))
+        return
+    }
+    err = s.modify(func(configs []*config.ChromeConfig) error {
+        _, err := config.RemoveConfig(cfg.ID, configs)
+        return err
+    })
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    log.Printf("[api] profile removed. config=%v", cfg)
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// startInstance 按 req 启动实例，实例已在运行或数据目录被占用时返回冲突错误。
+func startInstance(instance *chrome.Instance, req StartRequest) error {
+    if instance.IsRunning() {
+        return errorf(http.StatusConflict, CodeConflict, "chrome instance %s is already running", instance.Config())
+    }
+    start := instance.Start
+    if req.Headless {
+        start = instance.StartHeadless
+    }
+    err := start()
+    var inUseErr *chrome.InUseError
+    var staleErr *chrome.StaleLockError
+    if errors.As(err, &staleErr) && req.CleanStaleLock {
+        if _, err = instance.CleanStaleLock(); err == nil {
+            err = start()
+        }
+    }
+    if errors.As(err, &inUseErr) || errors.As(err, &staleErr) {
+        return errorf(http.StatusConflict, CodeConflict, "%v", err)
+    }
+    return err
+}
+
+func (s *Server) start(w http.ResponseWriter, r *http.Request) {
+    instance, err := s.instance(r)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    var req StartRequest
+    if err := decode(r, &req, true); err != nil {
+        writeError(w, err)
+        return
+    }
+    if err := startInstance(instance, req); err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, newProfile(instance))
+}
+
+func (s *Server) stop(w http.ResponseWriter, r *http.Request) {
+    instance, err := s.instance(r)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    if !instance.IsRunning() {
+        writeError(w, errorf(http.StatusConflict, CodeConflict, "chrome instance %s is not running", instance.Config()))
+        return
+    }
+    result, err := instance.Stop()
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, &StopResponse{Profile: newProfile(instance), Result: result.String()})
+}
+
+func (s *Server) restart(w http.ResponseWriter, r *http.Request) {
+    instance, err := s.instance(r)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    var req StartRequest
+    if err := decode(r, &req, true); err != nil {
+        writeError(w, err)
+        return
+    }
+    if instance.IsRunning() {
+        if _, err := instance.Stop(); err != nil {
+            writeError(w, err)
+            return
+        }
+    }
+    if err := startInstance(instance, req); err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, newProfile(instance))
+}
+
+func (s *Server) open(w http.ResponseWriter, r *http.Request) {
+    instance, err := s.instance(r)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    var req OpenRequest
+    if err := decode(r, &req, false); err != nil {
+        writeError(w, err)
+        return
+    }
+    if err := chrome.ValidateURLs(req.URLs); err != nil {
+        writeError(w, errorf(http.StatusBadRequest, CodeInvalid, "%v", err))
+        return
+    }
+    if instance.IsStopping() || instance.Headless() {
+        writeError(w, errorf(http.StatusConflict, CodeConflict, "cannot open urls in chrome instance %s while it is %s", instance.Config(), instance.State()))
+        return
+    }
+    if err := instance.Open(req.URLs...); err != nil {
+        writeError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, newProfile(instance))
+}
+
+// route 按链接路由把网址分配给配置并打开，需要用户选择配置的网址交给 Hooks.Choose。
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+    var req RouteRequest
+    if err := decode(r, &req, false); err != nil {
+        writeError(w, err)
+        return
+    }
+    if len(req.URLs) == 0 {
+        writeError(w, errorf(http.StatusBadRequest, CodeInvalid, "no url to open"))
+        return
+    }
+    if err := chrome.ValidateURLs(req.URLs); err != nil {
+        writeError(w, errorf(http.StatusBadRequest, CodeInvalid, "%v", err))
+        return
+    }
+    // 与界面使用同一份内存中的设置，对话框中保存的路由规则立即生效
+    router := s.manager.Registry().Settings().RouterOrEmpty()
+    var configs []*config.ChromeConfig
+    for _, instance := range s.manager.Instances() {
+        configs = append(configs, instance.Config())
+    }
+
+    resp := &RouteResponse{}
+    var choose []string
+    groups := make(map[string][]int) // 配置 ID -> 在其中打开的网址在 resp.Routes 中的序号
+    var order []*chrome.Instance
+    // 先为所有网址找到目标实例，任何一个找不到时不打开任何网址
+    for _, rawURL := range req.URLs {
+        cfg, rule := router.Route(configs, rawURL, req.Source)
+        if router.NeedsChooser(rule) && s.hooks.Choose != nil {
+            choose = append(choose, rawURL)
+            resp.Routes = append(resp.Routes, Route{URL: rawURL, Rule: rule, Chooser: true})
+            continue
+        }
+        if cfg == nil {
+            writeError(w, errorf(http.StatusNotFound, CodeNotFound, "no profile to open %s", rawURL))
+            return
+        }
+        if _, ok := groups[cfg.ID]; !ok {
+            instance := s.manager.Get(cfg.ID)
+            if instance == nil {
+                writeError(w, errorf(http.StatusNotFound, CodeNotFound, "profile %s not found", cfg.ID))
+                return
+            }
+            order = append(order, instance)
+        }
+        groups[cfg.ID] = append(groups[cfg.ID], len(resp.Routes))
+        resp.Routes = append(resp.Routes, Route{URL: rawURL, Profile: cfg.ID, Rule: rule})
+    }
+
+    // 某个配置打开失败时继续打开其他配置的网址，失败记录在对应的路由结果中
+    for _, instance := range order {
+        indexes := groups[instance.Config().ID]
+        urls := make([]string, len(indexes))
+        for i, index := range indexes {
+            urls[i] = resp.Routes[index].URL
+        }
+        if err := instance.Open(urls...); err != nil {
+            log.Printf("[api] open routed urls failed. config=%v, err=%v", instance.Config(), err)
+            for _, index := range indexes {
+                resp.Routes[index].Error = err.Error()
+            }
+        }
+    }
+    if len(choose) > 0 {
+        s.hooks.Choose(choose, req.Source)
+    }
+    writeJSON(w, http.StatusOK, resp)
+}
+
+// showWindow 把管理器的窗口显示到前台，用于再次启动管理器时转交给正在运行的管理器。
+func (s *Server) showWindow(w http.ResponseWriter, r *http.Request) {
+    if s.hooks.ShowWindow == nil {
+        writeError(w, errorf(http.StatusNotImplemented, CodeInvalid, "this manager has no window"))
+        return
+    }
+    s.hooks.ShowWindow()
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// events 把所有实例的状态变化以每行一个 JSON 对象的形式持续写入响应，直到客户端断开。
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        writeError(w, errors.New("streaming is not supported"))
+        return
+    }
+    events, unsubscribe := s.manager.Subscribe()
+    defer unsubscribe()
+
+    w.Header().Set("Content-Type", "application/x-ndjson")
+    w.WriteHeader(http.StatusOK)
+    flusher.Flush() // 让客户端知道订阅已经开始
+    encoder := json.NewEncoder(w)
+    for {
+        select {
+        case <-r.Context().Done():
+            return
+        case ev := <-events:
+            if err := encoder.Encode(newEvent(ev)); err != nil {
+                return
+            }
+            flusher.Flush()
+        }
+    }
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+// lockName 是保证每个用户只运行一个管理器的锁文件名，与控制接口的 socket 放在同一目录中。
+const lockName = "manager.lock"
+
+var (
+    // ErrLocked 表示另一个管理器正在运行并持有锁。
+    ErrLocked = errors.New("another manager is already running")
+    // ErrNotRunning 表示没有正在运行的管理器。
+    ErrNotRunning = errors.New("no manager is running")
+)
+
+// LockPath 返回当前用户的管理器锁文件路径。
+func LockPath() string {
+    return filepath.Join(filepath.Dir(SocketPath()), lockName)
+}
+
+// Lock 是管理器持有的单实例锁。锁由操作系统维护，进程退出（包括崩溃）后自动释放，不会留下残留的锁。
+type Lock struct {
+    file *os.File
+}
+
+// AcquireLock 获取 path 上的单实例锁，锁已被其他进程持有时立即返回 ErrLocked。
+// 锁和 socket 分开是为了让同时启动的两个管理器中只有一个会开始监听，另一个转而连接它。
+func AcquireLock(path string) (*Lock, error) {
+    dir := filepath.Dir(path)
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        return nil, fmt.Errorf("failed to create lock directory %s: %w", dir, err)
+    }
+    file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+    }
+    if err := lockFile(file); err != nil {
+        file.Close()
+        if errors.Is(err, ErrLocked) {
+            return nil, err
+        }
+        return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+    }
+    return &Lock{file: file}, nil
+}
+
+// Release 释放锁。
+func (l *Lock) Release() error {
+    return l.file.Close()
+}
+
+// Connect 返回连接到正在运行的管理器的客户端，没有管理器在运行时返回 ErrNotRunning。
+// 锁被持有、但还没有人监听时，持有锁的可能是正在启动的管理器（例如正在加载配置），也可能只是另一个
+// 正在检查的 Connect；因此在 ctx 结束前反复检查，直到 socket 就绪或锁被释放。
+// 检查时会短暂地获取锁，同时启动的管理器可能因此获取锁失败，它会通过 Connect 发现没有管理器在运行后重新获取。
+func Connect(ctx context.Context) (*Client, error) {
+    socketPath := SocketPath()
+    ticker := time.NewTicker(100 * time.Millisecond)
+    defer ticker.Stop()
+    for {
+        if listening(socketPath) {
+            return NewClient(socketPath), nil
+        }
+        lock, err := AcquireLock(LockPath())
+        if err == nil {
+            lock.Release()
+            return nil, ErrNotRunning
+        }
+        if !errors.Is(err, ErrLocked) {
+            return nil, err
+        }
+        select {
+        case <-ticker.C:
+        case <-ctx.Done():
+            return nil, fmt.Errorf("manager is not accepting connections on %s: %w", socketPath, ctx.Err())
+        }
+    }
+}
+
+// WaitForManager 等待管理器开始监听，返回连接到它的客户端，适用于刚启动了管理器的情况。
+// 与 Connect 不同，等待期间不会尝试获取锁，因此不会妨碍正在启动的管理器获取锁。
+func WaitForManager(ctx context.Context) (*Client, error) {
+    socketPath := SocketPath()
+    ticker := time.NewTicker(100 * time.Millisecond)
+    defer ticker.Stop()
+    for !listening(socketPath) {
+        select {
+        case <-ticker.C:
+        case <-ctx.Done():
+            return nil, fmt.Errorf("manager is not accepting connections on %s: %w", socketPath, ctx.Err())
+        }
+    }
+    return NewClient(socketPath), nil
+}
+
+// listening 返回 socketPath 上是否有管理器在监听。
+func listening(socketPath string) bool {
+    conn, err := net.DialTimeout("unix", socketPath, time.Second)
+    if err != nil {
+        return false
+    }
+    conn.Close()
+    return true
+}
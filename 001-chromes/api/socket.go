@@ -0,0 +1,57 @@
+package api
+
+import (
+    "errors"
+    "fmt"
+    "net"
+    "os"
+    "path/filepath"
+    "time"
+
+    "chromes/config"
+)
+
+// socketName 是控制接口的 Unix domain socket 文件名。
+const socketName = "control.sock"
+
+// ErrServing 表示已有另一个管理器在 socket 上提供控制接口。
+var ErrServing = errors.New("another manager is already serving the control api")
+
+// SocketPath 返回当前用户的控制接口 socket 路径：优先放在 $XDG_RUNTIME_DIR/chromes 下，
+// 该目录随用户会话创建和清理；未设置时放在配置目录的 ipc/ 下。
+func SocketPath() string {
+    if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+        return filepath.Join(dir, "chromes", socketName)
+    }
+    return filepath.Join(config.Dir(), "ipc", socketName)
+}
+
+// Listen 在 path 上监听控制接口的连接。
+// 访问控制依靠文件权限：socket 所在的目录为 0700、socket 本身为 0600，只有当前用户（和 root）可以连接。
+// 已有管理器在监听时返回 ErrServing；上次没有正常退出留下的 socket 文件会被删除后重新监听。
+func Listen(path string) (net.Listener, error) {
+    dir := filepath.Dir(path)
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        return nil, fmt.Errorf("failed to create socket directory %s: %w", dir, err)
+    }
+    if err := os.Chmod(dir, 0700); err != nil {
+        return nil, fmt.Errorf("failed to restrict socket directory %s: %w", dir, err)
+    }
+
+    if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+        conn.Close()
+        return nil, ErrServing
+    }
+    if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+        return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+    }
+    listener, err := net.Listen("unix", path)
+    if err != nil {
+        return nil, err
+    }
+    if err := os.Chmod(path, 0600); err != nil {
+        listener.Close()
+        return nil, fmt.Errorf("failed to restrict socket %s: %w", path, err)
+    }
+    return listener, nil
+}
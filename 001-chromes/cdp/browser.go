@@ -0,0 +1,57 @@
+package cdp
+
+import (
+    "context"
+    "errors"
+)
+
+// CloseBrowser 请求浏览器关闭（Browser.close），效果与从菜单退出相同，会保存会话状态。
+// 浏览器可能在回复之前就断开连接，此时同样视为成功；调用方需要自行等待进程退出。
+func (c *Client) CloseBrowser(ctx context.Context) error {
+    err := c.Call(ctx, "Browser.close", nil, nil)
+    if errors.Is(err, ErrClosed) {
+        return nil
+    }
+    return err
+}
+
+// Bounds 是浏览器窗口的位置、大小和状态。
+type Bounds struct {
+    Left        int    `json:"left,omitempty"`
+    Top         int    `json:"top,omitempty"`
+    Width       int    `json:"width,omitempty"`
+    Height      int    `json:"height,omitempty"`
+    WindowState string `json:"windowState,omitempty"` // "normal"、"minimized"、"maximized" 或 "fullscreen"
+}
+
+// WindowForTarget 返回目标所在窗口的 ID 和位置（Browser.getWindowForTarget）。
+func (c *Client) WindowForTarget(ctx context.Context, targetID string) (int, Bounds, error) {
+    var result struct {
+        WindowID int    `json:"windowId"`
+        Bounds   Bounds `json:"bounds"`
+    }
+    if err := c.Call(ctx, "Browser.getWindowForTarget", map[string]any{"targetId": targetID}, &result); err != nil {
+        return 0, Bounds{}, err
+    }
+    return result.WindowID, result.Bounds, nil
+}
+
+// SetWindowBounds 设置窗口的位置、大小和状态（Browser.setWindowBounds）。
+// 浏览器要求位置和大小只能在 "normal" 状态下设置，因此非 normal 状态时先恢复为 normal、设置位置后再切换状态。
+func (c *Client) SetWindowBounds(ctx context.Context, windowID int, bounds Bounds) error {
+    // Left 和 Top 为 0 是合法的位置，不能像 Bounds 的 JSON 那样省略
+    normal := map[string]any{
+        "left":        bounds.Left,
+        "top":         bounds.Top,
+        "width":       bounds.Width,
+        "height":      bounds.Height,
+        "windowState": "normal",
+    }
+    if err := c.Call(ctx, "Browser.setWindowBounds", map[string]any{"windowId": windowID, "bounds": normal}, nil); err != nil {
+        return err
+    }
+    if bounds.WindowState == "" || bounds.WindowState == "normal" {
+        return nil
+    }
+    return c.Call(ctx, "Browser.setWindowBounds", map[string]any{"windowId": windowID, "bounds": Bounds{WindowState: bounds.WindowState}}, nil)
+}
@@ -0,0 +1,166 @@
+package cdp
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "sync"
+)
+
+// Error 是浏览器对命令返回的错误。
+type Error struct {
+    Code    int    `json:"code"`
+    Message string `json:"message"`
+    Data    string `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+    if e.Data != "" {
+        return fmt.Sprintf("cdp error %d: %s (%s)", e.Code, e.Message, e.Data)
+    }
+    return fmt.Sprintf("cdp error %d: %s", e.Code, e.Message)
+}
+
+// ErrClosed 表示连接已经关闭，例如浏览器已经退出。
+var ErrClosed = errors.New("cdp connection closed")
+
+// message 是 CDP 的一条消息：命令、响应或事件。
+type message struct {
+    ID     int64           `json:"id,omitempty"`
+    Method string          `json:"method,omitempty"`
+    Params json.RawMessage `json:"params,omitempty"`
+    Result json.RawMessage `json:"result,omitempty"`
+    Error  *Error          `json:"error,omitempty"`
+}
+
+// Client 是到浏览器的一个 Chrome DevTools Protocol 连接，只依赖标准库，可以被多个 goroutine 同时使用。
+// 它通过浏览器级的 WebSocket 端点（/json/version 给出的 webSocketDebuggerUrl）管理标签页和关闭浏览器，
+// 或者通过某个页面的端点（/devtools/page/<目标 ID>）操作该页面；只发送命令并等待响应，不处理事件订阅和会话。
+type Client struct {
+    conn    *wsConn
+    nextID  int64                   // 上一条命令的 ID
+    pending map[int64]chan *message // 等待响应的命令
+    err     error                   // 连接断开的原因
+    done    chan struct{}           // 连接断开后关闭
+    mu      sync.Mutex              // 保护 nextID、pending 和 err
+}
+
+// Dial 连接 WebSocket 端点，例如浏览器级的 "ws://127.0.0.1:9222/devtools/browser/<id>"
+// 或页面的 "ws://127.0.0.1:9222/devtools/page/<目标 ID>"。
+func Dial(ctx context.Context, wsURL string) (*Client, error) {
+    conn, err := dialWebSocket(ctx, wsURL)
+    if err != nil {
+        return nil, fmt.Errorf("failed to connect to %s: %w", wsURL, err)
+    }
+    c := &Client{
+        conn:    conn,
+        pending: make(map[int64]chan *message),
+        done:    make(chan struct{}),
+    }
+    go c.readLoop()
+    return c, nil
+}
+
+// readLoop 读取浏览器发来的消息并交给等待响应的命令，事件被忽略。连接断开后结束所有等待。
+func (c *Client) readLoop() {
+    var err error
+    for {
+        var data []byte
+        if data, err = c.conn.ReadMessage(); err != nil {
+            break
+        }
+        msg := &message{}
+        if err := json.Unmarshal(data, msg); err != nil {
+            log.Printf("[cdp] invalid message ignored. err=%v", err)
+            continue
+        }
+        if msg.ID == 0 {
+            continue // 事件
+        }
+        c.mu.Lock()
+        ch, ok := c.pending[msg.ID]
+        delete(c.pending, msg.ID)
+        c.mu.Unlock()
+        if ok {
+            ch <- msg
+        }
+    }
+
+    c.mu.Lock()
+    c.err = fmt.Errorf("%w: %v", ErrClosed, err)
+    c.pending = nil
+    c.mu.Unlock()
+    close(c.done)
+}
+
+// Call 发送一条命令并等待响应，params 为 nil 时不带参数，result 为 nil 时忽略响应内容。
+func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+    msg := &message{Method: method}
+    if params != nil {
+        data, err := json.Marshal(params)
+        if err != nil {
+            return fmt.Errorf("failed to encode params of %s: %w", method, err)
+        }
+        msg.Params = data
+    }
+
+    ch := make(chan *message, 1)
+    c.mu.Lock()
+    if c.pending == nil {
+        err := c.err
+        c.mu.Unlock()
+        return err
+    }
+    c.nextID++
+    msg.ID = c.nextID
+    c.pending[msg.ID] = ch
+    c.mu.Unlock()
+
+    data, err := json.Marshal(msg)
+    if err == nil {
+        err = c.conn.WriteMessage(data)
+    }
+    if err != nil {
+        c.forget(msg.ID)
+        return fmt.Errorf("failed to send %s: %w", method, err)
+    }
+
+    select {
+    case resp := <-ch:
+        if resp.Error != nil {
+            return fmt.Errorf("%s: %w", method, resp.Error)
+        }
+        if result != nil && len(resp.Result) > 0 {
+            if err := json.Unmarshal(resp.Result, result); err != nil {
+                return fmt.Errorf("failed to decode result of %s: %w", method, err)
+            }
+        }
+        return nil
+    case <-c.done:
+        c.mu.Lock()
+        defer c.mu.Unlock()
+        return fmt.Errorf("%s: %w", method, c.err)
+    case <-ctx.Done():
+        c.forget(msg.ID)
+        return fmt.Errorf("%s: %w", method, ctx.Err())
+    }
+}
+
+// forget 不再等待某条命令的响应。
+func (c *Client) forget(id int64) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    delete(c.pending, id)
+}
+
+// Done 返回一个在连接断开后关闭的通道。
+func (c *Client) Done() <-chan struct{} {
+    return c.done
+}
+
+// Close 关闭连接。
+func (c *Client) Close() error {
+    return c.conn.Close()
+}
@@ -0,0 +1,501 @@
+package cdp
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "crypto/sha1"
+    "encoding/base64"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+// serverConn 是测试用 CDP 服务端的一条 WebSocket 连接。服务端发送的帧不加掩码，读取时要求客户端的帧带掩码。
+type serverConn struct {
+    t    *testing.T
+    conn net.Conn
+    br   *bufio.Reader
+}
+
+// serveCDP 启动一个模拟浏览器 WebSocket 端点的服务器，完成握手后在服务端执行 script，返回 ws:// 地址。
+// 测试结束时等待 script 返回。
+func serveCDP(t *testing.T, script func(sc *serverConn)) string {
+    t.Helper()
+    done := make(chan struct{})
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        key := r.Header.Get("Sec-WebSocket-Key")
+        if r.Method != http.MethodGet || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+            !strings.EqualFold(r.Header.Get("Connection"), "Upgrade") || r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+            t.Errorf("unexpected upgrade request: %s %s %v", r.Method, r.URL, r.Header)
+            http.Error(w, "bad upgrade request", http.StatusBadRequest)
+            return
+        }
+        if nonce, err := base64.StdEncoding.DecodeString(key); err != nil || len(nonce) != 16 {
+            t.Errorf("invalid Sec-WebSocket-Key %q", key)
+        }
+        conn, rw, err := http.NewResponseController(w).Hijack()
+        if err != nil {
+            t.Errorf("hijack failed: %v", err)
+            return
+        }
+        defer conn.Close()
+        sum := sha1.Sum([]byte(key + websocketGUID))
+        fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(sum[:]))
+        defer close(done)
+        script(&serverConn{t: t, conn: conn, br: rw.Reader})
+    }))
+    t.Cleanup(func() {
+        select {
+        case <-done:
+        case <-time.After(5 * time.Second):
+            t.Error("server script did not finish")
+        }
+        server.Close()
+    })
+    return "ws" + strings.TrimPrefix(server.URL, "http") + "/devtools/browser/test"
+}
+
+// writeFrame 写入一个不加掩码的帧，按负载长度使用 7 位、16 位或 64 位的长度字段。
+func (sc *serverConn) writeFrame(fin bool, opcode byte, payload []byte) {
+    b0 := opcode
+    if fin {
+        b0 |= 0x80
+    }
+    header := []byte{b0, 0}
+    switch n := len(payload); {
+    case n < 126:
+        header[1] = byte(n)
+    case n <= 0xFFFF:
+        header[1] = 126
+        header = binary.BigEndian.AppendUint16(header, uint16(n))
+    default:
+        header[1] = 127
+        header = binary.BigEndian.AppendUint64(header, uint64(n))
+    }
+    if _, err := sc.conn.Write(append(header, payload...)); err != nil {
+        sc.t.Errorf("server write failed: %v", err)
+    }
+}
+
+// readFrame 读取客户端发送的一个帧并去掉掩码，帧没有掩码时报告错误。
+func (sc *serverConn) readFrame() (bool, byte, []byte) {
+    sc.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    var head [2]byte
+    if _, err := io.ReadFull(sc.br, head[:]); err != nil {
+        sc.t.Errorf("server read failed: %v", err)
+        return false, 0, nil
+    }
+    if head[1]&0x80 == 0 {
+        sc.t.Errorf("client frame is not masked")
+    }
+    length := uint64(head[1] & 0x7F)
+    switch length {
+    case 126:
+        var ext [2]byte
+        io.ReadFull(sc.br, ext[:])
+        length = uint64(binary.BigEndian.Uint16(ext[:]))
+    case 127:
+        var ext [8]byte
+        io.ReadFull(sc.br, ext[:])
+        length = binary.BigEndian.Uint64(ext[:])
+    }
+    var mask [4]byte
+    io.ReadFull(sc.br, mask[:])
+    payload := make([]byte, length)
+    if _, err := io.ReadFull(sc.br, payload); err != nil {
+        sc.t.Errorf("server read failed: %v", err)
+        return false, 0, nil
+    }
+    for i := range payload {
+        payload[i] ^= mask[i%4]
+    }
+    return head[0]&0x80 != 0, head[0] & 0x0F, payload
+}
+
+// readCommand 读取客户端发送的一条 CDP 命令。
+func (sc *serverConn) readCommand() message {
+    _, opcode, payload := sc.readFrame()
+    var msg message
+    if opcode != opText {
+        sc.t.Errorf("got opcode %d, want a text frame", opcode)
+        return msg
+    }
+    if err := json.Unmarshal(payload, &msg); err != nil {
+        sc.t.Errorf("invalid command %s: %v", payload, err)
+    }
+    return msg
+}
+
+// send 以一个文本帧发送 JSON 消息。
+func (sc *serverConn) send(v any) {
+    data, err := json.Marshal(v)
+    if err != nil {
+        sc.t.Errorf("encode failed: %v", err)
+        return
+    }
+    sc.writeFrame(true, opText, data)
+}
+
+func dialTestClient(t *testing.T, wsURL string) *Client {
+    t.Helper()
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    client, err := Dial(ctx, wsURL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { client.Close() })
+    return client
+}
+
+func dialTestConn(t *testing.T, wsURL string) *wsConn {
+    t.Helper()
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    conn, err := dialWebSocket(ctx, wsURL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { conn.conn.Close() })
+    return conn
+}
+
+func TestDialRejectsBadHandshake(t *testing.T) {
+    tests := []struct {
+        name    string
+        handler http.HandlerFunc
+    }{
+        {"not upgraded", func(w http.ResponseWriter, r *http.Request) {
+            http.Error(w, "no websocket here", http.StatusNotFound)
+        }},
+        {"wrong accept", func(w http.ResponseWriter, r *http.Request) {
+            conn, _, err := http.NewResponseController(w).Hijack()
+            if err != nil {
+                return
+            }
+            defer conn.Close()
+            fmt.Fprint(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: bogus\r\n\r\n")
+        }},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := httptest.NewServer(tt.handler)
+            defer server.Close()
+            wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/devtools/browser/x"
+            if _, err := Dial(context.Background(), wsURL); err == nil || !strings.Contains(err.Error(), "handshake") {
+                t.Errorf("got err=%v, want a handshake error", err)
+            }
+        })
+    }
+
+    for _, wsURL := range []string{"http://127.0.0.1:1/devtools", "::"} {
+        if _, err := Dial(context.Background(), wsURL); err == nil {
+            t.Errorf("Dial(%q): expected an error", wsURL)
+        }
+    }
+}
+
+func TestClientFramesAreMasked(t *testing.T) {
+    sizes := []int{0, 125, 126, 300, 0xFFFF, 70000}
+    wsURL := serveCDP(t, func(sc *serverConn) {
+        for _, size := range sizes {
+            fin, opcode, payload := sc.readFrame()
+            if !fin || opcode != opText || !bytes.Equal(payload, bytes.Repeat([]byte("x"), size)) {
+                t.Errorf("size %d: got fin=%v, opcode=%d, %d bytes", size, fin, opcode, len(payload))
+            }
+        }
+    })
+    conn := dialTestConn(t, wsURL)
+    for _, size := range sizes {
+        if err := conn.WriteMessage(bytes.Repeat([]byte("x"), size)); err != nil {
+            t.Fatal(err)
+        }
+    }
+}
+
+func TestReadMessageExtendedLengths(t *testing.T) {
+    sizes := []int{10, 126, 300, 0xFFFF, 70000}
+    wsURL := serveCDP(t, func(sc *serverConn) {
+        for _, size := range sizes {
+            sc.writeFrame(true, opText, bytes.Repeat([]byte("y"), size))
+        }
+        sc.readFrame() // 等待客户端读完
+    })
+    conn := dialTestConn(t, wsURL)
+    for _, size := range sizes {
+        data, err := conn.ReadMessage()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !bytes.Equal(data, bytes.Repeat([]byte("y"), size)) {
+            t.Errorf("size %d: got %d bytes", size, len(data))
+        }
+    }
+    conn.WriteMessage([]byte("done"))
+}
+
+func TestReadMessageFragmentedWithPing(t *testing.T) {
+    wsURL := serveCDP(t, func(sc *serverConn) {
+        sc.writeFrame(false, opText, []byte("hel"))
+        sc.writeFrame(true, opPing, []byte("are you there"))
+        sc.writeFrame(false, opContinuation, []byte("lo "))
+        sc.writeFrame(true, opPong, []byte("unsolicited"))
+        sc.writeFrame(true, opContinuation, []byte("world"))
+
+        fin, opcode, payload := sc.readFrame()
+        if !fin || opcode != opPong || string(payload) != "are you there" {
+            t.Errorf("got fin=%v, opcode=%d, payload=%q, want a pong echoing the ping", fin, opcode, payload)
+        }
+        sc.readFrame() // 等待客户端读完
+    })
+    conn := dialTestConn(t, wsURL)
+    data, err := conn.ReadMessage()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(data) != "hello world" {
+        t.Errorf("got %q, want %q", data, "hello world")
+    }
+    conn.WriteMessage([]byte("done"))
+}
+
+func TestReadMessageProtocolErrors(t *testing.T) {
+    tests := []struct {
+        name   string
+        frames func(sc *serverConn)
+    }{
+        {"continuation without start", func(sc *serverConn) {
+            sc.writeFrame(true, opContinuation, []byte("x"))
+        }},
+        {"new message inside fragments", func(sc *serverConn) {
+            sc.writeFrame(false, opText, []byte("a"))
+            sc.writeFrame(true, opText, []byte("b"))
+        }},
+        {"unknown opcode", func(sc *serverConn) {
+            sc.writeFrame(true, 0x3, []byte("x"))
+        }},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            wsURL := serveCDP(t, func(sc *serverConn) {
+                tt.frames(sc)
+                sc.readFrame() // 等待客户端读完
+            })
+            conn := dialTestConn(t, wsURL)
+            if _, err := conn.ReadMessage(); err == nil || !strings.Contains(err.Error(), "protocol error") {
+                t.Errorf("got err=%v, want a protocol error", err)
+            }
+            conn.WriteMessage([]byte("done"))
+        })
+    }
+}
+
+func TestCloseHandshake(t *testing.T) {
+    t.Run("server closes", func(t *testing.T) {
+        wsURL := serveCDP(t, func(sc *serverConn) {
+            sc.writeFrame(true, opClose, []byte{0x03, 0xE9})
+            _, opcode, payload := sc.readFrame()
+            if opcode != opClose || !bytes.Equal(payload, []byte{0x03, 0xE9}) {
+                t.Errorf("got opcode=%d, payload=%v, want the close frame echoed", opcode, payload)
+            }
+        })
+        conn := dialTestConn(t, wsURL)
+        if _, err := conn.ReadMessage(); !errors.Is(err, errConnClosed) {
+            t.Errorf("got err=%v, want errConnClosed", err)
+        }
+    })
+
+    t.Run("client closes", func(t *testing.T) {
+        wsURL := serveCDP(t, func(sc *serverConn) {
+            _, opcode, payload := sc.readFrame()
+            if opcode != opClose || !bytes.Equal(payload, []byte{0x03, 0xE8}) {
+                t.Errorf("got opcode=%d, payload=%v, want a normal closure", opcode, payload)
+            }
+        })
+        client := dialTestClient(t, wsURL)
+        client.Close()
+        select {
+        case <-client.Done():
+        case <-time.After(5 * time.Second):
+            t.Fatal("Done not closed after Close")
+        }
+    })
+}
+
+func TestCallMatchesResponsesByID(t *testing.T) {
+    wsURL := serveCDP(t, func(sc *serverConn) {
+        first := sc.readCommand()
+        second := sc.readCommand()
+        if first.ID == second.ID {
+            t.Errorf("commands share id %d", first.ID)
+        }
+        // 事件和响应交错，响应的顺序与命令相反
+        sc.send(map[string]any{"method": "Target.targetCreated", "params": map[string]any{"targetInfo": map[string]any{"targetId": "x"}}})
+        sc.send(map[string]any{"id": second.ID, "result": map[string]any{"echo": second.Method}})
+        sc.writeFrame(true, opText, []byte("not json"))
+        sc.send(map[string]any{"method": "Target.targetDestroyed", "params": map[string]any{"targetId": "x"}})
+        sc.send(map[string]any{"id": 9999, "result": map[string]any{}}) // 没有人等待的响应
+        sc.send(map[string]any{"id": first.ID, "result": map[string]any{"echo": first.Method}})
+        sc.readFrame() // 等待客户端关闭
+    })
+    client := dialTestClient(t, wsURL)
+
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    methods := []string{"Test.first", "Test.second"}
+    results := make(chan string, len(methods))
+    errs := make(chan error, len(methods))
+    for i, method := range methods {
+        if i > 0 {
+            time.Sleep(50 * time.Millisecond) // 保证命令按顺序到达
+        }
+        go func() {
+            var result struct {
+                Echo string `json:"echo"`
+            }
+            if err := client.Call(ctx, method, nil, &result); err != nil {
+                errs <- err
+                return
+            }
+            if result.Echo != method {
+                errs <- fmt.Errorf("%s got the response of %s", method, result.Echo)
+                return
+            }
+            results <- method
+        }()
+    }
+    for range methods {
+        select {
+        case <-results:
+        case err := <-errs:
+            t.Error(err)
+        case <-ctx.Done():
+            t.Fatal("timed out waiting for responses")
+        }
+    }
+}
+
+func TestCallReturnsCDPError(t *testing.T) {
+    wsURL := serveCDP(t, func(sc *serverConn) {
+        cmd := sc.readCommand()
+        sc.send(map[string]any{"id": cmd.ID, "error": map[string]any{"code": -32601, "message": "'Test.missing' wasn't found", "data": "extra"}})
+        sc.readFrame() // 等待客户端关闭
+    })
+    client := dialTestClient(t, wsURL)
+
+    err := client.Call(context.Background(), "Test.missing", map[string]any{"a": 1}, nil)
+    var cdpErr *Error
+    if !errors.As(err, &cdpErr) || cdpErr.Code != -32601 || cdpErr.Data != "extra" {
+        t.Fatalf("got err=%v, want a cdp error with code -32601", err)
+    }
+    if !strings.HasPrefix(err.Error(), "Test.missing: ") {
+        t.Errorf("error %q does not name the method", err)
+    }
+}
+
+func TestCallCanceled(t *testing.T) {
+    wsURL := serveCDP(t, func(sc *serverConn) {
+        slow := sc.readCommand()
+        next := sc.readCommand()
+        // 已放弃等待的命令的响应迟到，不能被交给下一条命令
+        sc.send(map[string]any{"id": slow.ID, "result": map[string]any{"value": "slow"}})
+        sc.send(map[string]any{"id": next.ID, "result": map[string]any{"value": "next"}})
+        sc.readFrame() // 等待客户端关闭
+    })
+    client := dialTestClient(t, wsURL)
+
+    ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+    defer cancel()
+    if err := client.Call(ctx, "Test.slow", nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("got err=%v, want context.DeadlineExceeded", err)
+    }
+
+    var result struct {
+        Value string `json:"value"`
+    }
+    if err := client.Call(context.Background(), "Test.next", nil, &result); err != nil {
+        t.Fatal(err)
+    }
+    if result.Value != "next" {
+        t.Errorf("got %q, want the response of the second command", result.Value)
+    }
+}
+
+func TestCallAfterDisconnect(t *testing.T) {
+    wsURL := serveCDP(t, func(sc *serverConn) {
+        sc.readCommand()
+        sc.conn.Close() // 浏览器退出，没有回复
+    })
+    client := dialTestClient(t, wsURL)
+
+    if err := client.Call(context.Background(), "Test.pending", nil, nil); !errors.Is(err, ErrClosed) {
+        t.Fatalf("got err=%v, want ErrClosed", err)
+    }
+    select {
+    case <-client.Done():
+    case <-time.After(5 * time.Second):
+        t.Fatal("Done not closed after disconnect")
+    }
+    if err := client.Call(context.Background(), "Test.after", nil, nil); !errors.Is(err, ErrClosed) {
+        t.Errorf("got err=%v, want ErrClosed", err)
+    }
+    if err := client.CloseBrowser(context.Background()); err != nil {
+        t.Errorf("CloseBrowser on a closed connection: %v", err)
+    }
+}
+
+func TestTargetCommands(t *testing.T) {
+    type call struct {
+        method string
+        params string
+        result string
+    }
+    calls := []call{
+        {"Target.getTargets", ``, `{"targetInfos":[{"targetId":"a","type":"page","title":"A","url":"https://a.example/","attached":true},{"targetId":"w","type":"service_worker","url":"https://a.example/sw.js"}]}`},
+        {"Target.createTarget", `{"url":"https://b.example/"}`, `{"targetId":"b"}`},
+        {"Target.createTarget", `{"newWindow":true,"url":"https://c.example/"}`, `{"targetId":"c"}`},
+        {"Target.activateTarget", `{"targetId":"b"}`, `{}`},
+        {"Target.closeTarget", `{"targetId":"b"}`, `{"success":true}`},
+    }
+    wsURL := serveCDP(t, func(sc *serverConn) {
+        for _, want := range calls {
+            cmd := sc.readCommand()
+            if cmd.Method != want.method || string(cmd.Params) != want.params {
+                t.Errorf("got %s %s, want %s %s", cmd.Method, cmd.Params, want.method, want.params)
+            }
+            sc.send(map[string]any{"id": cmd.ID, "result": json.RawMessage(want.result)})
+        }
+        sc.readFrame() // 等待客户端关闭
+    })
+    client := dialTestClient(t, wsURL)
+    ctx := context.Background()
+
+    targets, err := client.Targets(ctx)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(targets) != 2 || targets[0].TargetID != "a" || !targets[0].IsTab() || !targets[0].Attached || targets[1].IsTab() {
+        t.Errorf("unexpected targets: %+v", targets)
+    }
+    if id, err := client.CreateTarget(ctx, "https://b.example/"); err != nil || id != "b" {
+        t.Errorf("CreateTarget = %q, %v", id, err)
+    }
+    if id, err := client.NewWindow(ctx, "https://c.example/"); err != nil || id != "c" {
+        t.Errorf("NewWindow = %q, %v", id, err)
+    }
+    if err := client.ActivateTarget(ctx, "b"); err != nil {
+        t.Errorf("ActivateTarget: %v", err)
+    }
+    if err := client.CloseTarget(ctx, "b"); err != nil {
+        t.Errorf("CloseTarget: %v", err)
+    }
+}
@@ -0,0 +1,14 @@
+package cdp
+
+import "context"
+
+// SetViewport 把页面的视口设为 width×height 个 CSS 像素，设备像素比为 1（Emulation.setDeviceMetricsOverride）。
+func (c *Client) SetViewport(ctx context.Context, width, height int) error {
+    params := map[string]any{
+        "width":             width,
+        "height":            height,
+        "deviceScaleFactor": 1,
+        "mobile":            false,
+    }
+    return c.Call(ctx, "Emulation.setDeviceMetricsOverride", params, nil)
+}
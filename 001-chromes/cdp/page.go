@@ -0,0 +1,43 @@
+package cdp
+
+import (
+    "context"
+    "fmt"
+)
+
+// Navigate 让页面加载 url（Page.navigate），在导航提交后返回，不等待页面加载完成。
+// 网址无法访问时（例如 DNS 解析失败）返回浏览器给出的网络错误。
+func (c *Client) Navigate(ctx context.Context, url string) error {
+    var result struct {
+        ErrorText string `json:"errorText"`
+    }
+    if err := c.Call(ctx, "Page.navigate", map[string]any{"url": url}, &result); err != nil {
+        return err
+    }
+    if result.ErrorText != "" {
+        return fmt.Errorf("failed to navigate to %s: %s", url, result.ErrorText)
+    }
+    return nil
+}
+
+// CaptureScreenshot 截取页面当前视口的 PNG 图像（Page.captureScreenshot）。
+func (c *Client) CaptureScreenshot(ctx context.Context) ([]byte, error) {
+    var result struct {
+        Data []byte `json:"data"` // base64 编码，由 encoding/json 解码
+    }
+    if err := c.Call(ctx, "Page.captureScreenshot", map[string]any{"format": "png"}, &result); err != nil {
+        return nil, err
+    }
+    return result.Data, nil
+}
+
+// PrintToPDF 将页面打印为 PDF，包含背景图形（Page.printToPDF）。只有无界面模式的浏览器支持此命令。
+func (c *Client) PrintToPDF(ctx context.Context) ([]byte, error) {
+    var result struct {
+        Data []byte `json:"data"` // base64 编码，由 encoding/json 解码
+    }
+    if err := c.Call(ctx, "Page.printToPDF", map[string]any{"printBackground": true}, &result); err != nil {
+        return nil, err
+    }
+    return result.Data, nil
+}
@@ -0,0 +1,33 @@
+package cdp
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+)
+
+// Evaluate 在页面中执行 JavaScript 表达式并把结果按 JSON 解码到 result（Runtime.evaluate）。
+// 表达式抛出异常时返回错误；result 为 nil 时忽略结果。
+func (c *Client) Evaluate(ctx context.Context, expression string, result any) error {
+    var response struct {
+        Result struct {
+            Value json.RawMessage `json:"value"`
+        } `json:"result"`
+        ExceptionDetails *struct {
+            Text string `json:"text"`
+        } `json:"exceptionDetails"`
+    }
+    params := map[string]any{"expression": expression, "returnByValue": true}
+    if err := c.Call(ctx, "Runtime.evaluate", params, &response); err != nil {
+        return err
+    }
+    if response.ExceptionDetails != nil {
+        return fmt.Errorf("failed to evaluate %q: %s", expression, response.ExceptionDetails.Text)
+    }
+    if result != nil && len(response.Result.Value) > 0 {
+        if err := json.Unmarshal(response.Result.Value, result); err != nil {
+            return fmt.Errorf("failed to decode result of %q: %w", expression, err)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,62 @@
+package cdp
+
+import "context"
+
+// TargetInfo 描述浏览器中的一个调试目标，例如标签页、扩展的后台页或 Service Worker。
+type TargetInfo struct {
+    TargetID string `json:"targetId"`
+    Type     string `json:"type"` // "page"、"background_page"、"service_worker"、"iframe" 等
+    Title    string `json:"title"`
+    URL      string `json:"url"`
+    Attached bool   `json:"attached"` // 是否已有调试客户端连接到该目标
+}
+
+// IsTab 返回目标是否为普通的标签页。
+func (t TargetInfo) IsTab() bool {
+    return t.Type == "page"
+}
+
+// Targets 返回浏览器中所有的调试目标（Target.getTargets）。
+func (c *Client) Targets(ctx context.Context) ([]TargetInfo, error) {
+    var result struct {
+        TargetInfos []TargetInfo `json:"targetInfos"`
+    }
+    if err := c.Call(ctx, "Target.getTargets", nil, &result); err != nil {
+        return nil, err
+    }
+    return result.TargetInfos, nil
+}
+
+// CreateTarget 在新标签页中打开 url，返回新标签页的目标 ID（Target.createTarget）。
+func (c *Client) CreateTarget(ctx context.Context, url string) (string, error) {
+    var result struct {
+        TargetID string `json:"targetId"`
+    }
+    params := map[string]any{"url": url}
+    if err := c.Call(ctx, "Target.createTarget", params, &result); err != nil {
+        return "", err
+    }
+    return result.TargetID, nil
+}
+
+// NewWindow 在新窗口中打开 url，返回新标签页的目标 ID（Target.createTarget 的 newWindow）。
+func (c *Client) NewWindow(ctx context.Context, url string) (string, error) {
+    var result struct {
+        TargetID string `json:"targetId"`
+    }
+    params := map[string]any{"url": url, "newWindow": true}
+    if err := c.Call(ctx, "Target.createTarget", params, &result); err != nil {
+        return "", err
+    }
+    return result.TargetID, nil
+}
+
+// ActivateTarget 激活目标所在的标签页并将其窗口置于前台（Target.activateTarget）。
+func (c *Client) ActivateTarget(ctx context.Context, targetID string) error {
+    return c.Call(ctx, "Target.activateTarget", map[string]any{"targetId": targetID}, nil)
+}
+
+// CloseTarget 关闭目标所在的标签页（Target.closeTarget）。
+func (c *Client) CloseTarget(ctx context.Context, targetID string) error {
+    return c.Call(ctx, "Target.closeTarget", map[string]any{"targetId": targetID}, nil)
+}
@@ -0,0 +1,268 @@
+package cdp
+
+import (
+    "bufio"
+    "context"
+    "crypto/rand"
+    "crypto/sha1"
+    "crypto/tls"
+    "encoding/base64"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "net/url"
+    "sync"
+    "time"
+)
+
+// WebSocket 操作码（RFC 6455 5.2 节）。
+const (
+    opContinuation = 0x0
+    opText         = 0x1
+    opBinary       = 0x2
+    opClose        = 0x8
+    opPing         = 0x9
+    opPong         = 0xA
+)
+
+// websocketGUID 是计算 Sec-WebSocket-Accept 时拼接在 Sec-WebSocket-Key 之后的固定字符串。
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxMessageSize 是单条消息的大小上限，DevTools 的截图等响应可能有数 MB。
+const maxMessageSize = 64 << 20
+
+// errConnClosed 表示对端发送了关闭帧。
+var errConnClosed = errors.New("websocket connection closed")
+
+// wsConn 是只实现 CDP 所需功能的 WebSocket 客户端连接：文本消息的收发、分片重组、ping/pong 和关闭握手。
+// 读取只能在一个 goroutine 中进行，写入可以并发。
+type wsConn struct {
+    conn net.Conn
+    br   *bufio.Reader
+    wmu  sync.Mutex // 保证帧的写入不会交错
+}
+
+// dialWebSocket 连接 ws:// 或 wss:// 地址并完成握手。ctx 只作用于连接和握手过程。
+func dialWebSocket(ctx context.Context, rawURL string) (*wsConn, error) {
+    u, err := url.Parse(rawURL)
+    if err != nil {
+        return nil, fmt.Errorf("invalid websocket url %s: %w", rawURL, err)
+    }
+    host := u.Host
+    switch u.Scheme {
+    case "ws":
+        if u.Port() == "" {
+            host = net.JoinHostPort(u.Hostname(), "80")
+        }
+    case "wss":
+        if u.Port() == "" {
+            host = net.JoinHostPort(u.Hostname(), "443")
+        }
+    default:
+        return nil, fmt.Errorf("unsupported websocket url scheme %s", u.Scheme)
+    }
+
+    var dialer net.Dialer
+    conn, err := dialer.DialContext(ctx, "tcp", host)
+    if err != nil {
+        return nil, err
+    }
+    if u.Scheme == "wss" {
+        tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+        if err := tlsConn.HandshakeContext(ctx); err != nil {
+            conn.Close()
+            return nil, err
+        }
+        conn = tlsConn
+    }
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+    }
+
+    ws, err := handshake(conn, u)
+    if err != nil {
+        conn.Close()
+        return nil, err
+    }
+    conn.SetDeadline(time.Time{})
+    return ws, nil
+}
+
+// handshake 发送升级请求并校验服务端的响应。
+func handshake(conn net.Conn, u *url.URL) (*wsConn, error) {
+    nonce := make([]byte, 16)
+    if _, err := rand.Read(nonce); err != nil {
+        return nil, err
+    }
+    key := base64.StdEncoding.EncodeToString(nonce)
+
+    req := &http.Request{
+        Method:     http.MethodGet,
+        URL:        u,
+        Host:       u.Host,
+        Proto:      "HTTP/1.1",
+        ProtoMajor: 1,
+        ProtoMinor: 1,
+        Header: http.Header{
+            "Upgrade":               {"websocket"},
+            "Connection":            {"Upgrade"},
+            "Sec-WebSocket-Key":     {key},
+            "Sec-WebSocket-Version": {"13"},
+        },
+    }
+    if err := req.Write(conn); err != nil {
+        return nil, fmt.Errorf("failed to send websocket handshake: %w", err)
+    }
+
+    br := bufio.NewReader(conn)
+    resp, err := http.ReadResponse(br, req)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read websocket handshake: %w", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusSwitchingProtocols {
+        return nil, fmt.Errorf("websocket handshake to %s failed: %s", u, resp.Status)
+    }
+    sum := sha1.Sum([]byte(key + websocketGUID))
+    if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+        return nil, fmt.Errorf("websocket handshake to %s failed: invalid Sec-WebSocket-Accept", u)
+    }
+    return &wsConn{conn: conn, br: br}, nil
+}
+
+// writeFrame 写入一个完整的帧。客户端发送的帧必须加掩码。
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+    header := make([]byte, 2, 14)
+    header[0] = 0x80 | opcode // FIN
+    switch n := len(payload); {
+    case n < 126:
+        header[1] = byte(n)
+    case n <= 0xFFFF:
+        header[1] = 126
+        header = binary.BigEndian.AppendUint16(header, uint16(n))
+    default:
+        header[1] = 127
+        header = binary.BigEndian.AppendUint64(header, uint64(n))
+    }
+    header[1] |= 0x80 // MASK
+    mask := make([]byte, 4)
+    if _, err := rand.Read(mask); err != nil {
+        return err
+    }
+    header = append(header, mask...)
+
+    masked := make([]byte, len(payload))
+    for i, b := range payload {
+        masked[i] = b ^ mask[i%4]
+    }
+
+    c.wmu.Lock()
+    defer c.wmu.Unlock()
+    if _, err := c.conn.Write(append(header, masked...)); err != nil {
+        return err
+    }
+    return nil
+}
+
+// WriteMessage 发送一条文本消息。
+func (c *wsConn) WriteMessage(data []byte) error {
+    return c.writeFrame(opText, data)
+}
+
+// readFrame 读取一个帧，返回 FIN 标志、操作码和（已去掉掩码的）负载。
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+    var head [2]byte
+    if _, err := io.ReadFull(c.br, head[:]); err != nil {
+        return false, 0, nil, err
+    }
+    fin := head[0]&0x80 != 0
+    opcode := head[0] & 0x0F
+    masked := head[1]&0x80 != 0
+    length := uint64(head[1] & 0x7F)
+    switch length {
+    case 126:
+        var ext [2]byte
+        if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+            return false, 0, nil, err
+        }
+        length = uint64(binary.BigEndian.Uint16(ext[:]))
+    case 127:
+        var ext [8]byte
+        if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+            return false, 0, nil, err
+        }
+        length = binary.BigEndian.Uint64(ext[:])
+    }
+    if length > maxMessageSize {
+        return false, 0, nil, fmt.Errorf("websocket frame too large: %d bytes", length)
+    }
+    var mask [4]byte
+    if masked {
+        if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+            return false, 0, nil, err
+        }
+    }
+    payload := make([]byte, length)
+    if _, err := io.ReadFull(c.br, payload); err != nil {
+        return false, 0, nil, err
+    }
+    if masked {
+        for i := range payload {
+            payload[i] ^= mask[i%4]
+        }
+    }
+    return fin, opcode, payload, nil
+}
+
+// ReadMessage 读取下一条文本或二进制消息，期间自动回应 ping，收到关闭帧时回应关闭并返回 errConnClosed。
+func (c *wsConn) ReadMessage() ([]byte, error) {
+    var message []byte
+    started := false
+    for {
+        fin, opcode, payload, err := c.readFrame()
+        if err != nil {
+            return nil, err
+        }
+        switch opcode {
+        case opPing:
+            if err := c.writeFrame(opPong, payload); err != nil {
+                return nil, err
+            }
+            continue
+        case opPong:
+            continue
+        case opClose:
+            c.writeFrame(opClose, payload) // 尽力回应，连接随后关闭
+            return nil, errConnClosed
+        case opText, opBinary:
+            if started {
+                return nil, errors.New("websocket protocol error: new message inside a fragmented message")
+            }
+            started = true
+            message = payload
+        case opContinuation:
+            if !started {
+                return nil, errors.New("websocket protocol error: unexpected continuation frame")
+            }
+            if len(message)+len(payload) > maxMessageSize {
+                return nil, fmt.Errorf("websocket message too large: more than %d bytes", maxMessageSize)
+            }
+            message = append(message, payload...)
+        default:
+            return nil, fmt.Errorf("websocket protocol error: unknown opcode %d", opcode)
+        }
+        if fin {
+            return message, nil
+        }
+    }
+}
+
+// Close 发送关闭帧（状态码 1000）并关闭底层连接。
+func (c *wsConn) Close() error {
+    c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+    c.writeFrame(opClose, []byte{0x03, 0xE8})
+    return c.conn.Close()
+}
@@ -0,0 +1,251 @@
+package chrome
+
+import (
+    "chromes/config"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "runtime"
+    "sync"
+)
+
+// browserCandidate 描述一种已知的 Chromium 系浏览器在各操作系统上的常见安装位置。
+type browserCandidate struct {
+    id      string
+    name    string
+    linux   []string // Linux 下在 PATH 中查找的命令名
+    darwin  []string // macOS 下相对于 Applications 目录的可执行文件路径
+    windows []string // Windows 下相对于 Program Files / LocalAppData 的可执行文件路径
+}
+
+// knownBrowsers 按优先级排列，第一个被发现的安装会作为默认浏览器的回退选项。
+var knownBrowsers = []browserCandidate{
+    {
+        id:      "google-chrome",
+        name:    "Google Chrome",
+        linux:   []string{"google-chrome", "google-chrome-stable"},
+        darwin:  []string{"Google Chrome.app/Contents/MacOS/Google Chrome"},
+        windows: []string{`Google\Chrome\Application\chrome.exe`},
+    },
+    {
+        id:      "google-chrome-beta",
+        name:    "Google Chrome Beta",
+        linux:   []string{"google-chrome-beta"},
+        darwin:  []string{"Google Chrome Beta.app/Contents/MacOS/Google Chrome Beta"},
+        windows: []string{`Google\Chrome Beta\Application\chrome.exe`},
+    },
+    {
+        id:      "google-chrome-dev",
+        name:    "Google Chrome Dev",
+        linux:   []string{"google-chrome-unstable"},
+        darwin:  []string{"Google Chrome Dev.app/Contents/MacOS/Google Chrome Dev"},
+        windows: []string{`Google\Chrome Dev\Application\chrome.exe`},
+    },
+    {
+        id:      "google-chrome-canary",
+        name:    "Google Chrome Canary",
+        darwin:  []string{"Google Chrome Canary.app/Contents/MacOS/Google Chrome Canary"},
+        windows: []string{`Google\Chrome SxS\Application\chrome.exe`},
+    },
+    {
+        id:      "chromium",
+        name:    "Chromium",
+        linux:   []string{"chromium", "chromium-browser"},
+        darwin:  []string{"Chromium.app/Contents/MacOS/Chromium"},
+        windows: []string{`Chromium\Application\chrome.exe`},
+    },
+    {
+        id:      "brave",
+        name:    "Brave",
+        linux:   []string{"brave-browser", "brave-browser-stable", "brave"},
+        darwin:  []string{"Brave Browser.app/Contents/MacOS/Brave Browser"},
+        windows: []string{`BraveSoftware\Brave-Browser\Application\brave.exe`},
+    },
+    {
+        id:      "microsoft-edge",
+        name:    "Microsoft Edge",
+        linux:   []string{"microsoft-edge", "microsoft-edge-stable"},
+        darwin:  []string{"Microsoft Edge.app/Contents/MacOS/Microsoft Edge"},
+        windows: []string{`Microsoft\Edge\Application\msedge.exe`},
+    },
+}
+
+// DiscoverBrowsers 在 PATH 和各操作系统的常见安装位置中查找已安装的浏览器。
+// 指向同一个可执行文件的多个候选（例如 google-chrome 与 google-chrome-stable）只保留一个。
+func DiscoverBrowsers() []*config.Browser {
+    var found []*config.Browser
+    seen := make(map[string]bool)
+
+    for _, candidate := range knownBrowsers {
+        for _, path := range candidatePaths(candidate) {
+            resolved, err := filepath.EvalSymlinks(path)
+            if err != nil {
+                continue
+            }
+            if seen[resolved] {
+                break
+            }
+            seen[resolved] = true
+            found = append(found, &config.Browser{
+                ID:         candidate.id,
+                Name:       candidate.name,
+                Path:       path,
+                Discovered: true,
+            })
+            break // 每种浏览器只取第一个可用的位置
+        }
+    }
+    return found
+}
+
+// candidatePaths 返回候选浏览器在当前操作系统上所有可能存在的可执行文件路径。
+func candidatePaths(candidate browserCandidate) []string {
+    var paths []string
+    switch runtime.GOOS {
+    case "darwin":
+        roots := []string{"/Applications"}
+        if homeDir, err := os.UserHomeDir(); err == nil {
+            roots = append(roots, filepath.Join(homeDir, "Applications"))
+        }
+        for _, root := range roots {
+            for _, rel := range candidate.darwin {
+                if isExecutableFile(filepath.Join(root, rel)) {
+                    paths = append(paths, filepath.Join(root, rel))
+                }
+            }
+        }
+    case "windows":
+        var roots []string
+        for _, env := range []string{"ProgramFiles", "ProgramFiles(x86)", "LOCALAPPDATA"} {
+            if dir := os.Getenv(env); dir != "" {
+                roots = append(roots, dir)
+            }
+        }
+        for _, root := range roots {
+            for _, rel := range candidate.windows {
+                if isExecutableFile(filepath.Join(root, rel)) {
+                    paths = append(paths, filepath.Join(root, rel))
+                }
+            }
+        }
+    default:
+        for _, name := range candidate.linux {
+            if path, err := exec.LookPath(name); err == nil {
+                paths = append(paths, path)
+            }
+        }
+    }
+    return paths
+}
+
+// isExecutableFile 检查路径是否指向一个存在的普通文件。
+func isExecutableFile(path string) bool {
+    info, err := os.Stat(path)
+    return err == nil && !info.IsDir()
+}
+
+// Registry 汇总自动发现的和用户手动注册的浏览器安装，并负责为配置解析实际使用的浏览器。
+// 手动注册的安装与自动发现的安装 ID 相同时，手动注册的优先。
+// 全局设置按写时复制的方式保存：已发布的设置不再被修改，修改通过 UpdateSettings 在副本上进行后替换，
+// 因此界面修改设置的同时，控制接口等其他 goroutine 可以安全地读取 Settings 返回的快照。
+type Registry struct {
+    settings   *config.Settings  // 全局设置的当前快照，包含手动注册的安装和默认浏览器
+    discovered []*config.Browser // 最近一次自动发现的安装
+    mu         sync.RWMutex      // 保护 settings 和 discovered 的并发访问
+    updateMu   sync.Mutex        // 串行执行 UpdateSettings，避免并发的修改互相覆盖
+}
+
+// NewRegistry 根据全局设置创建浏览器注册表，并立即执行一次自动发现。
+func NewRegistry(settings *config.Settings) *Registry {
+    r := &Registry{settings: settings}
+    r.Rescan()
+    return r
+}
+
+// Settings 返回注册表当前使用的全局设置。返回的是只读的快照，不能直接修改，修改请使用 UpdateSettings。
+func (r *Registry) Settings() *config.Settings {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    return r.settings
+}
+
+// SetSettings 以 settings 替换注册表使用的全局设置，例如从备份恢复之后。调用者之后不能再修改 settings。
+func (r *Registry) SetSettings(settings *config.Settings) {
+    r.mu.Lock()
+    r.settings = settings
+    r.mu.Unlock()
+}
+
+// UpdateSettings 在当前全局设置的副本上执行 update（通常是 config.SetRouter 等修改并保存设置的函数），
+// 成功后以副本替换当前设置；update 返回错误时当前设置保持不变。
+func (r *Registry) UpdateSettings(update func(settings *config.Settings) error) error {
+    r.updateMu.Lock()
+    defer r.updateMu.Unlock()
+    settings := r.Settings().Clone()
+    if err := update(settings); err != nil {
+        return err
+    }
+    r.SetSettings(settings)
+    return nil
+}
+
+// Rescan 重新扫描系统中已安装的浏览器。
+func (r *Registry) Rescan() {
+    discovered := DiscoverBrowsers()
+    r.mu.Lock()
+    r.discovered = discovered
+    r.mu.Unlock()
+}
+
+// Browsers 返回所有可用的浏览器安装，手动注册的排在前面。
+func (r *Registry) Browsers() []*config.Browser {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    browsers := make([]*config.Browser, 0, len(r.settings.Browsers)+len(r.discovered))
+    browsers = append(browsers, r.settings.Browsers...)
+    for _, b := range r.discovered {
+        if r.settings.FindBrowser(b.ID) == nil {
+            browsers = append(browsers, b)
+        }
+    }
+    return browsers
+}
+
+// Lookup 按 ID 查找浏览器安装，未找到时返回 nil。
+func (r *Registry) Lookup(id string) *config.Browser {
+    for _, b := range r.Browsers() {
+        if b.ID == id {
+            return b
+        }
+    }
+    return nil
+}
+
+// Default 返回全局默认浏览器。
+// 未设置默认浏览器时，回退到第一个可用的安装。
+func (r *Registry) Default() (*config.Browser, error) {
+    if id := r.Settings().DefaultBrowser; id != "" {
+        if b := r.Lookup(id); b != nil {
+            return b, nil
+        }
+        return nil, fmt.Errorf("default browser '%s' is not installed or registered", id)
+    }
+    browsers := r.Browsers()
+    if len(browsers) == 0 {
+        return nil, fmt.Errorf("no browser installation found, please register one manually")
+    }
+    return browsers[0], nil
+}
+
+// Resolve 返回配置实际使用的浏览器安装：优先使用配置指定的，否则使用全局默认浏览器。
+func (r *Registry) Resolve(cfg *config.ChromeConfig) (*config.Browser, error) {
+    if cfg.Browser == "" {
+        return r.Default()
+    }
+    if b := r.Lookup(cfg.Browser); b != nil {
+        return b, nil
+    }
+    return nil, fmt.Errorf("browser '%s' used by config %s is not installed or registered", cfg.Browser, cfg)
+}
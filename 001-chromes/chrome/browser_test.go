@@ -0,0 +1,70 @@
+package chrome
+
+import (
+    "errors"
+    "sync"
+    "testing"
+
+    "chromes/config"
+)
+
+func TestRegistryUpdateSettings(t *testing.T) {
+    registry := NewRegistry(&config.Settings{DefaultFlags: []string{"--lang=en"}, Router: &config.Router{Fallback: "a"}})
+    before := registry.Settings()
+
+    err := registry.UpdateSettings(func(settings *config.Settings) error {
+        settings.DefaultFlags = append(settings.DefaultFlags, "--incognito")
+        settings.Router.Fallback = "b"
+        return errors.New("save failed")
+    })
+    if err == nil {
+        t.Fatal("expected the update error")
+    }
+    if registry.Settings() != before || len(before.DefaultFlags) != 1 || before.Router.Fallback != "a" {
+        t.Fatalf("failed update changed the settings: %+v", registry.Settings())
+    }
+
+    err = registry.UpdateSettings(func(settings *config.Settings) error {
+        settings.DefaultFlags = append(settings.DefaultFlags, "--incognito")
+        settings.Router.Fallback = "b"
+        return nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    after := registry.Settings()
+    if len(after.DefaultFlags) != 2 || after.Router.Fallback != "b" {
+        t.Errorf("update not applied: %+v", after)
+    }
+    // 已发布的快照不会被修改
+    if len(before.DefaultFlags) != 1 || before.Router.Fallback != "a" {
+        t.Errorf("previous snapshot modified: %+v", before)
+    }
+}
+
+// TestRegistrySettingsConcurrentAccess 在 -race 下检查读取设置与修改设置之间没有数据竞争。
+func TestRegistrySettingsConcurrentAccess(t *testing.T) {
+    registry := NewRegistry(&config.Settings{})
+    var wg sync.WaitGroup
+    for i := 0; i < 4; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for j := 0; j < 100; j++ {
+                router := registry.Settings().RouterOrEmpty()
+                _ = len(router.Rules)
+                registry.Default()
+            }
+        }()
+    }
+    for j := 0; j < 100; j++ {
+        registry.UpdateSettings(func(settings *config.Settings) error {
+            settings.Router = settings.RouterOrEmpty().WithRule(&config.RouteRule{Match: config.MatchDomain, Pattern: "example.com", Profile: "a"})
+            return nil
+        })
+    }
+    wg.Wait()
+    if n := len(registry.Settings().Router.Rules); n != 100 {
+        t.Errorf("got %d rules, want 100", n)
+    }
+}
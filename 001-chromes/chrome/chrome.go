@@ -2,425 +2,647 @@ package chrome
 
 import (
     "chromes/config"
+    "chromes/xdg"
+    "context"
     "fmt"
-    "os"
+    "log"
     "os/exec"
     "path/filepath"
+    "regexp"
     "runtime"
     "strconv"
     "strings"
     "sync"
-    "syscall"
+    "time"
 )
 
 // Instance 封装了一个 Chrome 进程及其配置和运行时状态。
-// 它负责管理单个 Chrome 浏览器实例的生命周期。
+// 它负责管理单个 Chrome 浏览器实例的生命周期，状态的每次变化都会作为 Event 发布给订阅者。
 type Instance struct {
-    config    *config.ChromeConfig // 实例的配置信息
-    cmd       *exec.Cmd            // 运行中的 Chrome 进程命令对象
-    isRunning bool                 // 标记 Chrome 实例当前是否正在运行
-    mu        sync.Mutex           // 用于保护对此结构体内部状态（cmd, isRunning）的并发访问
+    config       *config.ChromeConfig // 实例的配置信息
+    registry     *Registry            // 浏览器注册表，用于解析配置使用的浏览器安装
+    browser      *config.Browser      // 最近一次启动时使用的浏览器安装
+    cmd          *exec.Cmd            // 由本程序启动、尚未退出的 Chrome 进程命令对象，重新接管的进程没有 cmd
+    done         chan struct{}        // 由本程序持有（启动或重新接管）的进程退出后关闭，不为 nil 说明正在监视该进程
+    exitErr      error                // 最近一次由本程序启动的进程退出时 cmd.Wait 的返回值
+    exitCode     int                  // 停止过程中主进程退出时的退出码，停止完成时随事件发布
+    pid          int                  // 持有用户数据目录的浏览器主进程号，未知时为 0
+    state        State                // 当前的生命周期状态
+    restart      RestartStatus        // 自动重启情况
+    restarts     []time.Time          // 最近的自动重启时间，用于限制时间窗口内的重启次数
+    restartTimer *time.Timer          // 计划中的自动重启，没有时为 nil
+    exitedPID    int                  // 最近一次退出的、由本程序持有的浏览器主进程号
+    lastStop     StopResult           // 最近一次成功停止时最终生效的步骤
+    headless     bool                 // 最近一次是否以无界面模式启动（见 StartHeadless），自动重启沿用该模式
+    events       broadcaster          // 状态变化的订阅者
+    sink         func(Event)          // 状态变化时额外调用的函数，由 Manager 设置以汇总所有实例的事件
+    mu           sync.Mutex           // 用于保护对此结构体内部状态的并发访问
 }
 
 // NewInstance 根据给定的配置创建一个新的 Instance。
+// 如果本程序上次运行时启动的浏览器仍在运行（见 RuntimeRecord），会重新接管它，否则检测浏览器是否由其他途径启动。
 // cfg: Chrome 配置对象。
+// registry: 浏览器注册表，用于确定启动和匹配进程时使用的可执行文件。
 // 返回一个新的 Instance 指针。
-func NewInstance(cfg *config.ChromeConfig) *Instance {
-    return &Instance{
-        config:    cfg,
-        isRunning: isChromeDirInUse(cfg.UserDataDir),
+func NewInstance(cfg *config.ChromeConfig, registry *Registry) *Instance {
+    ci := &Instance{
+        config:   cfg,
+        registry: registry,
+    }
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+    if !ci.reattach() {
+        ci.detect(nil)
+    }
+    return ci
+}
+
+// reattach 读取运行时记录，记录中的进程仍在运行时重新接管它：进入 StateRunning 并在后台监视其退出。
+// 记录失效时将其删除。返回是否接管成功。调用方需持有 ci.mu。
+func (ci *Instance) reattach() bool {
+    record, err := readRuntimeRecord(ci.config.ID)
+    if err != nil {
+        log.Printf("[record] read failed. config=%v, err=%v", ci.config, err)
+        removeRuntimeRecord(ci.config.ID)
+        return false
+    }
+    if record == nil {
+        return false
+    }
+    if !record.Verify() {
+        log.Printf("[record] process is gone, record removed. config=%v, pid=%d", ci.config, record.PID)
+        removeRuntimeRecord(ci.config.ID)
+        return false
+    }
+
+    ci.pid = record.PID
+    ci.done = make(chan struct{})
+    ci.transition(Event{To: StateRunning, PID: record.PID, ExitCode: noExitCode, Detail: "reattached"})
+    go ci.watchRecord(record, ci.done)
+    return true
+}
+
+// Subscribe 订阅此实例的状态变化，返回接收事件的通道和取消订阅的函数。
+func (ci *Instance) Subscribe() (<-chan Event, func()) {
+    return ci.events.subscribe()
+}
+
+// setSink 设置状态变化时额外调用的函数。
+func (ci *Instance) setSink(sink func(Event)) {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+    ci.sink = sink
+}
+
+// transition 切换到 ev.To 状态并发布事件，调用方需持有 ci.mu。
+// ev 中的 ID、From 和 Time 由此函数填充；状态没有变化且没有错误时不发布事件。
+func (ci *Instance) transition(ev Event) {
+    ev.ID = ci.config.ID
+    ev.From = ci.state
+    ev.Time = time.Now()
+    ci.state = ev.To
+    if ev.From == ev.To && ev.Err == nil {
+        return
+    }
+    log.Printf("[state] config=%v, from=%v, to=%v, pid=%d, exit=%d, detail=%q, err=%v", ci.config, ev.From, ev.To, ev.PID, ev.ExitCode, ev.Detail, ev.Err)
+    ci.events.publish(ev)
+    if ci.sink != nil {
+        ci.sink(ev)
     }
 }
 
+// State 返回实例当前的生命周期状态。
+func (ci *Instance) State() State {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+    return ci.state
+}
+
 // Config 返回此 Chrome 实例的配置信息。
 func (ci *Instance) Config() *config.ChromeConfig {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
     return ci.config
 }
 
+// ID 返回此 Chrome 实例对应配置的 ID。
+func (ci *Instance) ID() string {
+    return ci.Config().ID
+}
+
+// update 将实例切换到重新加载后的配置对象，进程对象保持不变。
+// 不是由本程序持有的实例会按新的配置重新检测运行状态。
+func (ci *Instance) update(cfg *config.ChromeConfig) {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+    ci.config = cfg
+    if cfg.RestartPolicy() == config.RestartNever {
+        ci.cancelRestart()
+    }
+    if ci.done == nil && ci.state != StateStopping {
+        ci.detect(nil)
+    }
+}
+
+// refresh 根据最新的进程列表重新检测由其他途径启动或退出的浏览器，状态变化时发布事件。
+// 由本程序持有的进程由 watch 负责，启动中和停止中的实例由对应的操作负责，这些情况下不做任何事。
+// processes 为 nil 时单独查找进程。
+func (ci *Instance) refresh(processes []Process) {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+    if ci.done != nil || ci.state == StateStarting || ci.state == StateStopping {
+        return
+    }
+    ci.detect(processes)
+}
+
+// detect 检测实例是否正在运行，并记录持有用户数据目录的进程号。调用方需持有 ci.mu。
+// 优先读取用户数据目录中的 SingletonLock：有效的锁说明目录正被使用，失效的锁说明浏览器没有在运行；
+// 没有锁时（例如 Windows 或默认实例）回退到按命令行查找进程。
+// 检测到运行时进入 StateUnknown；检测到没有运行时进入 StateStopped，但保留 StateCrashed。
+// processes 是事先获取的进程列表，为 nil 时单独查找进程。
+func (ci *Instance) detect(processes []Process) {
+    running, pid := ci.probe(processes)
+    switch {
+    case running:
+        ci.pid = pid
+        ci.transition(Event{To: StateUnknown, PID: pid, ExitCode: noExitCode})
+    case ci.state != StateCrashed:
+        ci.pid = 0
+        ci.transition(Event{To: StateStopped, ExitCode: noExitCode})
+    }
+}
+
+// probe 检测是否有浏览器进程正在使用此实例的用户数据目录，返回检测结果和进程号（未知时为 0）。调用方需持有 ci.mu。
+func (ci *Instance) probe(processes []Process) (bool, int) {
+    if dir := ci.config.UserDataDir; dir != "" {
+        lock, err := ReadSingletonLock(dir)
+        if err != nil {
+            log.Printf("[detect] read lock failed. config=%v, err=%v", ci.config, err)
+        }
+        if lock != nil {
+            if lock.IsStale() {
+                return false, 0
+            }
+            return true, lock.PID
+        }
+    }
+    if processes != nil {
+        executable := ci.executable()
+        if executable == "" {
+            return false, 0
+        }
+        pids := matchBrowserProcesses(processes, executable, ci.config.UserDataDir)
+        if len(pids) == 0 {
+            return false, 0
+        }
+        return true, rootPID(processes, pids)
+    }
+    return isChromeDirInUse(ci.executable(), ci.config.UserDataDir), 0
+}
+
+// PID 返回持有用户数据目录的浏览器主进程号：由本程序启动的进程，或者 SingletonLock 记录的进程。未知时返回 0。
+func (ci *Instance) PID() int {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+    return ci.pid
+}
+
+// Lock 读取此实例用户数据目录中的 SingletonLock，没有锁或者是默认实例时返回 nil。
+func (ci *Instance) Lock() (*SingletonLock, error) {
+    dir := ci.Config().UserDataDir
+    if dir == "" {
+        return nil, nil
+    }
+    return ReadSingletonLock(dir)
+}
+
+// CleanStaleLock 删除用户数据目录中已失效的 SingletonLock，使下一次 Start 不会因为“配置文件正被使用”而失败。
+// 返回是否删除了锁；锁仍被存活的进程持有时返回 ErrLockNotStale。
+func (ci *Instance) CleanStaleLock() (bool, error) {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+    if ci.state.Active() {
+        return false, fmt.Errorf("chrome instance %s is running", ci.config)
+    }
+    if ci.config.UserDataDir == "" {
+        return false, nil
+    }
+    return removeStaleSingletonLock(ci.config.UserDataDir)
+}
+
+// Flags 返回启动时附加的额外参数：全局默认参数与配置自身参数按 config.MergeFlags 的规则合并。
+func (ci *Instance) Flags() []string {
+    return config.MergeFlags(ci.registry.Settings().DefaultFlags, ci.config.Flags)
+}
+
+// executable 返回用于匹配进程的浏览器可执行文件路径。
+// 优先使用最近一次启动时的安装，否则按配置解析；无法解析时返回空字符串。
+func (ci *Instance) executable() string {
+    if ci.browser != nil {
+        return ci.browser.Path
+    }
+    if b, err := ci.registry.Resolve(ci.config); err == nil {
+        return b.Path
+    }
+    return ""
+}
+
 // Start 启动 Chrome 实例。
-// 它会根据操作系统类型和配置中的用户数据目录来构建并执行启动命令。
+// 它会根据配置引用的浏览器安装和用户数据目录来构建并执行启动命令。
+// 启动成功后实例自行在后台等待进程退出，退出时发布 StateStopped 或 StateCrashed 事件。
 // 如果实例已在运行，则返回错误。
 func (ci *Instance) Start() error {
     ci.mu.Lock() // 获取锁以修改共享状态
     defer ci.mu.Unlock()
 
-    if ci.isRunning {
-        return fmt.Errorf("chrome instance %s is already running", ci.config.Name)
+    ci.resetRestarts()
+    ci.headless = false
+    return ci.start("")
+}
+
+// start 启动浏览器进程，detail 写入进入 StateStarting 的事件，urls 是启动后打开的网址。调用方需持有 ci.mu。
+func (ci *Instance) start(detail string, urls ...string) error {
+    if ci.state.Active() {
+        return fmt.Errorf("chrome instance %s is already running", ci.config)
     }
 
-    var cmd *exec.Cmd
-    var err error
+    browser, err := ci.registry.Resolve(ci.config)
+    if err != nil {
+        return err
+    }
 
     userDataDir := ci.config.UserDataDir // 从配置中获取用户数据目录
+    args := []string{}
+    if userDataDir != "" {
+        absPath, err := filepath.Abs(userDataDir) // 确保路径是绝对路径
+        if err != nil {
+            return fmt.Errorf("failed to get absolute path for %s: %w", userDataDir, err)
+        }
+        if runtime.GOOS == "windows" {
+            absPath = strings.ReplaceAll(absPath, "/", "\\") // 适配Windows路径分隔符
+        }
+        args = append(args, "--user-data-dir="+absPath)
 
-    // 根据不同操作系统构建 Chrome 启动命令
-    switch runtime.GOOS {
-    case "darwin": // macOS
-        chromePath := "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome"
-        args := []string{}
-        if userDataDir != "" {
-            absPath, err := filepath.Abs(userDataDir) // 确保路径是绝对路径
-            if err != nil {
-                return fmt.Errorf("failed to get absolute path for %s: %w", userDataDir, err)
-            }
-            args = append(args, "--user-data-dir="+absPath)
+        // 目录中的 SingletonLock 说明它已被其他浏览器进程（例如从桌面快捷方式启动的）占用，或者上次没有正常退出
+        lock, err := ReadSingletonLock(absPath)
+        if err != nil {
+            log.Printf("[start] read lock failed. config=%v, err=%v", ci.config, err)
         }
-        args = append(args, "--no-first-run", "--no-default-browser-check") // 添加通用启动参数
-        cmd = exec.Command(chromePath, args...)
-    case "windows":
-        chromePath := "C:\\Program Files\\Google\\Chrome\\Application\\chrome.exe" // Windows Chrome路径
-        args := []string{}
-        if userDataDir != "" {
-            args = append(args, "--user-data-dir="+strings.ReplaceAll(userDataDir, "/", "\\")) // 适配Windows路径分隔符
+        if lock != nil {
+            if lock.IsStale() {
+                return &StaleLockError{Lock: lock}
+            }
+            ci.pid = lock.PID
+            ci.transition(Event{To: StateUnknown, PID: lock.PID, ExitCode: noExitCode})
+            err := &InUseError{Config: ci.config, PID: lock.PID, Headless: ci.headless}
+            ci.headless = false // 持有目录的是其他途径启动的浏览器
+            return err
         }
-        args = append(args, "--no-first-run", "--no-default-browser-check")
-        cmd = exec.Command(chromePath, args...)
-    case "linux":
-        chromePath := "google-chrome" // Linux 下通常的 Chrome 命令
-        args := []string{}
-        if userDataDir != "" {
-            args = append(args, "--user-data-dir="+userDataDir)
+    }
+    if ci.devToolsEnabled() {
+        // 浏览器启动后把实际监听的端口写入 DevToolsActivePort，先删除上次留下的，避免读到过期的端口
+        port, err := allocateDebugPort(ci.config.DebugPort)
+        if err != nil {
+            return err
         }
-        args = append(args, "--no-first-run", "--no-default-browser-check")
-        cmd = exec.Command(chromePath, args...)
-    default: // 其他或未知操作系统
-        args := []string{}
-        if userDataDir != "" {
-            args = append(args, "--user-data-dir="+userDataDir)
+        if err := removeDevToolsActivePort(userDataDir); err != nil {
+            log.Printf("[devtools] remove stale port file failed. config=%v, err=%v", ci.config, err)
         }
-        args = append(args, "--no-first-run", "--no-default-browser-check")
-        cmd = exec.Command("chrome", args...) // 尝试通用 "chrome" 命令
+        args = append(args, "--remote-debugging-port="+strconv.Itoa(port))
+    }
+    if ci.headless {
+        args = append(args, HeadlessFlag)
+    }
+    args = append(args, "--no-first-run", "--no-default-browser-check") // 添加通用启动参数
+    if class := ci.config.WindowClass(); class != "" && xdg.Supported() && !ci.headless {
+        args = append(args, "--class="+class) // 每个配置的窗口单独分组，与 xdg 包生成的桌面启动器对应
+    }
+    args = append(args, LoggingFlags(ci.config.LogLevel)...) // 浏览器自身的日志，放在额外参数之前以便被覆盖
+    args = append(args, ci.Flags()...)                       // 全局默认参数与配置参数合并后的额外参数
+    if len(urls) > 0 {
+        args = append(args, "--") // 之后的参数都是网址，不会被浏览器当作启动参数
+        args = append(args, urls...)
     }
 
+    ci.transition(Event{To: StateStarting, ExitCode: noExitCode, Detail: detail})
+    cmd := exec.Command(browser.Path, args...)
+    // 标准输出和标准错误直接写入日志文件，不经过本程序转发，本程序退出后浏览器的输出也不会丢失
+    logFile, err := openLog(ci.config.ID)
+    if err != nil {
+        log.Printf("[logs] open failed, output discarded. config=%v, err=%v", ci.config, err)
+    } else {
+        writeLogHeader(logFile, browser.Path, args)
+        cmd.Stdout = logFile
+        cmd.Stderr = logFile
+        defer logFile.Close() // 子进程持有自己的副本
+    }
     err = cmd.Start() // 异步启动 Chrome 进程
     if err != nil {
-        return fmt.Errorf("failed to start chrome %s (dir: %s): %w", ci.config.Name, userDataDir, err)
+        err = fmt.Errorf("failed to start %s %s (dir: %s): %w", browser.Name, ci.config, userDataDir, err)
+        ci.transition(Event{To: StateStopped, ExitCode: noExitCode, Err: err})
+        return err
     }
 
-    ci.cmd = cmd        // 保存命令对象
-    ci.isRunning = true // 更新运行状态
+    ci.cmd = cmd // 保存命令对象
+    ci.done = make(chan struct{})
+    ci.pid = cmd.Process.Pid
+    ci.browser = browser
+    if err := writeRuntimeRecord(newRuntimeRecord(ci.config.ID, cmd)); err != nil {
+        log.Printf("[record] write failed. config=%v, err=%v", ci.config, err) // 只影响重启后的重新接管
+    }
+    ci.transition(Event{To: StateRunning, PID: ci.pid, ExitCode: noExitCode})
+    go ci.watch(cmd, ci.done)
     return nil
 }
 
-// Stop 停止 Chrome 实例。
-// 它首先尝试通过已保存的 cmd 对象发送 SIGTERM 信号来优雅地关闭进程。
-// 如果失败或 cmd 对象不存在（例如，应用重启后），则会尝试通过用户数据目录来查找并停止进程。
-func (ci *Instance) Stop() error {
-    ci.mu.Lock() // 获取锁以修改共享状态
+// watch 等待由 Start 启动的进程退出并回收它，然后更新状态。
+// 停止过程中的退出视为正常停止，由停止过程负责切换状态；否则退出码非 0 或被信号结束时视为崩溃。
+func (ci *Instance) watch(cmd *exec.Cmd, done chan struct{}) {
+    err := cmd.Wait()
+    ci.exited(done, cmd.ProcessState.ExitCode(), err)
+}
+
+// watchRecord 轮询重新接管的进程直到它退出，然后更新状态。
+// 重新接管的进程不是本程序的子进程，无法获得退出码，退出总是视为正常停止。
+func (ci *Instance) watchRecord(record *RuntimeRecord, done chan struct{}) {
+    for record.Verify() {
+        time.Sleep(attachPollInterval)
+    }
+    ci.exited(done, noExitCode, nil)
+}
+
+// exited 在由本程序持有的进程退出后清理状态并删除运行时记录，done 标识退出的是哪一次持有的进程。
+func (ci *Instance) exited(done chan struct{}, exitCode int, err error) {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+    defer close(done)
+    ci.exitErr = err
+    if ci.done != done {
+        return // 状态已被外部逻辑重置
+    }
+    ci.cmd = nil
+    ci.done = nil
+    removeRuntimeRecord(ci.config.ID)
+    if ci.state == StateStopping {
+        // 主进程退出后子进程可能仍在运行，由停止过程确认整棵进程树退出后再切换状态
+        ci.exitCode = exitCode
+        return
+    }
+    if ci.state != StateRunning {
+        ci.pid = 0
+        ci.transition(Event{To: StateStopped, ExitCode: exitCode}) // 停止过程已经确认进程退出，此时的退出不视为崩溃
+        return
+    }
+    ci.exitedPID = ci.pid
+    ci.pid = 0
+
+    // 不是通过本程序停止的退出，按重启策略决定是否自动重启
+    ev := Event{To: StateStopped, ExitCode: exitCode}
+    if err != nil {
+        ev.To = StateCrashed
+        ev.Err = fmt.Errorf("chrome instance %s exited unexpectedly: %w", ci.config, err)
+        ci.recordFailure(err)
+    }
+    ev.Detail = ci.scheduleRestart(ev.To == StateCrashed)
+    ci.transition(ev)
+}
+
+// Stop 停止 Chrome 实例，阻塞直到浏览器及其所有子进程退出。
+// 先请求浏览器正常退出，超过全局设置中的期限（config.Settings.StopDeadline）后强制结束，返回最终生效的步骤。
+// 停止期间实例处于 StateStopping，IsRunning 仍返回 true；停止失败时回到原来的状态并发布带有错误的事件。
+func (ci *Instance) Stop() (StopResult, error) {
+    stop, err := ci.beginStop()
+    if err != nil {
+        return StopAlreadyExited, err
+    }
+    return stop()
+}
+
+// beginStop 检查实例是否可以停止并将其标记为停止中，返回执行实际停止过程的函数。
+// 需要停止的进程依次取自：本程序启动的进程、SingletonLock 记录的进程、按用户数据目录查找到的进程。
+func (ci *Instance) beginStop() (func() (StopResult, error), error) {
+    ci.mu.Lock()
     defer ci.mu.Unlock()
 
-    if !ci.isRunning {
-        return fmt.Errorf("chrome instance %s is not running", ci.config.Name)
+    if ci.state == StateStopping {
+        return nil, fmt.Errorf("chrome instance %s is already stopping", ci.config)
+    }
+    ci.cancelRestart() // 由用户停止的浏览器不会被自动重启
+    if !ci.state.Active() {
+        return nil, fmt.Errorf("chrome instance %s is not running", ci.config)
     }
 
-    // 优先尝试通过已知的进程对象停止
-    if ci.cmd != nil && ci.cmd.Process != nil {
-        err := ci.cmd.Process.Signal(syscall.SIGTERM) // 发送终止信号
-        if err != nil {
-            // 如果发送信号失败 (例如进程已自行退出)，则尝试备用方法
-            // 这也处理了进程可能已经不存在的情况
-            stopped, stopErr := chromeStop(ci.config.UserDataDir)
-            if stopErr != nil {
-                return fmt.Errorf("failed to send signal to %s and fallback stop failed: %v, fallback error: %v", ci.config.Name, err, stopErr)
-            }
-            if !stopped {
-                // 备用方法也未能找到或停止进程
-                return fmt.Errorf("failed to send signal to %s and fallback stop could not find process: %v", ci.config.Name, err)
-            }
-        }
-        // 信号发送成功或备用方法成功后，标记为非运行
-        // Wait() goroutine (如果存在) 会处理 cmd.Wait() 的返回
+    var roots []int
+    if ci.pid > 0 {
+        // 由本程序启动或重新接管的进程，或者 SingletonLock 记录的进程
+        roots = []int{ci.pid}
     } else {
         // 如果没有 cmd 对象 (例如应用重启后，只知道配置和目录)
-        // 则直接尝试通过用户数据目录停止
-        stopped, err := chromeStop(ci.config.UserDataDir)
+        // 则通过用户数据目录查找进程
+        pids, err := findBrowserPIDs(ci.executable(), ci.config.UserDataDir)
         if err != nil {
-            return fmt.Errorf("failed to stop chrome %s (dir: %s) by user data dir: %w", ci.config.Name, ci.config.UserDataDir, err)
+            return nil, fmt.Errorf("failed to find chrome %s (dir: %s) by user data dir: %w", ci.config, ci.config.UserDataDir, err)
         }
-        if !stopped {
-            // 无法通过用户数据目录找到并停止进程
-            return fmt.Errorf("could not find chrome process for %s (dir: %s) to stop", ci.config.Name, ci.config.UserDataDir)
+        if len(pids) == 0 {
+            // 无法通过用户数据目录找到进程
+            return nil, fmt.Errorf("could not find chrome process for %s (dir: %s) to stop", ci.config, ci.config.UserDataDir)
         }
+        roots = pids
     }
 
-    ci.isRunning = false // 标记为已停止
-    // ci.cmd = nil // cmd 的清理最好在 Wait() 成功返回后，或 chromeStop 确认进程已消失后
-    // 此处仅更新 isRunning 状态，UI 会据此刷新
-    return nil
+    previous := ci.state
+    cfg := ci.config
+    var closeBrowser func() error
+    if ci.devToolsEnabled() {
+        // 开启了远程调试的浏览器先通过 Browser.close 关闭，避免下次启动时提示未正常关闭
+        closeBrowser = func() error {
+            ctx, cancel := context.WithTimeout(context.Background(), devToolsTimeout*2)
+            defer cancel()
+            return closeViaDevTools(ctx, cfg.UserDataDir)
+        }
+    }
+    ci.exitCode = noExitCode
+    timeout := ci.registry.Settings().StopDeadline()
+    ci.transition(Event{To: StateStopping, PID: roots[0], ExitCode: noExitCode})
+    return func() (StopResult, error) {
+        result, err := stopProcessTree(roots, timeout, closeBrowser)
+        log.Printf("[stop] finished. config=%v, pids=%v, result=%v, err=%v", cfg, roots, result, err)
+
+        ci.mu.Lock()
+        defer ci.mu.Unlock()
+        if err != nil {
+            err = fmt.Errorf("failed to stop chrome %s: %w", cfg, err)
+            if ci.state == StateStopping {
+                if previous == StateRunning && ci.done == nil {
+                    previous = StateUnknown // 主进程已被回收，剩下的进程不再由本程序持有
+                }
+                ci.transition(Event{To: previous, PID: ci.pid, ExitCode: noExitCode, Err: err})
+            }
+            return result, err
+        }
+        // 进程已确认退出；由本程序启动的进程如果还没有被 watch 回收，之后的回收不会再改变状态
+        ci.lastStop = result
+        if ci.state == StateStopping {
+            ci.pid = 0
+            removeRuntimeRecord(cfg.ID)
+            ci.transition(Event{To: StateStopped, ExitCode: ci.exitCode, Detail: result.String()})
+        }
+        return result, nil
+    }, nil
 }
 
-// IsRunning 返回 Chrome 实例是否正在运行。
-// 它会检查 isRunning 标志，并且如果存在 cmd 对象，还会检查进程是否已退出。
-func (ci *Instance) IsRunning() bool {
+// LastStop 返回最近一次成功停止时最终生效的步骤，例如是否通过 DevTools 正常关闭或被强制结束。
+func (ci *Instance) LastStop() StopResult {
     ci.mu.Lock()
     defer ci.mu.Unlock()
+    return ci.lastStop
+}
 
-    // 如果有 cmd 对象并且其关联的进程已退出，则更新状态
-    if ci.cmd != nil && ci.cmd.ProcessState != nil && ci.cmd.ProcessState.Exited() {
-        ci.isRunning = false
-        ci.cmd = nil // 清理已退出的进程命令对象
-    }
-    return ci.isRunning
+// IsStopping 返回实例是否正在停止：已请求浏览器退出，但还没有确认所有进程都已退出。
+func (ci *Instance) IsStopping() bool {
+    return ci.State() == StateStopping
+}
+
+// IsRunning 返回是否有浏览器进程在使用此实例的配置，包括启动中、停止中和外部启动的浏览器。
+func (ci *Instance) IsRunning() bool {
+    return ci.State().Active()
 }
 
 // SetRunningState 允许外部逻辑（例如，应用启动时通过 isChromeDirInUse 检测）更新实例的运行状态。
-// isRunning: true 表示正在运行, false 表示已停止。
+// isRunning: true 表示正在运行（不是由本程序启动时为 StateUnknown）, false 表示已停止。
 func (ci *Instance) SetRunningState(isRunning bool) {
     ci.mu.Lock()
     defer ci.mu.Unlock()
-    ci.isRunning = isRunning
-    if !isRunning {
-        ci.cmd = nil // 如果设置为非运行状态，则清除 cmd 对象
+    if isRunning {
+        if !ci.state.Active() {
+            ci.transition(Event{To: StateUnknown, PID: ci.pid, ExitCode: noExitCode})
+        }
+        return
     }
+    ci.cmd = nil // 如果设置为非运行状态，则清除 cmd 对象，watch 不再更新状态
+    ci.done = nil
+    ci.pid = 0
+    removeRuntimeRecord(ci.config.ID)
+    ci.transition(Event{To: StateStopped, ExitCode: noExitCode})
 }
 
-// Wait 等待由 Start() 方法启动的 Chrome 进程结束。
-// 此方法是阻塞的，通常应该在一个单独的 goroutine 中调用。
-// 当进程退出后，它会更新实例的运行状态。
+// Wait 等待由 Start() 方法启动（或重新接管）的 Chrome 进程结束。
+// 进程由实例自行在后台回收并更新状态，此方法只是阻塞到那时为止；没有由本程序持有的进程时立即返回 nil。
 // 返回进程的退出错误（如果有）。
 func (ci *Instance) Wait() error {
-    currentCmd := func() *exec.Cmd { // Safely get current command
-        ci.mu.Lock()
-        defer ci.mu.Unlock()
-        return ci.cmd
-    }()
-
-    if currentCmd == nil {
-        ci.mu.Lock()
-        wasRunning := ci.isRunning
-        ci.isRunning = false // 确保状态一致性
-        ci.cmd = nil         // 确保 cmd 清理
-        ci.mu.Unlock()
-        if wasRunning {
-        }
+    ci.mu.Lock()
+    done := ci.done
+    ci.mu.Unlock()
+    if done == nil {
         return nil
     }
 
-    err := currentCmd.Wait() // 等待进程退出
-
-    // 进程已退出，更新状态
+    <-done
     ci.mu.Lock()
-    ci.isRunning = false
-    ci.cmd = nil // 清理命令对象
-    ci.mu.Unlock()
-
-    return err // 返回 Wait 的错误（通常是 nil 或 *ExitError）
+    defer ci.mu.Unlock()
+    return ci.exitErr // 返回 Wait 的错误（通常是 nil 或 *ExitError）
 }
 
-// isChromeDirInUse 检查指定的用户数据目录是否被Chrome进程正在使用
-// 如果 userDataDir 为空字符串，则检查默认的 Chrome 实例（未明确指定 --user-data-dir 的实例）是否正在运行
-func isChromeDirInUse(userDataDir string) bool {
-    var cmd *exec.Cmd
-    var output []byte
-    var err error
-
-    if userDataDir == "" { // Check for default instance (no --user-data-dir arg)
-        switch runtime.GOOS {
-        case "darwin":
-            script := `ps -eo command | grep "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome" | grep -v grep | grep -v -- '--user-data-dir='`
-            cmd = exec.Command("sh", "-c", script)
-        case "windows":
-            psScript := `Get-CimInstance Win32_Process -Filter "Name='chrome.exe'" | Where-Object {$_.CommandLine -notlike '*--user-data-dir=*'} | Select-Object -ExpandProperty ProcessId`
-            cmd = exec.Command("powershell", "-Command", psScript)
-        case "linux":
-            script := `ps -eo command | grep -E '(^|/)google-chrome( |$)' | grep -v grep | grep -v -- '--user-data-dir='`
-            cmd = exec.Command("sh", "-c", script)
-        default:
-            return false // Unsupported OS for this specific default check
-        }
-        output, err = cmd.Output()
-        if err != nil {
-            return false // Error executing command or no process found
-        }
-        return len(strings.TrimSpace(string(output))) > 0
-
-    } else { // Check for specific userDataDir
-        absUserDataDir := userDataDir
-        var errPath error
-        if runtime.GOOS == "darwin" || runtime.GOOS == "linux" {
-            absUserDataDir, errPath = filepath.Abs(userDataDir)
-            if errPath != nil {
-                fmt.Printf("Warning: could not get absolute path for %s: %v\n", userDataDir, errPath)
-                absUserDataDir = userDataDir
-            }
-        } else if runtime.GOOS == "windows" {
-            absUserDataDir, errPath = filepath.Abs(userDataDir)
-            if errPath != nil {
-                fmt.Printf("Warning: could not get absolute path for %s: %v\n", userDataDir, errPath)
-                absUserDataDir = userDataDir
-            }
-            absUserDataDir = strings.ReplaceAll(absUserDataDir, "/", "\\\\")
-        }
+// isChromeDirInUse 检查指定的用户数据目录是否被 executable 对应的浏览器进程正在使用
+// 如果 userDataDir 为空字符串，则检查默认的浏览器实例（未明确指定 --user-data-dir 的实例）是否正在运行
+func isChromeDirInUse(executable string, userDataDir string) bool {
+    if executable == "" {
+        return false // 无法确定浏览器安装时无从匹配
+    }
+    pids, err := findBrowserPIDs(executable, userDataDir)
+    return err == nil && len(pids) > 0
+}
 
-        switch runtime.GOOS {
-        case "darwin":
-            chromeExecutablePath := "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome"
-            cmd = exec.Command("pgrep", "-f", fmt.Sprintf("%s.*--user-data-dir=%s", chromeExecutablePath, absUserDataDir))
-        case "windows":
-            psScript := fmt.Sprintf(`Get-CimInstance Win32_Process -Filter "Name='chrome.exe' AND CommandLine LIKE '%%%%--user-data-dir=%s%%%%'" | Select-Object -ExpandProperty ProcessId`, absUserDataDir)
-            cmd = exec.Command("powershell", "-Command", psScript)
-        case "linux":
-            cmd = exec.Command("pgrep", "-f", fmt.Sprintf("chrome.*--user-data-dir=%s", absUserDataDir))
-        default:
-            cmd = exec.Command("pgrep", "-f", fmt.Sprintf("chrome.*--user-data-dir=%s", absUserDataDir))
+// findBrowserPIDs 查找属于 executable 对应浏览器安装、并使用 userDataDir 的进程。
+// 有 defaultProcessLister 时逐个解析进程的命令行并精确比较 --user-data-dir，否则回退到 findBrowserPIDsByCommand。
+func findBrowserPIDs(executable string, userDataDir string) ([]int, error) {
+    if defaultProcessLister != nil {
+        processes, err := defaultProcessLister.Processes()
+        if err == nil {
+            return matchBrowserProcesses(processes, executable, userDataDir), nil
         }
-        output, err = cmd.Output()
-        if err != nil {
-            return false // Error executing command or no process found
-        }
-        return len(strings.TrimSpace(string(output))) > 0
+        log.Printf("[process] list failed, falling back to commands. err=%v", err)
     }
+    return findBrowserPIDsByCommand(executable, userDataDir)
 }
 
-// chromeStop 通过用户数据目录停止Chrome进程
-// 如果 userDataDir 为空字符串，则尝试停止默认的 Chrome 实例（未指定 --user-data-dir）
-func chromeStop(userDataDir string) (bool, error) {
-    var pidsToKill []string
+// findBrowserPIDsByCommand 通过 ps/pgrep（macOS、Linux）或 PowerShell（Windows）查找浏览器进程。
+// 这些命令按子串或正则匹配命令行，无法区分以相同路径开头的用户数据目录，仅在无法读取进程列表时使用。
+func findBrowserPIDsByCommand(executable string, userDataDir string) ([]int, error) {
+    binary := binaryPattern(resolveBrowserBinary(executable))
+    exeName := filepath.Base(executable)
 
-    if userDataDir == "" { // Stop default instance (no --user-data-dir arg)
-        var cmd *exec.Cmd
-        var output []byte
-        var err error
+    var cmd *exec.Cmd
+    if userDataDir == "" { // Find default instance (no --user-data-dir arg)
         switch runtime.GOOS {
-        case "darwin":
-            script := `ps -eo pid,command | grep "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome" | grep -v grep | grep -v -- '--user-data-dir=' | awk '{print $1}'`
-            cmd = exec.Command("sh", "-c", script)
+        case "darwin", "linux":
+            // 主程序的正则作为位置参数传入，避免路径中的空格等字符破坏脚本
+            script := `ps -eo pid,command | grep -E -- "^ *[0-9]+ $1( |$)" | grep -v -- '--user-data-dir=' | grep -v -- '--type=crashpad-handler' | awk '{print $1}'`
+            cmd = exec.Command("sh", "-c", script, "sh", binary)
         case "windows":
-            psScript := `(Get-CimInstance Win32_Process -Filter "Name='chrome.exe'" | Where-Object {$_.CommandLine -notlike '*--user-data-dir=*'} | Select-Object -ExpandProperty ProcessId) -join ','`
+            psScript := fmt.Sprintf(`(Get-CimInstance Win32_Process -Filter "Name='%s'" | Where-Object {$_.CommandLine -notlike '*--user-data-dir=*'} | Select-Object -ExpandProperty ProcessId) -join ','`, exeName)
             cmd = exec.Command("powershell", "-Command", psScript)
-        case "linux":
-            script := `ps -eo pid,command | grep -E '(^|/)google-chrome( |$)' | grep -v grep | grep -v -- '--user-data-dir=' | awk '{print $1}'`
-            cmd = exec.Command("sh", "-c", script)
         default:
-            return false, fmt.Errorf("unsupported OS for stopping default chrome instance")
-        }
-        output, err = cmd.Output()
-        if err != nil {
-            if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && len(output) == 0 {
-                return false, nil // No process found
-            }
-            return false, fmt.Errorf("failed to find default chrome process to stop: %w", err)
-        }
-        pidsStr := strings.TrimSpace(string(output))
-        if pidsStr == "" {
-            return false, nil // No process found
+            return nil, fmt.Errorf("unsupported OS for finding default chrome instance")
         }
-        if runtime.GOOS == "windows" {
-            pidsToKill = strings.Split(pidsStr, ",")
-        } else {
-            pidsToKill = strings.Split(pidsStr, "\n")
-        }
-
-    } else { // Stop instance with specific userDataDir
-        var findCmd *exec.Cmd
-        var output []byte
-        var err error
-        absUserDataDir := userDataDir
-        var errPath error
-
-        if runtime.GOOS == "darwin" || runtime.GOOS == "linux" {
-            absUserDataDir, errPath = filepath.Abs(userDataDir)
-            if errPath != nil {
-                fmt.Printf("Warning: could not get absolute path for %s: %v\n", userDataDir, errPath)
-                absUserDataDir = userDataDir
-            }
-        } else if runtime.GOOS == "windows" {
-            absUserDataDir, errPath = filepath.Abs(userDataDir)
-            if errPath != nil {
-                fmt.Printf("Warning: could not get absolute path for %s: %v\n", userDataDir, errPath)
-                absUserDataDir = userDataDir
-            }
-            absUserDataDir = strings.ReplaceAll(absUserDataDir, "/", "\\\\")
+    } else { // Find instance with specific userDataDir
+        absUserDataDir, errPath := filepath.Abs(userDataDir)
+        if errPath != nil {
+            log.Printf("[process] abs path failed. dir=%v, err=%v", userDataDir, errPath)
+            absUserDataDir = userDataDir
         }
 
         switch runtime.GOOS {
-        case "darwin":
-            chromeExecutablePath := "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome"
-            pgrepPattern := fmt.Sprintf("%s.*--user-data-dir=%s", chromeExecutablePath, absUserDataDir)
-            findCmd = exec.Command("pgrep", "-f", pgrepPattern)
+        case "darwin", "linux":
+            cmd = exec.Command("pgrep", "-f", processPattern(binary, absUserDataDir))
         case "windows":
-            psScript := fmt.Sprintf(`(Get-CimInstance Win32_Process -Filter "Name='chrome.exe' AND CommandLine LIKE '%%%%--user-data-dir=%s%%%%'" | Select-Object -ExpandProperty ProcessId) -join ','`, absUserDataDir)
-            findCmd = exec.Command("powershell", "-Command", psScript)
-        case "linux":
-            pgrepPattern := fmt.Sprintf("chrome.*--user-data-dir=%s", absUserDataDir)
-            findCmd = exec.Command("pgrep", "-f", pgrepPattern)
+            absUserDataDir = strings.ReplaceAll(absUserDataDir, "/", "\\\\")
+            psScript := fmt.Sprintf(`(Get-CimInstance Win32_Process -Filter "Name='%s' AND CommandLine LIKE '%%%%--user-data-dir=%s%%%%'" | Select-Object -ExpandProperty ProcessId) -join ','`, exeName, absUserDataDir)
+            cmd = exec.Command("powershell", "-Command", psScript)
         default:
-            return false, fmt.Errorf("unsupported OS for stopping chrome instance by user data dir: %s", runtime.GOOS)
-        }
-
-        output, err = findCmd.Output()
-        if err != nil {
-            if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-                return false, nil // No process found
-            }
-            return false, fmt.Errorf("find process failed for %s: %w", userDataDir, err)
-        }
-        pidsStr := strings.TrimSpace(string(output))
-        if pidsStr == "" {
-            return false, nil // No process found
-        }
-        if runtime.GOOS == "windows" {
-            pidsToKill = strings.Split(pidsStr, ",")
-        } else {
-            pidsToKill = strings.Split(pidsStr, "\n")
+            return nil, fmt.Errorf("unsupported OS for finding chrome instance by user data dir: %s", runtime.GOOS)
         }
     }
 
-    if len(pidsToKill) == 0 || (len(pidsToKill) == 1 && strings.TrimSpace(pidsToKill[0]) == "") {
-        return false, nil // No PIDs found or only empty strings
+    output, err := cmd.Output()
+    if err != nil {
+        if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+            return nil, nil // No process found
+        }
+        return nil, fmt.Errorf("find process failed for %s: %w", userDataDir, err)
     }
 
-    killedAtLeastOne := false
-    var lastKillError error
-    for _, pidStr := range pidsToKill {
-        pidStr = strings.TrimSpace(pidStr)
-        if pidStr == "" {
-            continue
-        }
-        pid, err := strconv.Atoi(pidStr)
+    var pids []int
+    for _, field := range strings.FieldsFunc(string(output), func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+        pid, err := strconv.Atoi(strings.TrimSpace(field))
         if err != nil {
-            fmt.Printf("Warning: invalid PID string \"%s\": %v\n", pidStr, err)
-            lastKillError = fmt.Errorf("invalid PID string \"%s\": %w", pidStr, err)
+            log.Printf("[process] invalid pid. value=%q, err=%v", field, err)
             continue
         }
+        pids = append(pids, pid)
+    }
+    return pids, nil
+}
 
-        var killErr error
-        if runtime.GOOS == "windows" {
-            killCmd := exec.Command("taskkill", "/PID", strconv.Itoa(pid), "/F")
-            killErr = killCmd.Run()
-        } else {
-            process, err := os.FindProcess(pid)
-            if err != nil {
-                if err == syscall.ESRCH || strings.Contains(err.Error(), "process already finished") {
-                    killedAtLeastOne = true
-                    continue
-                }
-                fmt.Printf("Warning: failed to find process for PID %d: %v\n", pid, err)
-                lastKillError = fmt.Errorf("failed to find process for PID %d: %w", pid, err)
-                continue
-            }
-            killErr = process.Signal(syscall.SIGTERM)
-        }
-
-        if killErr != nil {
-            if runtime.GOOS == "windows" {
-                if exitErr, ok := killErr.(*exec.ExitError); ok && exitErr.ExitCode() == 128 {
-                    killedAtLeastOne = true
-                    continue
-                }
-            } else if killErr == syscall.ESRCH {
-                killedAtLeastOne = true
-                continue
-            }
-            fmt.Printf("Warning: failed to send SIGTERM to PID %d: %v\n", pid, killErr)
-            lastKillError = fmt.Errorf("failed to kill PID %d: %w", pid, killErr)
-        } else {
-            killedAtLeastOne = true
+// binaryPattern 构建匹配浏览器主程序的扩展正则（ERE）：命令行的第一个参数是主程序的完整路径，或文件名是主程序可能使用的名字。
+func binaryPattern(b browserBinary) string {
+    var alternatives []string
+    if b.path != "" {
+        alternatives = append(alternatives, regexp.QuoteMeta(b.path))
+    }
+    if len(b.names) > 0 {
+        names := make([]string, len(b.names))
+        for i, name := range b.names {
+            names[i] = regexp.QuoteMeta(name)
         }
+        alternatives = append(alternatives, "([^ ]*/)?("+strings.Join(names, "|")+")")
     }
+    return "(" + strings.Join(alternatives, "|") + ")"
+}
 
-    if !killedAtLeastOne && lastKillError != nil {
-        return false, lastKillError
-    }
-    return killedAtLeastOne, nil
+// processPattern 构建 pgrep -f 使用的正则：进程命令行以浏览器主程序开头，且带有指定的 --user-data-dir。
+// 正则以参数结束或空白结尾，避免 /p/work 匹配到 /p/work2。
+func processPattern(binary string, absUserDataDir string) string {
+    return "^" + binary + "( .*)? --user-data-dir=" + regexp.QuoteMeta(absUserDataDir) + "( |$)"
 }
@@ -0,0 +1,210 @@
+package chrome
+
+import (
+    "bufio"
+    "chromes/cdp"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// devToolsActivePortFile 是浏览器开启远程调试后写入用户数据目录的文件，
+// 第一行为实际监听的端口，第二行为浏览器级 WebSocket 端点的路径。
+const devToolsActivePortFile = "DevToolsActivePort"
+
+// devToolsTimeout 是访问 DevTools HTTP 接口的超时时间。
+const devToolsTimeout = 2 * time.Second
+
+// DevToolsEndpoint 描述一个正在运行的浏览器的远程调试端点，可以交给 Puppeteer/Playwright 等工具连接。
+type DevToolsEndpoint struct {
+    Port                 int    // 监听的本机端口
+    HTTPURL              string // DevTools HTTP 接口的地址，例如 "http://127.0.0.1:9222"
+    WebSocketDebuggerURL string // 浏览器级 WebSocket 端点，例如 "ws://127.0.0.1:9222/devtools/browser/<id>"
+    Browser              string // 浏览器报告的产品名和版本，例如 "Chrome/126.0.6478.126"
+}
+
+// PortInUseError 表示配置指定的远程调试端口已被其他程序占用。
+type PortInUseError struct {
+    Port int
+    Err  error
+}
+
+func (e *PortInUseError) Error() string {
+    return fmt.Sprintf("remote debugging port %d is already in use: %v", e.Port, e.Err)
+}
+
+func (e *PortInUseError) Unwrap() error {
+    return e.Err
+}
+
+// allocateDebugPort 返回启动浏览器时使用的远程调试端口。
+// fixed 大于 0 时检查该端口在本机回环地址上是否可用，被占用时返回 *PortInUseError；
+// 否则由系统分配一个空闲端口。检查与浏览器实际监听之间仍有短暂的间隔，极少数情况下端口可能被抢占，
+// 此时浏览器不会开启远程调试，DevTools 返回错误。
+func allocateDebugPort(fixed int) (int, error) {
+    listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(fixed)))
+    if err != nil {
+        if fixed > 0 {
+            return 0, &PortInUseError{Port: fixed, Err: err}
+        }
+        return 0, fmt.Errorf("failed to allocate remote debugging port: %w", err)
+    }
+    defer listener.Close()
+    return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// ReadDevToolsActivePort 读取用户数据目录中的 DevToolsActivePort，返回端口和浏览器级 WebSocket 端点的路径。
+// 文件不存在时返回的错误满足 os.IsNotExist。
+func ReadDevToolsActivePort(userDataDir string) (int, string, error) {
+    path := filepath.Join(userDataDir, devToolsActivePortFile)
+    f, err := os.Open(path)
+    if err != nil {
+        return 0, "", err
+    }
+    defer f.Close()
+
+    scanner := bufio.NewScanner(f)
+    var lines []string
+    for scanner.Scan() && len(lines) < 2 {
+        lines = append(lines, strings.TrimSpace(scanner.Text()))
+    }
+    if err := scanner.Err(); err != nil {
+        return 0, "", fmt.Errorf("failed to read %s: %w", path, err)
+    }
+    if len(lines) < 2 {
+        return 0, "", fmt.Errorf("malformed %s: expected port and path", path)
+    }
+    port, err := strconv.Atoi(lines[0])
+    if err != nil || port <= 0 || port > 65535 {
+        return 0, "", fmt.Errorf("malformed %s: invalid port '%s'", path, lines[0])
+    }
+    return port, lines[1], nil
+}
+
+// removeDevToolsActivePort 删除用户数据目录中上次运行留下的 DevToolsActivePort，避免启动后读到过期的端口。
+func removeDevToolsActivePort(userDataDir string) error {
+    err := os.Remove(filepath.Join(userDataDir, devToolsActivePortFile))
+    if err != nil && !os.IsNotExist(err) {
+        return err
+    }
+    return nil
+}
+
+// QueryDevTools 访问 host:port 上的 DevTools HTTP 接口 /json/version，返回浏览器的调试端点。
+// 既用于确认端口上确实是正在运行的浏览器，也用于取得浏览器报告的 webSocketDebuggerUrl。
+func QueryDevTools(ctx context.Context, host string, port int) (*DevToolsEndpoint, error) {
+    ctx, cancel := context.WithTimeout(ctx, devToolsTimeout)
+    defer cancel()
+
+    httpURL := "http://" + net.JoinHostPort(host, strconv.Itoa(port))
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, httpURL+"/json/version", nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("devtools endpoint %s is not reachable: %w", httpURL, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("devtools endpoint %s returned %s", httpURL, resp.Status)
+    }
+
+    var version struct {
+        Browser              string `json:"Browser"`
+        WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+        return nil, fmt.Errorf("invalid response from devtools endpoint %s: %w", httpURL, err)
+    }
+    if version.WebSocketDebuggerURL == "" {
+        return nil, fmt.Errorf("devtools endpoint %s did not report webSocketDebuggerUrl", httpURL)
+    }
+    return &DevToolsEndpoint{
+        Port:                 port,
+        HTTPURL:              httpURL,
+        WebSocketDebuggerURL: version.WebSocketDebuggerURL,
+        Browser:              version.Browser,
+    }, nil
+}
+
+// ErrDevToolsDisabled 表示实例的配置没有开启远程调试。
+var ErrDevToolsDisabled = errors.New("remote debugging is not enabled for this profile")
+
+// DevTools 返回正在运行的实例的远程调试端点。
+// 端口取自浏览器启动后写入用户数据目录的 DevToolsActivePort，并通过 /json/version 确认浏览器仍在监听；
+// 浏览器刚启动、尚未写入该文件时返回的错误满足 os.IsNotExist，稍后重试即可。
+func (ci *Instance) DevTools(ctx context.Context) (*DevToolsEndpoint, error) {
+    ci.mu.Lock()
+    cfg, state, enabled := ci.config, ci.state, ci.devToolsEnabled()
+    ci.mu.Unlock()
+
+    if !enabled {
+        return nil, ErrDevToolsDisabled
+    }
+    if state != StateRunning && state != StateUnknown {
+        return nil, fmt.Errorf("chrome instance %s is not running", cfg)
+    }
+    return devToolsEndpoint(ctx, cfg.UserDataDir)
+}
+
+// devToolsEnabled 返回实例是否开启了远程调试：配置开启了远程调试，或者以无界面模式启动。
+// 端口通过用户数据目录中的 DevToolsActivePort 读取，因此要求配置了数据目录。调用方需持有 ci.mu。
+func (ci *Instance) devToolsEnabled() bool {
+    return (ci.config.DevTools || ci.headless) && ci.config.UserDataDir != ""
+}
+
+// devToolsEndpoint 读取使用 userDataDir 的浏览器的远程调试端点，不检查实例状态。
+func devToolsEndpoint(ctx context.Context, userDataDir string) (*DevToolsEndpoint, error) {
+    port, path, err := ReadDevToolsActivePort(userDataDir)
+    if err != nil {
+        return nil, err
+    }
+    endpoint, err := QueryDevTools(ctx, "127.0.0.1", port)
+    if err != nil {
+        return nil, err
+    }
+    if !strings.HasSuffix(endpoint.WebSocketDebuggerURL, path) {
+        // 端口上是另一个浏览器，说明 DevToolsActivePort 是过期的
+        return nil, fmt.Errorf("devtools endpoint on port %d belongs to another browser (expected %s, got %s)", port, path, endpoint.WebSocketDebuggerURL)
+    }
+    return endpoint, nil
+}
+
+// closeViaDevTools 通过远程调试端点发送 Browser.close，请求浏览器像从菜单退出一样关闭。
+// 只负责发送命令，不等待进程退出。
+func closeViaDevTools(ctx context.Context, userDataDir string) error {
+    endpoint, err := devToolsEndpoint(ctx, userDataDir)
+    if err != nil {
+        return err
+    }
+    client, err := cdp.Dial(ctx, endpoint.WebSocketDebuggerURL)
+    if err != nil {
+        return err
+    }
+    defer client.Close()
+    return client.CloseBrowser(ctx)
+}
+
+// WaitDevTools 等待刚启动的实例开启远程调试端点，直到成功、ctx 结束或遇到不可重试的错误。
+func (ci *Instance) WaitDevTools(ctx context.Context) (*DevToolsEndpoint, error) {
+    for {
+        endpoint, err := ci.DevTools(ctx)
+        if err == nil || (!os.IsNotExist(err) && ci.State() != StateStarting) {
+            return endpoint, err
+        }
+        select {
+        case <-ctx.Done():
+            return nil, fmt.Errorf("timed out waiting for devtools endpoint: %w", err)
+        case <-time.After(pollInterval):
+        }
+    }
+}
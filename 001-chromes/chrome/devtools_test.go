@@ -0,0 +1,236 @@
+package chrome
+
+import (
+    "chromes/config"
+    "context"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// fakeDevTools 启动一个模拟 DevTools HTTP 接口的服务器，/json/version 返回 body，返回服务器和端口。
+func fakeDevTools(t *testing.T, status int, body string) (*httptest.Server, int) {
+    t.Helper()
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/json/version" {
+            http.NotFound(w, r)
+            return
+        }
+        w.WriteHeader(status)
+        fmt.Fprint(w, body)
+    }))
+    t.Cleanup(server.Close)
+    return server, server.Listener.Addr().(*net.TCPAddr).Port
+}
+
+func writeActivePort(t *testing.T, dir string, content string) {
+    t.Helper()
+    if err := os.WriteFile(filepath.Join(dir, devToolsActivePortFile), []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestQueryDevTools(t *testing.T) {
+    _, port := fakeDevTools(t, http.StatusOK, `{"Browser":"Chrome/126.0.6478.126","webSocketDebuggerUrl":"ws://127.0.0.1:1/devtools/browser/abc"}`)
+    endpoint, err := QueryDevTools(context.Background(), "127.0.0.1", port)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if endpoint.Port != port || endpoint.HTTPURL != fmt.Sprintf("http://127.0.0.1:%d", port) {
+        t.Errorf("unexpected endpoint: %+v", endpoint)
+    }
+    if endpoint.Browser != "Chrome/126.0.6478.126" || endpoint.WebSocketDebuggerURL != "ws://127.0.0.1:1/devtools/browser/abc" {
+        t.Errorf("unexpected endpoint: %+v", endpoint)
+    }
+}
+
+func TestQueryDevToolsErrors(t *testing.T) {
+    tests := []struct {
+        name   string
+        status int
+        body   string
+        want   string
+    }{
+        {"status", http.StatusInternalServerError, `{}`, "returned 500"},
+        {"malformed json", http.StatusOK, `{"webSocketDebuggerUrl":`, "invalid response"},
+        {"not an object", http.StatusOK, `"ws://x"`, "invalid response"},
+        {"missing url", http.StatusOK, `{"Browser":"Chrome/126"}`, "did not report webSocketDebuggerUrl"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, port := fakeDevTools(t, tt.status, tt.body)
+            _, err := QueryDevTools(context.Background(), "127.0.0.1", port)
+            if err == nil || !strings.Contains(err.Error(), tt.want) {
+                t.Errorf("got err=%v, want it to contain %q", err, tt.want)
+            }
+        })
+    }
+
+    t.Run("unreachable", func(t *testing.T) {
+        server, port := fakeDevTools(t, http.StatusOK, `{}`)
+        server.Close()
+        _, err := QueryDevTools(context.Background(), "127.0.0.1", port)
+        if err == nil || !strings.Contains(err.Error(), "not reachable") {
+            t.Errorf("got err=%v, want a reachability error", err)
+        }
+    })
+
+    t.Run("canceled", func(t *testing.T) {
+        _, port := fakeDevTools(t, http.StatusOK, `{"webSocketDebuggerUrl":"ws://x"}`)
+        ctx, cancel := context.WithCancel(context.Background())
+        cancel()
+        if _, err := QueryDevTools(ctx, "127.0.0.1", port); !errors.Is(err, context.Canceled) {
+            t.Errorf("got err=%v, want context.Canceled", err)
+        }
+    })
+}
+
+func TestReadDevToolsActivePort(t *testing.T) {
+    dir := t.TempDir()
+    if _, _, err := ReadDevToolsActivePort(dir); !os.IsNotExist(err) {
+        t.Fatalf("missing file: got err=%v, want a not-exist error", err)
+    }
+
+    writeActivePort(t, dir, "9222\n/devtools/browser/abc\n")
+    port, path, err := ReadDevToolsActivePort(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if port != 9222 || path != "/devtools/browser/abc" {
+        t.Errorf("got %d %q", port, path)
+    }
+
+    // Windows 上的换行和多余的空白同样可以解析
+    writeActivePort(t, dir, " 9333 \r\n/devtools/browser/def\r\n")
+    if port, path, err := ReadDevToolsActivePort(dir); err != nil || port != 9333 || path != "/devtools/browser/def" {
+        t.Errorf("got %d %q, err=%v", port, path, err)
+    }
+
+    for _, content := range []string{
+        "",
+        "9222\n",
+        "port\n/devtools/browser/abc\n",
+        "0\n/devtools/browser/abc\n",
+        "70000\n/devtools/browser/abc\n",
+        "-1\n/devtools/browser/abc\n",
+    } {
+        writeActivePort(t, dir, content)
+        if _, _, err := ReadDevToolsActivePort(dir); err == nil || os.IsNotExist(err) {
+            t.Errorf("content %q: got err=%v, want a malformed error", content, err)
+        }
+    }
+}
+
+func TestDevToolsEndpoint(t *testing.T) {
+    _, port := fakeDevTools(t, http.StatusOK, `{"webSocketDebuggerUrl":"ws://127.0.0.1:1/devtools/browser/abc"}`)
+    dir := t.TempDir()
+
+    writeActivePort(t, dir, fmt.Sprintf("%d\n/devtools/browser/abc\n", port))
+    endpoint, err := devToolsEndpoint(context.Background(), dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if endpoint.Port != port {
+        t.Errorf("got port %d, want %d", endpoint.Port, port)
+    }
+
+    // 端口上是另一个浏览器：文件中的路径与浏览器报告的端点不一致
+    writeActivePort(t, dir, fmt.Sprintf("%d\n/devtools/browser/other\n", port))
+    if _, err := devToolsEndpoint(context.Background(), dir); err == nil || !strings.Contains(err.Error(), "belongs to another browser") {
+        t.Errorf("mismatched path: got err=%v", err)
+    }
+
+    // 浏览器已经退出，文件中的端口没有人监听
+    stale, stalePort := fakeDevTools(t, http.StatusOK, `{}`)
+    stale.Close()
+    writeActivePort(t, dir, fmt.Sprintf("%d\n/devtools/browser/abc\n", stalePort))
+    if _, err := devToolsEndpoint(context.Background(), dir); err == nil || !strings.Contains(err.Error(), "not reachable") {
+        t.Errorf("stale port: got err=%v", err)
+    }
+}
+
+func TestAllocateDebugPort(t *testing.T) {
+    port, err := allocateDebugPort(0)
+    if err != nil || port <= 0 {
+        t.Fatalf("got port %d, err=%v", port, err)
+    }
+
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer listener.Close()
+    busy := listener.Addr().(*net.TCPAddr).Port
+
+    _, err = allocateDebugPort(busy)
+    var inUse *PortInUseError
+    if !errors.As(err, &inUse) || inUse.Port != busy {
+        t.Fatalf("got err=%v, want *PortInUseError for port %d", err, busy)
+    }
+}
+
+func TestStartWithBusyDebugPort(t *testing.T) {
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer listener.Close()
+    busy := listener.Addr().(*net.TCPAddr).Port
+
+    executable := filepath.Join(t.TempDir(), "fake-browser")
+    if err := os.WriteFile(executable, []byte("#!/bin/sh\n"), 0755); err != nil {
+        t.Fatal(err)
+    }
+    registry := NewRegistry(&config.Settings{Browsers: []*config.Browser{{ID: "fake", Name: "Fake", Path: executable}}})
+    cfg := &config.ChromeConfig{
+        ID:          config.NewConfigID(),
+        Name:        "devtools",
+        UserDataDir: t.TempDir(),
+        Browser:     "fake",
+        DevTools:    true,
+        DebugPort:   busy,
+    }
+
+    ci := NewInstance(cfg, registry)
+    err = ci.Start()
+    var inUse *PortInUseError
+    if !errors.As(err, &inUse) || inUse.Port != busy {
+        t.Fatalf("got err=%v, want *PortInUseError for port %d", err, busy)
+    }
+    if ci.State().Active() {
+        t.Errorf("instance is %v after a failed start", ci.State())
+    }
+}
+
+func TestOpenWorkspaceWithoutDevTools(t *testing.T) {
+    marker := filepath.Join(t.TempDir(), "launched")
+    executable := filepath.Join(t.TempDir(), "fake-browser")
+    if err := os.WriteFile(executable, []byte("#!/bin/sh\ntouch "+marker+"\n"), 0755); err != nil {
+        t.Fatal(err)
+    }
+    registry := NewRegistry(&config.Settings{Browsers: []*config.Browser{{ID: "fake", Name: "Fake", Path: executable}}})
+    cfg := &config.ChromeConfig{
+        ID:          config.NewConfigID(),
+        Name:        "no-devtools",
+        UserDataDir: t.TempDir(),
+        Browser:     "fake",
+    }
+    ws := &config.Workspace{Windows: []config.WorkspaceWindow{{Tabs: []config.WorkspaceTab{{URL: "https://example.com"}}}}}
+
+    ci := NewInstance(cfg, registry)
+    if err := ci.OpenWorkspace(context.Background(), ws); !errors.Is(err, ErrDevToolsDisabled) {
+        t.Fatalf("got err=%v, want ErrDevToolsDisabled", err)
+    }
+    if ci.State().Active() {
+        t.Errorf("instance is %v after refusing to open the workspace", ci.State())
+    }
+    if _, err := os.Stat(marker); !os.IsNotExist(err) {
+        t.Errorf("browser was launched: err=%v", err)
+    }
+}
@@ -0,0 +1,55 @@
+package chrome
+
+import (
+    "chromes/config"
+    "fmt"
+)
+
+// HeadlessFlag 是以无界面模式启动浏览器的参数。新的无界面模式与有界面的浏览器使用同一套实现，
+// 登录状态、Cookie 和扩展等都取自用户数据目录。
+const HeadlessFlag = "--headless=new"
+
+// InUseError 表示用户数据目录已被另一个浏览器进程打开，例如从桌面快捷方式启动的有界面浏览器。
+// 同一个数据目录同时只能被一个浏览器进程使用。
+type InUseError struct {
+    Config   *config.ChromeConfig
+    PID      int  // 持有用户数据目录的浏览器主进程号，未知时为 0
+    Headless bool // 是否是在以无界面模式启动时遇到的
+}
+
+func (e *InUseError) Error() string {
+    if e.Headless {
+        return fmt.Sprintf("cannot start chrome instance %s headless: its user data dir is already open in a browser window (pid %d)", e.Config, e.PID)
+    }
+    return fmt.Sprintf("chrome instance %s is already running (pid %d)", e.Config, e.PID)
+}
+
+// StartHeadless 以无界面模式启动实例，并总是开启远程调试（不论配置的 DevTools 是否开启），
+// 用于在不显示窗口的情况下借助配置的登录状态渲染页面等后台任务。
+// 用户数据目录已被其他浏览器打开时拒绝启动并返回 *InUseError，不会连接到那个浏览器。
+// 默认实例没有独立的数据目录，不支持无界面模式。
+func (ci *Instance) StartHeadless() error {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+
+    if ci.config.UserDataDir == "" {
+        return fmt.Errorf("headless mode requires a user data dir, chrome instance %s has none", ci.config)
+    }
+    if ci.state.Active() {
+        if ci.headless {
+            return fmt.Errorf("chrome instance %s is already running headless", ci.config)
+        }
+        return &InUseError{Config: ci.config, PID: ci.pid, Headless: true}
+    }
+
+    ci.resetRestarts()
+    ci.headless = true
+    return ci.start("headless")
+}
+
+// Headless 返回实例是否正以无界面模式运行。
+func (ci *Instance) Headless() bool {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+    return ci.headless && ci.state.Active()
+}
@@ -0,0 +1,217 @@
+package chrome
+
+import (
+    "bytes"
+    "chromes/config"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const (
+    maxLogSize  = 5 << 20 // 单个日志文件的大小上限，超过后轮转
+    maxLogFiles = 3       // 保留的已轮转日志文件数量，即 <id>.log.1 … <id>.log.3
+)
+
+// LogPath 返回配置 ID 对应的浏览器输出日志文件路径，位于配置目录的 logs/ 下。
+func LogPath(id string) string {
+    return filepath.Join(config.Dir(), "logs", id+".log")
+}
+
+// LoggingFlags 返回日志级别对应的浏览器启动参数：0 表示不开启浏览器自身的日志，
+// 1 开启 --enable-logging=stderr，更高的级别额外以 --v=<level-1> 输出详细日志。
+// 日志写到 stderr，与标准输出一起被捕获到 LogPath 指向的文件中。
+func LoggingFlags(level int) []string {
+    if level <= 0 {
+        return nil
+    }
+    flags := []string{"--enable-logging=stderr"}
+    if level > 1 {
+        flags = append(flags, "--v="+strconv.Itoa(level-1))
+    }
+    return flags
+}
+
+// openLog 打开配置 ID 对应的日志文件用于追加，文件过大时先轮转。
+// 返回的文件直接作为浏览器进程的标准输出和标准错误，本程序退出或重启后浏览器仍可继续写入。
+func openLog(id string) (*os.File, error) {
+    path := LogPath(id)
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return nil, fmt.Errorf("failed to create log directory: %w", err)
+    }
+    if err := rotateLogIfNeeded(path); err != nil {
+        log.Printf("[logs] rotate failed. path=%v, err=%v", path, err)
+    }
+    return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+}
+
+// rotateLogIfNeeded 在日志文件超过 maxLogSize 时轮转：<id>.log.N 依次后移，最旧的被删除，
+// 当前内容复制到 <id>.log.1 后将原文件截断。
+// 浏览器进程以追加方式持有原文件，复制后截断而不是重命名，它的后续输出才会写到新的文件中。
+func rotateLogIfNeeded(path string) error {
+    info, err := os.Stat(path)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil
+        }
+        return err
+    }
+    if info.Size() < maxLogSize {
+        return nil
+    }
+
+    for i := maxLogFiles - 1; i >= 1; i-- {
+        from := fmt.Sprintf("%s.%d", path, i)
+        if err := os.Rename(from, fmt.Sprintf("%s.%d", path, i+1)); err != nil && !os.IsNotExist(err) {
+            return err
+        }
+    }
+    src, err := os.Open(path)
+    if err != nil {
+        return err
+    }
+    defer src.Close()
+    dst, err := os.OpenFile(path+".1", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+    if err != nil {
+        return err
+    }
+    if _, err := io.Copy(dst, src); err != nil {
+        dst.Close()
+        return err
+    }
+    if err := dst.Close(); err != nil {
+        return err
+    }
+    if err := os.Truncate(path, 0); err != nil {
+        return err
+    }
+    log.Printf("[logs] rotated. path=%v, size=%v", path, info.Size())
+    return nil
+}
+
+// writeLogHeader 在每次启动前向日志写入一行分隔信息，便于区分多次启动的输出。
+func writeLogHeader(w io.Writer, path string, args []string) {
+    fmt.Fprintf(w, "\n==== %s start %s %s\n", time.Now().Format(time.DateTime), path, strings.Join(args, " "))
+}
+
+// Severity 是浏览器日志行的严重级别。
+type Severity int
+
+const (
+    SeverityUnknown Severity = iota // 无法识别级别的行，例如浏览器之外的程序输出或多行日志的后续行
+    SeverityVerbose                 // VERBOSEn，由 --v=N 开启
+    SeverityInfo
+    SeverityWarning
+    SeverityError
+    SeverityFatal
+)
+
+func (s Severity) String() string {
+    switch s {
+    case SeverityVerbose:
+        return "VERBOSE"
+    case SeverityInfo:
+        return "INFO"
+    case SeverityWarning:
+        return "WARNING"
+    case SeverityError:
+        return "ERROR"
+    case SeverityFatal:
+        return "FATAL"
+    default:
+        return "UNKNOWN"
+    }
+}
+
+// severityPattern 匹配 Chromium 日志行的前缀，例如 "[12345:12345:0102/030405.678901:WARNING:foo.cc(42)] ..."。
+var severityPattern = regexp.MustCompile(`^\[[^\]]*:(VERBOSE\d*|INFO|WARNING|ERROR|FATAL):[^\]]*\]`)
+
+// ParseSeverity 返回日志行的严重级别，不是 Chromium 日志格式的行返回 SeverityUnknown。
+func ParseSeverity(line string) Severity {
+    m := severityPattern.FindStringSubmatch(line)
+    if m == nil {
+        return SeverityUnknown
+    }
+    switch {
+    case strings.HasPrefix(m[1], "VERBOSE"):
+        return SeverityVerbose
+    case m[1] == "INFO":
+        return SeverityInfo
+    case m[1] == "WARNING":
+        return SeverityWarning
+    case m[1] == "ERROR":
+        return SeverityError
+    default:
+        return SeverityFatal
+    }
+}
+
+// LogTail 增量读取一个正在被写入的日志文件，用于实时查看。
+// 文件被轮转截断后自动从头开始读取。
+type LogTail struct {
+    path    string // 日志文件路径
+    offset  int64  // 下一次读取的位置
+    partial []byte // 上一次读到的不完整的最后一行
+    skip    bool   // 从文件中间开始读取时，第一行不完整，需要丢弃
+}
+
+// NewLogTail 创建一个日志读取器，第一次 Read 最多返回文件末尾 backlog 字节内的完整行。
+func NewLogTail(path string, backlog int64) *LogTail {
+    t := &LogTail{path: path}
+    if info, err := os.Stat(path); err == nil && info.Size() > backlog {
+        t.offset = info.Size() - backlog
+        t.skip = true
+    }
+    return t
+}
+
+// Read 返回上次读取之后新写入的完整行。文件不存在时返回 nil, nil。
+func (t *LogTail) Read() ([]string, error) {
+    f, err := os.Open(t.path)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil, nil
+        }
+        return nil, err
+    }
+    defer f.Close()
+
+    info, err := f.Stat()
+    if err != nil {
+        return nil, err
+    }
+    if info.Size() < t.offset {
+        t.offset = 0 // 文件已被轮转截断
+        t.partial = nil
+        t.skip = false
+    }
+    if info.Size() == t.offset {
+        return nil, nil
+    }
+    data := make([]byte, info.Size()-t.offset)
+    n, err := f.ReadAt(data, t.offset)
+    if err != nil && err != io.EOF {
+        return nil, err
+    }
+    t.offset += int64(n)
+    data = append(t.partial, data[:n]...)
+
+    end := bytes.LastIndexByte(data, '\n')
+    if end < 0 {
+        t.partial = data
+        return nil, nil
+    }
+    t.partial = append([]byte(nil), data[end+1:]...)
+    lines := strings.Split(string(data[:end]), "\n")
+    if t.skip {
+        t.skip = false
+        lines = lines[1:]
+    }
+    return lines, nil
+}
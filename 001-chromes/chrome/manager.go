@@ -0,0 +1,181 @@
+package chrome
+
+import (
+    "chromes/config"
+    "context"
+    "fmt"
+    "log"
+    "sync"
+    "time"
+)
+
+// Manager 持有所有配置对应的 Instance，生命周期与主程序相同。
+// 配置列表变化后通过 Reconcile 与之对齐：已有的 Instance 按配置 ID 保留（包括其进程对象），
+// 新增的配置创建新的 Instance，被删除的配置对应的 Instance 被移除。
+// 启动和停止都应通过 Manager 进行，这样配置变化不会使运行中的浏览器失去控制。
+// 所有实例的状态变化都会汇总发布给 Subscribe 的订阅者。
+type Manager struct {
+    registry  *Registry            // 浏览器注册表，传给新建的 Instance
+    instances []*Instance          // 按配置列表顺序排列的实例
+    byID      map[string]*Instance // 配置 ID -> 实例
+    events    broadcaster          // 所有实例状态变化的订阅者
+    mu        sync.RWMutex         // 保护 instances 和 byID
+}
+
+// NewManager 创建一个空的实例管理器，需要调用 Reconcile 载入配置。
+func NewManager(registry *Registry) *Manager {
+    return &Manager{
+        registry: registry,
+        byID:     make(map[string]*Instance),
+    }
+}
+
+// Registry 返回管理器使用的浏览器注册表。
+func (m *Manager) Registry() *Registry {
+    return m.registry
+}
+
+// Subscribe 订阅所有实例的状态变化，返回接收事件的通道和取消订阅的函数。
+// 事件中的 ID 为配置 ID；已被移除的配置对应的实例仍可能发布事件（例如进程退出）。
+func (m *Manager) Subscribe() (<-chan Event, func()) {
+    return m.events.subscribe()
+}
+
+// Reconcile 使实例列表与 configs 一致，并按 configs 的顺序排列。
+// 已存在的实例更新为新的配置对象，未由本程序启动的实例会重新检测运行状态；
+// 被移除的配置如果仍在运行，其浏览器进程不受影响，只是不再由管理器控制。
+func (m *Manager) Reconcile(configs []*config.ChromeConfig) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    instances := make([]*Instance, 0, len(configs))
+    byID := make(map[string]*Instance, len(configs))
+    for _, cfg := range configs {
+        if _, dup := byID[cfg.ID]; dup {
+            log.Printf("[manager] duplicate config id ignored. config=%v", cfg)
+            continue
+        }
+        instance, ok := m.byID[cfg.ID]
+        if ok {
+            instance.update(cfg)
+        } else {
+            instance = NewInstance(cfg, m.registry)
+            instance.setSink(m.events.publish)
+            log.Printf("[manager] added. config=%v, running=%v", cfg, instance.IsRunning())
+        }
+        instances = append(instances, instance)
+        byID[cfg.ID] = instance
+    }
+    for id, instance := range m.byID {
+        if _, ok := byID[id]; !ok {
+            instance.CancelRestart()
+            log.Printf("[manager] retired. config=%v, running=%v", instance.Config(), instance.IsRunning())
+        }
+    }
+    m.instances = instances
+    m.byID = byID
+}
+
+// Run 按 interval 周期性地调用 Rescan，使实例状态与系统中实际运行的浏览器保持一致，直到 ctx 被取消。
+// 通常在单独的 goroutine 中调用。
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            m.Rescan()
+        }
+    }
+}
+
+// Rescan 重新检测所有实例是否被其他途径启动或关闭（例如从窗口菜单退出、从桌面快捷方式启动），状态变化时发布事件。
+// 每次只读取一次进程列表供所有实例匹配；有 SingletonLock 的目录只需要读取锁，不涉及进程列表。
+func (m *Manager) Rescan() {
+    var processes []Process
+    if defaultProcessLister != nil {
+        var err error
+        if processes, err = defaultProcessLister.Processes(); err != nil {
+            log.Printf("[manager] list processes failed. err=%v", err)
+            processes = nil
+        }
+    }
+    for _, instance := range m.Instances() {
+        instance.refresh(processes)
+        // 运行中的浏览器持续写入日志，在这里顺便检查是否需要轮转
+        if instance.IsRunning() {
+            if err := rotateLogIfNeeded(LogPath(instance.ID())); err != nil {
+                log.Printf("[logs] rotate failed. id=%v, err=%v", instance.ID(), err)
+            }
+        }
+    }
+}
+
+// Len 返回当前管理的实例数量。
+func (m *Manager) Len() int {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    return len(m.instances)
+}
+
+// At 返回第 i 个实例，越界时返回 nil。
+func (m *Manager) At(i int) *Instance {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    if i < 0 || i >= len(m.instances) {
+        return nil
+    }
+    return m.instances[i]
+}
+
+// Index 返回配置 ID 对应实例在列表中的位置，未找到时返回 -1。
+func (m *Manager) Index(id string) int {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    for i, instance := range m.instances {
+        if instance.ID() == id {
+            return i
+        }
+    }
+    return -1
+}
+
+// Get 按配置 ID 返回实例，未找到时返回 nil。
+func (m *Manager) Get(id string) *Instance {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    return m.byID[id]
+}
+
+// Instances 返回当前所有实例的快照。
+func (m *Manager) Instances() []*Instance {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    return append([]*Instance(nil), m.instances...)
+}
+
+// Start 启动指定配置的实例。进程退出由实例自行检测，并通过 Subscribe 的事件通知。
+func (m *Manager) Start(id string) error {
+    instance := m.Get(id)
+    if instance == nil {
+        return fmt.Errorf("chrome instance '%s' not found", id)
+    }
+    return instance.Start()
+}
+
+// Stop 开始停止指定配置的实例，立即返回；实例在停止期间处于 StateStopping。
+// 停止过程在后台进行，结果通过 Subscribe 的事件通知：成功时切换到 StateStopped，失败时回到原状态并带有错误。
+func (m *Manager) Stop(id string) error {
+    instance := m.Get(id)
+    if instance == nil {
+        return fmt.Errorf("chrome instance '%s' not found", id)
+    }
+    stop, err := instance.beginStop()
+    if err != nil {
+        return err
+    }
+    go stop()
+    return nil
+}
@@ -0,0 +1,86 @@
+package chrome
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "net/url"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// openTimeout 是把网址交给运行中的浏览器的期限。转交的进程只负责通知持有用户数据目录的浏览器，随后立即退出。
+const openTimeout = 10 * time.Second
+
+// Open 在实例中打开网址。实例没有运行时以这些网址启动它；
+// 正在运行时以相同的用户数据目录再执行一次浏览器，浏览器的 ProcessSingleton 会把命令行转交给运行中的进程，
+// 由它在新标签页中打开网址并将窗口置于前台，这样不需要开启远程调试，由其他途径启动的浏览器也同样适用。
+// 没有网址时只启动实例，或者在运行中的浏览器里打开一个新窗口，供桌面启动器使用。
+// 无界面模式的实例和停止中的实例不能打开网址。
+func (ci *Instance) Open(urls ...string) error {
+    if err := ValidateURLs(urls); err != nil {
+        return err
+    }
+    ci.mu.Lock()
+    if !ci.state.Active() {
+        defer ci.mu.Unlock()
+        ci.resetRestarts()
+        ci.headless = false
+        return ci.start("open", urls...)
+    }
+    cfg, state, headless, executable := ci.config, ci.state, ci.headless, ci.executable()
+    ci.mu.Unlock()
+
+    switch {
+    case state == StateStopping:
+        return fmt.Errorf("chrome instance %s is stopping", cfg)
+    case headless:
+        return fmt.Errorf("cannot open urls in chrome instance %s, it is running headless", cfg)
+    case executable == "":
+        return fmt.Errorf("no browser available for chrome instance %s", cfg)
+    }
+
+    var args []string
+    if cfg.UserDataDir != "" {
+        absPath, err := filepath.Abs(cfg.UserDataDir)
+        if err != nil {
+            return fmt.Errorf("failed to get absolute path for %s: %w", cfg.UserDataDir, err)
+        }
+        args = append(args, "--user-data-dir="+absPath)
+    }
+    if len(urls) > 0 {
+        args = append(args, "--") // 之后的参数都是网址，不会被浏览器当作启动参数
+        args = append(args, urls...)
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), openTimeout)
+    defer cancel()
+    if output, err := exec.CommandContext(ctx, executable, args...).CombinedOutput(); err != nil {
+        if len(urls) == 0 {
+            return fmt.Errorf("failed to open a new window in chrome instance %s: %w (%s)", cfg, err, output)
+        }
+        return fmt.Errorf("failed to open %v in chrome instance %s: %w (%s)", urls, cfg, err, output)
+    }
+    log.Printf("[open] handed over to running browser. config=%v, urls=%v", cfg, urls)
+    return nil
+}
+
+// ValidateURLs 检查要在浏览器中打开的网址：必须是带协议的绝对网址，且不能以 "-" 开头，
+// 避免网址被浏览器当作启动参数（例如 --renderer-cmd-prefix）执行。
+func ValidateURLs(urls []string) error {
+    for _, rawURL := range urls {
+        if strings.HasPrefix(rawURL, "-") {
+            return fmt.Errorf("invalid url '%s': urls must not start with '-'", rawURL)
+        }
+        u, err := url.Parse(rawURL)
+        if err != nil {
+            return fmt.Errorf("invalid url '%s': %w", rawURL, err)
+        }
+        if !u.IsAbs() {
+            return fmt.Errorf("invalid url '%s': urls must be absolute and include a scheme", rawURL)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,33 @@
+package chrome
+
+import "testing"
+
+func TestValidateURLs(t *testing.T) {
+    valid := []string{
+        "https://example.com/path?q=1",
+        "http://localhost:8080",
+        "file:///tmp/page.html",
+        "about:blank",
+        "mailto:someone@example.com",
+    }
+    if err := ValidateURLs(valid); err != nil {
+        t.Errorf("valid urls rejected: %v", err)
+    }
+    if err := ValidateURLs(nil); err != nil {
+        t.Errorf("no urls: got err=%v", err)
+    }
+
+    for _, rawURL := range []string{
+        "--renderer-cmd-prefix=xterm",
+        "-incognito",
+        "example.com",
+        "/tmp/page.html",
+        "//example.com/path",
+        "",
+        "http://[::1",
+    } {
+        if err := ValidateURLs([]string{"https://example.com", rawURL}); err == nil {
+            t.Errorf("url %q accepted", rawURL)
+        }
+    }
+}
@@ -0,0 +1,273 @@
+package chrome
+
+import (
+    "bytes"
+    "fmt"
+    "os"
+    "path/filepath"
+    "runtime"
+    "slices"
+    "strconv"
+    "strings"
+)
+
+// Process 描述一个正在运行的进程。
+type Process struct {
+    PID  int      // 进程号
+    PPID int      // 父进程号
+    Exe  string   // 可执行文件的实际路径，无权限读取时为空
+    Args []string // 命令行参数，Args[0] 通常为启动时使用的可执行文件路径
+}
+
+// ProcessLister 列出系统中正在运行的进程，用于按浏览器安装和用户数据目录查找浏览器进程。
+type ProcessLister interface {
+    Processes() ([]Process, error)
+}
+
+// ProcFS 通过读取 procfs（Linux 上的 /proc）列出进程。
+// Root 可以指向按相同结构组织的其他目录，便于在没有真实进程的情况下验证匹配逻辑。
+type ProcFS struct {
+    Root string // procfs 的挂载点，通常为 "/proc"
+}
+
+// Processes 遍历 Root 下以数字命名的目录，读取每个进程的 cmdline、stat 和 exe。
+// 读取过程中退出的进程或无权限读取的进程会被跳过。
+func (p *ProcFS) Processes() ([]Process, error) {
+    entries, err := os.ReadDir(p.Root)
+    if err != nil {
+        return nil, err
+    }
+
+    var processes []Process
+    for _, entry := range entries {
+        pid, err := strconv.Atoi(entry.Name())
+        if err != nil || pid <= 0 {
+            continue
+        }
+        process, err := p.Process(pid)
+        if err != nil {
+            continue
+        }
+        processes = append(processes, process)
+    }
+    return processes, nil
+}
+
+// Process 读取一个进程的 cmdline、stat 和 exe。
+// 进程已退出、是没有命令行的内核线程或僵尸进程时返回错误。
+func (p *ProcFS) Process(pid int) (Process, error) {
+    dir := filepath.Join(p.Root, strconv.Itoa(pid))
+    data, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+    if err != nil {
+        return Process{}, err
+    }
+    if len(data) == 0 {
+        return Process{}, fmt.Errorf("process %d has no command line", pid)
+    }
+    stat, err := readProcStat(dir)
+    if err != nil {
+        return Process{}, err
+    }
+    if stat.state == 'Z' {
+        return Process{}, fmt.Errorf("process %d is a zombie", pid) // 已经退出，只是还没有被父进程回收
+    }
+    exe, _ := os.Readlink(filepath.Join(dir, "exe"))
+    return Process{PID: pid, PPID: stat.ppid, Exe: exe, Args: parseCmdline(data)}, nil
+}
+
+// procStat 是 /proc/<pid>/stat 中用到的字段。
+type procStat struct {
+    state byte   // 进程状态，例如 'R'、'S'、'Z'（僵尸）
+    ppid  int    // 父进程号
+    start uint64 // 进程的启动时间（系统启动后的时钟节拍数），与进程号一起唯一标识一个进程
+}
+
+// readProcStat 读取进程目录下的 stat 文件。
+// 第二个字段是括号包围的进程名，其中可能含有空格和括号，因此从最后一个 ')' 之后开始解析。
+func readProcStat(dir string) (procStat, error) {
+    data, err := os.ReadFile(filepath.Join(dir, "stat"))
+    if err != nil {
+        return procStat{}, err
+    }
+    end := bytes.LastIndexByte(data, ')')
+    if end < 0 {
+        return procStat{}, fmt.Errorf("malformed stat in %s", dir)
+    }
+    // 进程名之后依次是 state、ppid……第 22 个字段 starttime 位于其后的第 20 个
+    fields := strings.Fields(string(data[end+1:]))
+    if len(fields) < 20 || len(fields[0]) != 1 {
+        return procStat{}, fmt.Errorf("malformed stat in %s", dir)
+    }
+    ppid, err := strconv.Atoi(fields[1])
+    if err != nil {
+        return procStat{}, fmt.Errorf("malformed stat in %s: %w", dir, err)
+    }
+    start, err := strconv.ParseUint(fields[19], 10, 64)
+    if err != nil {
+        return procStat{}, fmt.Errorf("malformed stat in %s: %w", dir, err)
+    }
+    return procStat{state: fields[0][0], ppid: ppid, start: start}, nil
+}
+
+// parseCmdline 解析 /proc/<pid>/cmdline：参数之间以 NUL 分隔，末尾通常带有一个 NUL。
+// 部分程序（包括 Chrome 的子进程）会改写自己的命令行，用空格代替 NUL 连接参数，
+// 此时只能按空格拆分，路径中带空格的参数可能会被拆开。
+func parseCmdline(data []byte) []string {
+    data = bytes.TrimRight(data, "\x00")
+    if !bytes.Contains(data, []byte{0}) {
+        return strings.Fields(string(data))
+    }
+    return strings.Split(string(data), "\x00")
+}
+
+// defaultProcessLister 是查找浏览器进程时使用的 ProcessLister。
+// 为 nil 时（非 Linux 系统或 /proc 不可用）回退到 ps/pgrep/PowerShell 命令。
+var defaultProcessLister = newDefaultProcessLister()
+
+func newDefaultProcessLister() ProcessLister {
+    if runtime.GOOS != "linux" {
+        return nil
+    }
+    if _, err := os.Stat("/proc/self/cmdline"); err != nil {
+        return nil
+    }
+    return &ProcFS{Root: "/proc"}
+}
+
+// userDataDirArg 从命令行参数中取出 --user-data-dir 的值，支持 "--user-data-dir=<dir>" 和 "--user-data-dir <dir>" 两种形式。
+func userDataDirArg(args []string) (string, bool) {
+    for i, arg := range args {
+        if value, ok := strings.CutPrefix(arg, "--user-data-dir="); ok {
+            return value, true
+        }
+        if arg == "--user-data-dir" && i+1 < len(args) {
+            return args[i+1], true
+        }
+    }
+    return "", false
+}
+
+// browserBinary 描述一个浏览器安装的主程序，用于按可执行文件匹配进程。
+type browserBinary struct {
+    path  string   // 解析符号链接后的主程序路径，解析到通用启动程序时为空
+    names []string // 主程序进程可能使用的文件名
+}
+
+// launcherNames 是只负责转交启动的通用程序。可执行文件解析到它们时（例如 /snap/bin/chromium 指向 /usr/bin/snap），
+// 不能按其路径或文件名匹配，否则会把无关的进程当成浏览器。
+var launcherNames = []string{"snap", "flatpak", "env", "sh", "bash", "dash"}
+
+// wrapperBinaries 列出常见的 Linux 启动脚本及其实际执行的主程序文件名，
+// 例如 google-chrome 是一个脚本，浏览器进程是 /opt/google/chrome/chrome。
+var wrapperBinaries = map[string][]string{
+    "google-chrome":          {"chrome"},
+    "google-chrome-stable":   {"chrome"},
+    "google-chrome-beta":     {"chrome"},
+    "google-chrome-unstable": {"chrome"},
+    "chromium":               {"chrome"},
+    "chromium-browser":       {"chrome"},
+    "brave-browser":          {"brave"},
+    "brave-browser-stable":   {"brave"},
+    "microsoft-edge":         {"msedge"},
+    "microsoft-edge-stable":  {"msedge"},
+}
+
+// resolveBrowserBinary 解析 executable 对应的浏览器主程序：解析符号链接后的完整路径，
+// 以及可执行文件名、解析后的文件名和启动脚本对应的主程序文件名。
+func resolveBrowserBinary(executable string) browserBinary {
+    resolved, err := filepath.EvalSymlinks(executable)
+    if err != nil {
+        resolved = executable
+    }
+
+    var b browserBinary
+    if !slices.Contains(launcherNames, filepath.Base(resolved)) {
+        b.path = resolved
+    }
+    for _, name := range []string{filepath.Base(executable), filepath.Base(resolved)} {
+        if slices.Contains(launcherNames, name) {
+            continue
+        }
+        for _, n := range append([]string{name}, wrapperBinaries[name]...) {
+            if !slices.Contains(b.names, n) {
+                b.names = append(b.names, n)
+            }
+        }
+    }
+    return b
+}
+
+// matches 检查进程是否运行着该浏览器的主程序：可执行文件与主程序路径相同，或文件名是主程序可能使用的名字。
+// 同时比较实际的可执行文件和命令行中的第一个参数，因为后者可能被改写而前者可能无权读取。
+// chrome_crashpad_handler 等辅助程序的文件名不同，不会被匹配。
+func (b browserBinary) matches(proc Process) bool {
+    candidates := []string{proc.Exe}
+    if len(proc.Args) > 0 {
+        candidates = append(candidates, proc.Args[0])
+    }
+    for _, path := range candidates {
+        if path == "" {
+            continue
+        }
+        if b.path != "" && path == b.path {
+            return true
+        }
+        if slices.Contains(b.names, filepath.Base(path)) {
+            return true
+        }
+    }
+    return false
+}
+
+// isHelperProcess 检查进程是否是浏览器以主程序身份启动的辅助进程（例如旧版本的 crashpad），
+// 这类进程会脱离浏览器独立运行，不属于任何实例。
+func isHelperProcess(proc Process) bool {
+    return slices.Contains(proc.Args, "--type=crashpad-handler")
+}
+
+// matchBrowserProcesses 返回运行着 executable 对应浏览器主程序、且 --user-data-dir 与 userDataDir 完全相同的进程号。
+// userDataDir 为空时返回没有 --user-data-dir 参数的进程，即默认实例。
+func matchBrowserProcesses(processes []Process, executable string, userDataDir string) []int {
+    binary := resolveBrowserBinary(executable)
+    want := ""
+    if userDataDir != "" {
+        want = cleanUserDataDir(userDataDir)
+    }
+
+    var pids []int
+    for _, proc := range processes {
+        if !binary.matches(proc) {
+            continue
+        }
+        dir, ok := userDataDirArg(proc.Args)
+        if userDataDir == "" {
+            if !ok && !isHelperProcess(proc) {
+                pids = append(pids, proc.PID)
+            }
+            continue
+        }
+        if ok && cleanUserDataDir(dir) == want {
+            pids = append(pids, proc.PID)
+        }
+    }
+    return pids
+}
+
+// rootPID 返回 pids 中父进程不在 pids 中的第一个进程，即浏览器主进程；
+// 子进程（渲染进程等）的命令行同样带有 --user-data-dir，停止时必须从主进程开始。
+func rootPID(processes []Process, pids []int) int {
+    for _, proc := range processes {
+        if slices.Contains(pids, proc.PID) && !slices.Contains(pids, proc.PPID) {
+            return proc.PID
+        }
+    }
+    return pids[0]
+}
+
+// cleanUserDataDir 将用户数据目录转换为用于比较的绝对路径。
+func cleanUserDataDir(dir string) string {
+    if abs, err := filepath.Abs(dir); err == nil {
+        return abs
+    }
+    return filepath.Clean(dir)
+}
@@ -0,0 +1,278 @@
+package chrome
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "slices"
+    "strings"
+    "testing"
+)
+
+// writeFakeProcess 在伪造的 proc 目录下创建一个进程的 cmdline、stat 和 exe 符号链接。
+// exe 为空时不创建符号链接，模拟无权读取的情况。
+func writeFakeProcess(t *testing.T, root string, pid int, ppid int, comm string, state byte, exe string, cmdline string) {
+    t.Helper()
+    dir := filepath.Join(root, fmt.Sprint(pid))
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        t.Fatal(err)
+    }
+    stat := fmt.Sprintf("%d (%s) %c %d 0 0 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 %d 0 0\n", pid, comm, state, ppid, 1000+pid)
+    if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if exe != "" {
+        if err := os.Symlink(exe, filepath.Join(dir, "exe")); err != nil {
+            t.Fatal(err)
+        }
+    }
+}
+
+func TestProcFSProcesses(t *testing.T) {
+    root := t.TempDir()
+    writeFakeProcess(t, root, 100, 1, "chrome", 'S', "/opt/google/chrome/chrome", "/opt/google/chrome/chrome\x00--user-data-dir=/p/work\x00")
+    writeFakeProcess(t, root, 101, 100, "chrome", 'S', "", "/opt/google/chrome/chrome --type=renderer --user-data-dir=/p/work")
+    writeFakeProcess(t, root, 102, 1, "kworker/0:1", 'I', "", "")        // 内核线程没有命令行
+    writeFakeProcess(t, root, 103, 100, "chrome", 'Z', "", "chrome\x00") // 僵尸进程
+    if err := os.MkdirAll(filepath.Join(root, "self"), 0755); err != nil {
+        t.Fatal(err)
+    }
+
+    procfs := &ProcFS{Root: root}
+    processes, err := procfs.Processes()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(processes) != 2 {
+        t.Fatalf("got %d processes, want 2: %+v", len(processes), processes)
+    }
+    slices.SortFunc(processes, func(a, b Process) int { return a.PID - b.PID })
+
+    main := processes[0]
+    if main.PID != 100 || main.PPID != 1 || main.Exe != "/opt/google/chrome/chrome" {
+        t.Errorf("unexpected main process: %+v", main)
+    }
+    if !slices.Equal(main.Args, []string{"/opt/google/chrome/chrome", "--user-data-dir=/p/work"}) {
+        t.Errorf("unexpected args: %q", main.Args)
+    }
+    renderer := processes[1]
+    if renderer.PID != 101 || renderer.PPID != 100 || renderer.Exe != "" || len(renderer.Args) != 3 {
+        t.Errorf("unexpected renderer process: %+v", renderer)
+    }
+
+    if _, err := procfs.Process(102); err == nil {
+        t.Error("expected an error for a process without a command line")
+    }
+    if _, err := procfs.Process(103); err == nil {
+        t.Error("expected an error for a zombie process")
+    }
+    if _, err := procfs.Process(999); err == nil {
+        t.Error("expected an error for a missing process")
+    }
+}
+
+func TestProcFSMissingRoot(t *testing.T) {
+    procfs := &ProcFS{Root: filepath.Join(t.TempDir(), "missing")}
+    if _, err := procfs.Processes(); err == nil {
+        t.Fatal("expected an error for a missing proc root")
+    }
+}
+
+func TestParseCmdline(t *testing.T) {
+    tests := []struct {
+        data string
+        want []string
+    }{
+        {"/opt/chrome\x00--user-data-dir=/p/my work\x00https://example.com\x00", []string{"/opt/chrome", "--user-data-dir=/p/my work", "https://example.com"}},
+        {"/opt/chrome\x00\x00", []string{"/opt/chrome"}},
+        {"/opt/chrome\x00--flag=\x00", []string{"/opt/chrome", "--flag="}},
+        {"/opt/chrome --type=renderer  --user-data-dir=/p/work", []string{"/opt/chrome", "--type=renderer", "--user-data-dir=/p/work"}},
+        {"/opt/chrome --type=gpu-process\x00", []string{"/opt/chrome", "--type=gpu-process"}},
+    }
+    for _, tt := range tests {
+        if got := parseCmdline([]byte(tt.data)); !slices.Equal(got, tt.want) {
+            t.Errorf("parseCmdline(%q) = %q, want %q", tt.data, got, tt.want)
+        }
+    }
+}
+
+func TestReadProcStat(t *testing.T) {
+    dir := t.TempDir()
+    // 进程名中含有 ") "，必须从最后一个 ')' 之后解析
+    stat := "42 (evil) S 1 (x) R 7 0 0 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 123456 0 0\n"
+    if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644); err != nil {
+        t.Fatal(err)
+    }
+    got, err := readProcStat(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got.state != 'R' || got.ppid != 7 || got.start != 123456 {
+        t.Errorf("readProcStat = %+v, want state R, ppid 7, start 123456", got)
+    }
+
+    for _, bad := range []string{
+        "42 no parenthesis S 1",
+        "42 (chrome) S 1 0 0",
+        "42 (chrome) S x 0 0 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 1 0 0",
+        "42 (chrome) SS 1 0 0 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 1 0 0",
+    } {
+        if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(bad), 0644); err != nil {
+            t.Fatal(err)
+        }
+        if _, err := readProcStat(dir); err == nil {
+            t.Errorf("readProcStat(%q): expected an error", bad)
+        }
+    }
+}
+
+func TestUserDataDirArg(t *testing.T) {
+    tests := []struct {
+        args   []string
+        want   string
+        wantOK bool
+    }{
+        {[]string{"chrome", "--user-data-dir=/p/work"}, "/p/work", true},
+        {[]string{"chrome", "--user-data-dir", "/p/my work"}, "/p/my work", true},
+        {[]string{"chrome", "--user-data-dir="}, "", true},
+        {[]string{"chrome", "--user-data-dir"}, "", false},
+        {[]string{"chrome", "--user-data-directory=/p/work"}, "", false},
+        {[]string{"chrome"}, "", false},
+    }
+    for _, tt := range tests {
+        got, ok := userDataDirArg(tt.args)
+        if got != tt.want || ok != tt.wantOK {
+            t.Errorf("userDataDirArg(%q) = %q, %v, want %q, %v", tt.args, got, ok, tt.want, tt.wantOK)
+        }
+    }
+}
+
+// fakeInstall 创建一个浏览器安装：install/chrome 为主程序，bin/google-chrome 为启动脚本。
+func fakeInstall(t *testing.T) (script string, binary string) {
+    t.Helper()
+    dir := t.TempDir()
+    binary = filepath.Join(dir, "install", "chrome")
+    script = filepath.Join(dir, "bin", "google-chrome")
+    for _, path := range []string{binary, script} {
+        if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return script, binary
+}
+
+func TestMatchBrowserProcessesByUserDataDir(t *testing.T) {
+    _, binary := fakeInstall(t)
+    odd := "/p/my work (1)+[x].*"
+    processes := []Process{
+        {PID: 10, PPID: 1, Exe: binary, Args: []string{binary, "--user-data-dir=/p/work"}},
+        {PID: 11, PPID: 10, Args: []string{binary, "--type=renderer", "--user-data-dir=/p/work"}},
+        {PID: 20, PPID: 1, Exe: binary, Args: []string{binary, "--user-data-dir=/p/work2"}},
+        {PID: 30, PPID: 1, Exe: binary, Args: []string{binary, "--user-data-dir", odd}},
+        {PID: 31, PPID: 1, Exe: binary, Args: []string{binary, "--user-data-dir=/p/my work (1)+[x]"}},
+        {PID: 40, PPID: 1, Exe: "/usr/bin/other", Args: []string{"/usr/bin/other", "--user-data-dir=/p/work"}},
+        {PID: 50, PPID: 1, Exe: binary, Args: []string{binary, "--user-data-dir=/p/work/"}},
+    }
+
+    if got := matchBrowserProcesses(processes, binary, "/p/work"); !slices.Equal(got, []int{10, 11, 50}) {
+        t.Errorf("/p/work matched %v, want [10 11 50]", got)
+    }
+    if got := matchBrowserProcesses(processes, binary, "/p/work2"); !slices.Equal(got, []int{20}) {
+        t.Errorf("/p/work2 matched %v, want [20]", got)
+    }
+    if got := matchBrowserProcesses(processes, binary, odd); !slices.Equal(got, []int{30}) {
+        t.Errorf("%q matched %v, want [30]", odd, got)
+    }
+    if got := matchBrowserProcesses(processes, binary, "/p/missing"); len(got) != 0 {
+        t.Errorf("/p/missing matched %v", got)
+    }
+}
+
+func TestMatchBrowserProcessesDefaultInstance(t *testing.T) {
+    script, binary := fakeInstall(t)
+    dir := filepath.Dir(binary)
+    processes := []Process{
+        {PID: 10, PPID: 1, Exe: binary, Args: []string{binary}},
+        {PID: 11, PPID: 10, Args: []string{binary, "--type=renderer"}},
+        {PID: 12, PPID: 1, Exe: filepath.Join(dir, "chrome_crashpad_handler"), Args: []string{filepath.Join(dir, "chrome_crashpad_handler"), "--database=/tmp"}},
+        {PID: 13, PPID: 1, Exe: binary, Args: []string{binary, "--type=crashpad-handler"}},
+        {PID: 20, PPID: 1, Exe: binary, Args: []string{binary, "--user-data-dir=/p/work"}},
+        {PID: 30, PPID: 1, Exe: filepath.Join(filepath.Dir(script), "bash"), Args: []string{"bash"}},
+        {PID: 31, PPID: 1, Exe: filepath.Join(filepath.Dir(script), "vim"), Args: []string{filepath.Join(filepath.Dir(script), "vim")}},
+    }
+
+    // 通过启动脚本和直接使用主程序时，默认实例都只包含浏览器主程序的进程
+    for _, executable := range []string{script, binary} {
+        if got := matchBrowserProcesses(processes, executable, ""); !slices.Equal(got, []int{10, 11}) {
+            t.Errorf("default instance of %s matched %v, want [10 11]", executable, got)
+        }
+    }
+}
+
+func TestMatchBrowserProcessesThroughLauncher(t *testing.T) {
+    // /snap/bin/chromium 是指向 /usr/bin/snap 的符号链接，不能把 /usr/bin 下的其他程序当成浏览器
+    dir := t.TempDir()
+    snap := filepath.Join(dir, "usr", "bin", "snap")
+    executable := filepath.Join(dir, "snap", "bin", "chromium")
+    for _, path := range []string{snap, executable} {
+        if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := os.WriteFile(snap, nil, 0755); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.Symlink(snap, executable); err != nil {
+        t.Fatal(err)
+    }
+
+    real := "/snap/chromium/3000/usr/lib/chromium-browser/chrome"
+    processes := []Process{
+        {PID: 10, PPID: 1, Exe: real, Args: []string{real}},
+        {PID: 11, PPID: 1, Exe: real, Args: []string{real, "--user-data-dir=/p/work"}},
+        {PID: 20, PPID: 1, Exe: snap, Args: []string{snap, "run", "other"}},
+        {PID: 21, PPID: 1, Exe: filepath.Join(filepath.Dir(snap), "python3"), Args: []string{"python3"}},
+    }
+    if got := matchBrowserProcesses(processes, executable, ""); !slices.Equal(got, []int{10}) {
+        t.Errorf("default instance matched %v, want [10]", got)
+    }
+    if got := matchBrowserProcesses(processes, executable, "/p/work"); !slices.Equal(got, []int{11}) {
+        t.Errorf("/p/work matched %v, want [11]", got)
+    }
+}
+
+func TestRootPID(t *testing.T) {
+    processes := []Process{
+        {PID: 11, PPID: 10},
+        {PID: 10, PPID: 1},
+        {PID: 12, PPID: 10},
+    }
+    if got := rootPID(processes, []int{11, 10, 12}); got != 10 {
+        t.Errorf("rootPID = %d, want 10", got)
+    }
+}
+
+func TestProcessPattern(t *testing.T) {
+    _, binary := fakeInstall(t)
+    pattern := regexp.MustCompile(processPattern(binaryPattern(resolveBrowserBinary(binary)), "/p/my work (1)+"))
+    for cmdline, want := range map[string]bool{
+        binary + " --user-data-dir=/p/my work (1)+":                                                      true,
+        binary + " --type=renderer --user-data-dir=/p/my work (1)+ --x":                                  true,
+        "/elsewhere/chrome --user-data-dir=/p/my work (1)+":                                              true,
+        binary + " --user-data-dir=/p/my work (1)+2":                                                     false,
+        binary + " --user-data-dir=/p/my work (1)":                                                       false,
+        "/usr/bin/vim --user-data-dir=/p/my work (1)+":                                                   false,
+        strings.TrimSuffix(binary, "chrome") + "chrome_crashpad_handler --user-data-dir=/p/my work (1)+": false,
+    } {
+        if got := pattern.MatchString(cmdline); got != want {
+            t.Errorf("pattern %q on %q = %v, want %v", pattern, cmdline, got, want)
+        }
+    }
+}
@@ -0,0 +1,127 @@
+package chrome
+
+import (
+    "chromes/config"
+    "encoding/json"
+    "fmt"
+    "log"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "runtime"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// attachPollInterval 是检查重新接管的进程是否退出的间隔。
+const attachPollInterval = time.Second
+
+// RuntimeRecord 是由本程序启动的浏览器进程的运行时记录，保存在配置目录的 run/ 下，每个实例一个文件。
+// 本程序重启后根据它重新接管仍在运行的浏览器：继续监视其退出，并按进程号精确停止。
+type RuntimeRecord struct {
+    ID         string    `json:"id"`                   // 配置 ID
+    PID        int       `json:"pid"`                  // 浏览器主进程号
+    StartTime  time.Time `json:"start_time"`           // 启动时间
+    ProcStart  uint64    `json:"proc_start,omitempty"` // Linux 上 /proc/<pid>/stat 中的启动时间，用于识别进程号被复用的情况
+    Executable string    `json:"executable"`           // 浏览器可执行文件路径
+    Args       []string  `json:"args"`                 // 启动参数，不含可执行文件
+}
+
+// runtimeRecordDir 返回运行时记录所在的目录。
+func runtimeRecordDir() string {
+    return filepath.Join(config.Dir(), "run")
+}
+
+// runtimeRecordPath 返回配置 ID 对应的运行时记录文件路径。
+func runtimeRecordPath(id string) string {
+    return filepath.Join(runtimeRecordDir(), id+".json")
+}
+
+// newRuntimeRecord 为刚刚启动的进程创建运行时记录。
+func newRuntimeRecord(id string, cmd *exec.Cmd) *RuntimeRecord {
+    record := &RuntimeRecord{
+        ID:         id,
+        PID:        cmd.Process.Pid,
+        StartTime:  time.Now(),
+        Executable: cmd.Path,
+        Args:       cmd.Args[1:],
+    }
+    if procfs, ok := defaultProcessLister.(*ProcFS); ok {
+        if stat, err := readProcStat(filepath.Join(procfs.Root, strconv.Itoa(record.PID))); err == nil {
+            record.ProcStart = stat.start
+        }
+    }
+    return record
+}
+
+// writeRuntimeRecord 原子地写入运行时记录。
+func writeRuntimeRecord(record *RuntimeRecord) error {
+    data, err := json.MarshalIndent(record, "", "  ")
+    if err != nil {
+        return err
+    }
+    return config.WriteFileAtomic(runtimeRecordPath(record.ID), data, 0600)
+}
+
+// readRuntimeRecord 读取配置 ID 对应的运行时记录，不存在时返回 nil, nil。
+func readRuntimeRecord(id string) (*RuntimeRecord, error) {
+    data, err := os.ReadFile(runtimeRecordPath(id))
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil, nil
+        }
+        return nil, err
+    }
+    record := &RuntimeRecord{}
+    if err := json.Unmarshal(data, record); err != nil {
+        return nil, fmt.Errorf("invalid runtime record %s: %w", runtimeRecordPath(id), err)
+    }
+    return record, nil
+}
+
+// removeRuntimeRecord 删除配置 ID 对应的运行时记录。
+func removeRuntimeRecord(id string) {
+    if err := os.Remove(runtimeRecordPath(id)); err != nil && !os.IsNotExist(err) {
+        log.Printf("[record] remove failed. id=%v, err=%v", id, err)
+    }
+}
+
+// Verify 检查记录中的进程是否仍然是当初启动的那个浏览器进程，而不是复用了同一进程号的其他进程。
+// Linux 上比较进程的启动时间和命令行；其他 Unix 系统比较 ps 给出的命令行；Windows 上只检查进程是否存在。
+func (r *RuntimeRecord) Verify() bool {
+    if r.PID <= 0 || !processAlive(r.PID) {
+        return false
+    }
+    if procfs, ok := defaultProcessLister.(*ProcFS); ok {
+        dir := filepath.Join(procfs.Root, strconv.Itoa(r.PID))
+        stat, err := readProcStat(dir)
+        if err != nil || (r.ProcStart != 0 && stat.start != r.ProcStart) {
+            return false
+        }
+        data, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+        if err != nil {
+            return false
+        }
+        return r.matchArgs(parseCmdline(data))
+    }
+    if runtime.GOOS == "windows" {
+        return true
+    }
+    output, err := exec.Command("ps", "-p", strconv.Itoa(r.PID), "-o", "command=").Output()
+    if err != nil {
+        return false
+    }
+    return strings.HasPrefix(strings.TrimSpace(string(output)), r.Executable)
+}
+
+// matchArgs 检查进程的命令行是否与记录一致：可执行文件相同（或是其对应的浏览器主程序），且 --user-data-dir 参数相同。
+// 浏览器可能会改写自己的命令行，因此不要求其余参数完全一致。
+func (r *RuntimeRecord) matchArgs(args []string) bool {
+    if len(args) == 0 || (args[0] != r.Executable && !resolveBrowserBinary(r.Executable).matches(Process{Args: args})) {
+        return false
+    }
+    want, wantOK := userDataDirArg(r.Args)
+    got, gotOK := userDataDirArg(args)
+    return wantOK == gotOK && want == got
+}
@@ -0,0 +1,154 @@
+package chrome
+
+import (
+    "chromes/cdp"
+    "context"
+    "fmt"
+    "log"
+    "time"
+)
+
+// 渲染的输出格式。
+const (
+    RenderPNG = "png"
+    RenderPDF = "pdf"
+)
+
+// 等待页面加载的条件，对应 document.readyState。
+const (
+    WaitLoad             = "load"             // 页面及其图片、样式表等子资源都已加载（readyState 为 complete）
+    WaitDOMContentLoaded = "domcontentloaded" // 文档已解析完成（readyState 为 interactive 或 complete）
+    WaitNone             = "none"             // 导航提交后立即渲染
+)
+
+// 渲染选项的默认值。
+const (
+    defaultViewportWidth  = 1280
+    defaultViewportHeight = 800
+    defaultRenderTimeout  = 30 * time.Second
+)
+
+// RenderOptions 是渲染页面的选项，零值表示使用默认值。
+type RenderOptions struct {
+    Format    string        // RenderPNG（默认）或 RenderPDF
+    Width     int           // 视口宽度（CSS 像素），默认 1280
+    Height    int           // 视口高度（CSS 像素），默认 800
+    WaitUntil string        // WaitLoad（默认）、WaitDOMContentLoaded 或 WaitNone
+    Timeout   time.Duration // 从打开页面到得到结果的期限，默认 30 秒
+}
+
+// withDefaults 返回填充了默认值的选项，并检查选项是否合法。
+func (o RenderOptions) withDefaults() (RenderOptions, error) {
+    if o.Format == "" {
+        o.Format = RenderPNG
+    }
+    if o.Width == 0 {
+        o.Width = defaultViewportWidth
+    }
+    if o.Height == 0 {
+        o.Height = defaultViewportHeight
+    }
+    if o.WaitUntil == "" {
+        o.WaitUntil = WaitLoad
+    }
+    if o.Timeout == 0 {
+        o.Timeout = defaultRenderTimeout
+    }
+    switch {
+    case o.Format != RenderPNG && o.Format != RenderPDF:
+        return o, fmt.Errorf("unsupported render format %s", o.Format)
+    case o.Width < 0 || o.Height < 0:
+        return o, fmt.Errorf("invalid viewport %dx%d", o.Width, o.Height)
+    case o.WaitUntil != WaitLoad && o.WaitUntil != WaitDOMContentLoaded && o.WaitUntil != WaitNone:
+        return o, fmt.Errorf("unsupported wait condition %s", o.WaitUntil)
+    case o.Timeout < 0:
+        return o, fmt.Errorf("invalid render timeout %v", o.Timeout)
+    }
+    return o, nil
+}
+
+// Render 在以无界面模式运行的实例中打开 url，等待页面加载后渲染为 PNG 截图或 PDF，返回文件内容。
+// 页面在新标签页中打开，使用配置的 Cookie 和登录状态，渲染结束后关闭该标签页。
+// 实例需要先通过 StartHeadless 启动；有界面的实例不会被用来渲染。
+func (ci *Instance) Render(ctx context.Context, url string, opts RenderOptions) ([]byte, error) {
+    opts, err := opts.withDefaults()
+    if err != nil {
+        return nil, err
+    }
+    if !ci.Headless() {
+        return nil, fmt.Errorf("chrome instance %s is not running headless", ci.Config())
+    }
+    ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+    defer cancel()
+
+    endpoint, err := ci.WaitDevTools(ctx)
+    if err != nil {
+        return nil, err
+    }
+    browser, err := cdp.Dial(ctx, endpoint.WebSocketDebuggerURL)
+    if err != nil {
+        return nil, err
+    }
+    defer browser.Close()
+    targetID, err := browser.CreateTarget(ctx, "about:blank")
+    if err != nil {
+        return nil, err
+    }
+    defer func() {
+        // 超时后 ctx 已经结束，关闭标签页使用单独的期限
+        closeCtx, cancel := context.WithTimeout(context.Background(), devToolsTimeout)
+        defer cancel()
+        if err := browser.CloseTarget(closeCtx, targetID); err != nil {
+            log.Printf("[render] close tab failed. config=%v, target=%v, err=%v", ci.Config(), targetID, err)
+        }
+    }()
+
+    page, err := cdp.Dial(ctx, fmt.Sprintf("ws://127.0.0.1:%d/devtools/page/%s", endpoint.Port, targetID))
+    if err != nil {
+        return nil, err
+    }
+    defer page.Close()
+    if err := page.SetViewport(ctx, opts.Width, opts.Height); err != nil {
+        return nil, err
+    }
+    if err := page.Navigate(ctx, url); err != nil {
+        return nil, err
+    }
+    if err := waitReadyState(ctx, page, opts.WaitUntil); err != nil {
+        return nil, fmt.Errorf("failed to wait for %s to %s: %w", url, opts.WaitUntil, err)
+    }
+
+    var data []byte
+    if opts.Format == RenderPDF {
+        data, err = page.PrintToPDF(ctx)
+    } else {
+        data, err = page.CaptureScreenshot(ctx)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to render %s: %w", url, err)
+    }
+    log.Printf("[render] finished. config=%v, url=%v, format=%v, size=%d bytes", ci.Config(), url, opts.Format, len(data))
+    return data, nil
+}
+
+// waitReadyState 轮询页面的 document.readyState，直到满足 waitUntil 或 ctx 结束。
+// 导航提交后页面已经是新的文档，因此不会读到之前的 about:blank 的状态。
+func waitReadyState(ctx context.Context, page *cdp.Client, waitUntil string) error {
+    if waitUntil == WaitNone {
+        return nil
+    }
+    for {
+        var state string
+        if err := page.Evaluate(ctx, "document.readyState", &state); err != nil {
+            return err
+        }
+        if state == "complete" || (state == "interactive" && waitUntil == WaitDOMContentLoaded) {
+            return nil
+        }
+        select {
+        case <-ctx.Done():
+            return fmt.Errorf("page is still %s: %w", state, ctx.Err())
+        case <-time.After(pollInterval):
+        }
+    }
+}
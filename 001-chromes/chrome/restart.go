@@ -0,0 +1,150 @@
+package chrome
+
+import (
+    "chromes/config"
+    "errors"
+    "fmt"
+    "log"
+    "time"
+)
+
+const (
+    restartBackoffMin = time.Second      // 第一次自动重启前的等待时间，之后每次翻倍
+    restartBackoffMax = time.Minute      // 自动重启前等待时间的上限，第 7 次重启起达到
+    restartLimit      = 10               // restartWindow 内最多自动重启的次数，达到后放弃
+    restartWindow     = 10 * time.Minute // 统计自动重启次数的时间窗口
+)
+
+// RestartStatus 描述实例的自动重启情况，用于在界面中显示。
+type RestartStatus struct {
+    Count           int       // 自上次手动启动以来自动重启的次数
+    LastFailure     string    // 最近一次异常退出或自动重启失败的原因，没有时为空
+    LastFailureTime time.Time // 最近一次失败的时间
+    Next            time.Time // 计划中的下一次自动重启时间，没有计划时为零值
+    GaveUp          bool      // 时间窗口内的自动重启次数已达上限，不再自动重启
+}
+
+// Pending 返回是否有计划中的自动重启。
+func (s RestartStatus) Pending() bool {
+    return !s.Next.IsZero()
+}
+
+// RestartStatus 返回实例当前的自动重启情况。
+func (ci *Instance) RestartStatus() RestartStatus {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+    return ci.restart
+}
+
+// CancelRestart 取消计划中的自动重启，返回是否确实有被取消的重启。
+func (ci *Instance) CancelRestart() bool {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+    return ci.cancelRestart()
+}
+
+// cancelRestart 取消计划中的自动重启，调用方需持有 ci.mu。
+func (ci *Instance) cancelRestart() bool {
+    if ci.restartTimer == nil {
+        return false
+    }
+    ci.restartTimer.Stop()
+    ci.restartTimer = nil
+    ci.restart.Next = time.Time{}
+    log.Printf("[restart] canceled. config=%v", ci.config)
+    return true
+}
+
+// recordFailure 记录一次异常退出或启动失败的原因，调用方需持有 ci.mu。
+func (ci *Instance) recordFailure(err error) {
+    ci.restart.LastFailure = err.Error()
+    ci.restart.LastFailureTime = time.Now()
+}
+
+// scheduleRestart 在由本程序持有的浏览器退出后，按配置的重启策略安排自动重启，调用方需持有 ci.mu。
+// failed 表示浏览器是异常退出的。返回写入退出事件 Detail 的说明，没有安排重启时为空。
+// 等待时间从 restartBackoffMin 开始随时间窗口内的重启次数翻倍，窗口内的次数达到 restartLimit 后放弃。
+func (ci *Instance) scheduleRestart(failed bool) string {
+    switch ci.config.RestartPolicy() {
+    case config.RestartAlways:
+    case config.RestartOnFailure:
+        if !failed {
+            return ""
+        }
+    default:
+        return ""
+    }
+
+    now := time.Now()
+    recent := ci.restarts[:0]
+    for _, t := range ci.restarts {
+        if now.Sub(t) < restartWindow {
+            recent = append(recent, t)
+        }
+    }
+    ci.restarts = recent
+    if len(recent) >= restartLimit {
+        ci.restart.GaveUp = true
+        log.Printf("[restart] limit reached, giving up. config=%v, restarts=%d, window=%v", ci.config, len(recent), restartWindow)
+        return fmt.Sprintf("restart limit reached (%d in %v)", restartLimit, restartWindow)
+    }
+
+    delay := restartDelay(len(recent))
+    ci.cancelRestart()
+    var timer *time.Timer
+    timer = time.AfterFunc(delay, func() { ci.autoRestart(timer) })
+    ci.restartTimer = timer
+    ci.restart.Next = now.Add(delay)
+    log.Printf("[restart] scheduled. config=%v, delay=%v, recent=%d", ci.config, delay, len(recent))
+    return fmt.Sprintf("restarting in %v", delay)
+}
+
+// restartDelay 返回时间窗口内已经自动重启 recent 次后，下一次重启前的等待时间。
+func restartDelay(recent int) time.Duration {
+    delay := restartBackoffMin
+    for i := 0; i < recent && delay < restartBackoffMax; i++ {
+        delay *= 2
+    }
+    return min(delay, restartBackoffMax)
+}
+
+// resetRestarts 取消计划中的自动重启并重新开始计算重启次数：手动启动取代自动重启。调用方需持有 ci.mu。
+func (ci *Instance) resetRestarts() {
+    ci.cancelRestart()
+    ci.restarts = nil
+    ci.restart.Count = 0
+    ci.restart.GaveUp = false
+}
+
+// autoRestart 在等待时间结束后重新启动浏览器，timer 标识这是哪一次计划的重启。
+// 退出的浏览器没有机会删除自己的 SingletonLock，因此进程号与它相同的残留锁会被直接清理；
+// 启动失败同样计入重启次数，并按退避继续尝试。
+func (ci *Instance) autoRestart(timer *time.Timer) {
+    ci.mu.Lock()
+    defer ci.mu.Unlock()
+    if ci.restartTimer != timer {
+        return // 已被取消或重新安排
+    }
+    ci.restartTimer = nil
+    ci.restart.Next = time.Time{}
+    if ci.state.Active() {
+        return // 浏览器已经由其他途径启动
+    }
+
+    ci.restarts = append(ci.restarts, time.Now())
+    ci.restart.Count++
+    err := ci.start(fmt.Sprintf("auto restart #%d", ci.restart.Count))
+    var staleErr *StaleLockError
+    if errors.As(err, &staleErr) && staleErr.Lock.PID == ci.exitedPID {
+        if _, cleanErr := removeStaleSingletonLock(ci.config.UserDataDir); cleanErr == nil {
+            err = ci.start(fmt.Sprintf("auto restart #%d", ci.restart.Count))
+        }
+    }
+    if err == nil || ci.state.Active() {
+        return
+    }
+    err = fmt.Errorf("auto restart of chrome %s failed: %w", ci.config, err)
+    ci.recordFailure(err)
+    detail := ci.scheduleRestart(true)
+    ci.transition(Event{To: ci.state, ExitCode: noExitCode, Detail: detail, Err: err})
+}
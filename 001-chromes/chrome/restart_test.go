@@ -0,0 +1,114 @@
+package chrome
+
+import (
+    "strings"
+    "testing"
+    "time"
+
+    "chromes/config"
+)
+
+func TestRestartDelay(t *testing.T) {
+    want := []time.Duration{
+        time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second,
+        time.Minute, time.Minute, time.Minute, time.Minute,
+    }
+    if len(want) != restartLimit {
+        t.Fatalf("table covers %d restarts, restartLimit is %d", len(want), restartLimit)
+    }
+    for recent, delay := range want {
+        if got := restartDelay(recent); got != delay {
+            t.Errorf("restartDelay(%d) = %v, want %v", recent, got, delay)
+        }
+    }
+    if got := restartDelay(100); got != restartBackoffMax {
+        t.Errorf("restartDelay(100) = %v, want %v", got, restartBackoffMax)
+    }
+}
+
+// newRestartInstance 返回使用 policy 重启策略、已在时间窗口内自动重启过 recent 次的实例。
+func newRestartInstance(t *testing.T, policy string, recent int) *Instance {
+    t.Helper()
+    cfg := &config.ChromeConfig{ID: config.NewConfigID(), Name: "restart", UserDataDir: t.TempDir(), Restart: policy}
+    ci := NewInstance(cfg, NewRegistry(&config.Settings{}))
+    now := time.Now()
+    for i := 0; i < recent; i++ {
+        ci.restarts = append(ci.restarts, now.Add(-time.Duration(i)*time.Second))
+    }
+    t.Cleanup(func() { ci.CancelRestart() })
+    return ci
+}
+
+func TestScheduleRestartBackoff(t *testing.T) {
+    for recent := 0; recent < restartLimit; recent++ {
+        ci := newRestartInstance(t, config.RestartAlways, recent)
+        before := time.Now()
+        detail := ci.scheduleRestart(false)
+        status := ci.RestartStatus()
+        if !status.Pending() || status.GaveUp {
+            t.Fatalf("recent=%d: restart not scheduled: %+v", recent, status)
+        }
+        delay := restartDelay(recent)
+        if status.Next.Before(before.Add(delay)) || status.Next.After(time.Now().Add(delay)) {
+            t.Errorf("recent=%d: next restart at %v, want about %v from now", recent, status.Next, delay)
+        }
+        if detail != "restarting in "+delay.String() {
+            t.Errorf("recent=%d: detail %q", recent, detail)
+        }
+    }
+}
+
+func TestScheduleRestartGivesUpAtLimit(t *testing.T) {
+    ci := newRestartInstance(t, config.RestartAlways, restartLimit)
+    detail := ci.scheduleRestart(true)
+    status := ci.RestartStatus()
+    if !status.GaveUp || status.Pending() {
+        t.Fatalf("got %+v, want to give up without scheduling", status)
+    }
+    if !strings.HasPrefix(detail, "restart limit reached") {
+        t.Errorf("detail %q", detail)
+    }
+
+    // 时间窗口之外的重启不计入次数
+    ci = newRestartInstance(t, config.RestartAlways, 0)
+    for i := 0; i < restartLimit; i++ {
+        ci.restarts = append(ci.restarts, time.Now().Add(-restartWindow-time.Minute))
+    }
+    ci.scheduleRestart(true)
+    if status := ci.RestartStatus(); status.GaveUp || !status.Pending() {
+        t.Errorf("old restarts counted: %+v", status)
+    }
+    if len(ci.restarts) != 0 {
+        t.Errorf("old restarts kept: %d", len(ci.restarts))
+    }
+
+    // 手动启动后重新计数
+    ci = newRestartInstance(t, config.RestartAlways, restartLimit)
+    ci.scheduleRestart(true)
+    ci.resetRestarts()
+    ci.scheduleRestart(true)
+    if status := ci.RestartStatus(); status.GaveUp || !status.Pending() {
+        t.Errorf("restarts not reset: %+v", status)
+    }
+}
+
+func TestScheduleRestartPolicy(t *testing.T) {
+    tests := []struct {
+        policy string
+        failed bool
+        want   bool
+    }{
+        {"", true, false},
+        {config.RestartNever, true, false},
+        {config.RestartOnFailure, false, false},
+        {config.RestartOnFailure, true, true},
+        {config.RestartAlways, false, true},
+    }
+    for _, tt := range tests {
+        ci := newRestartInstance(t, tt.policy, 0)
+        detail := ci.scheduleRestart(tt.failed)
+        if got := ci.RestartStatus().Pending(); got != tt.want || (detail != "") != tt.want {
+            t.Errorf("policy %q, failed=%v: pending=%v, detail=%q", tt.policy, tt.failed, got, detail)
+        }
+    }
+}
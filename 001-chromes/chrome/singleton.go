@@ -0,0 +1,94 @@
+package chrome
+
+import (
+    "chromes/config"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+// singletonFiles 是 Chrome 在用户数据目录中用于保证单实例的文件，SingletonLock 必须排在第一位。
+// 三者同时创建、同时失效，清理残留锁时一起删除。
+var singletonFiles = []string{config.SingletonLockName, "SingletonSocket", "SingletonCookie"}
+
+// SingletonLock 描述 Chrome 在用户数据目录中创建的 SingletonLock 符号链接，链接目标为 "<主机名>-<进程号>"。
+// macOS 和 Linux 上的 Chrome 启动时创建它、退出时删除它，因此它比扫描进程命令行更能说明目录被谁占用。
+type SingletonLock struct {
+    Path     string // SingletonLock 的路径
+    Hostname string // 持有锁的主机名
+    PID      int    // 持有锁的浏览器主进程号
+}
+
+// ReadSingletonLock 读取用户数据目录中的 SingletonLock。目录中没有锁时返回 nil, nil。
+func ReadSingletonLock(userDataDir string) (*SingletonLock, error) {
+    path := filepath.Join(userDataDir, singletonFiles[0])
+    target, err := os.Readlink(path)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil, nil
+        }
+        return nil, fmt.Errorf("failed to read %s: %w", path, err)
+    }
+
+    // 主机名中可能包含 "-"，进程号总在最后一个 "-" 之后
+    sep := strings.LastIndex(target, "-")
+    if sep < 0 {
+        return nil, fmt.Errorf("malformed singleton lock %s -> %s", path, target)
+    }
+    pid, err := strconv.Atoi(target[sep+1:])
+    if err != nil || pid <= 0 {
+        return nil, fmt.Errorf("malformed singleton lock %s -> %s", path, target)
+    }
+    return &SingletonLock{Path: path, Hostname: target[:sep], PID: pid}, nil
+}
+
+// IsStale 检查锁是否已失效：持有锁的主机不是本机，或者持有锁的进程已经不存在。
+// 其他主机的锁通常来自同步或挂载到多台机器上的目录，本机无法判断它是否仍然有效，同样视为失效。
+func (l *SingletonLock) IsStale() bool {
+    if hostname, err := os.Hostname(); err == nil && hostname != l.Hostname {
+        return true
+    }
+    return !processAlive(l.PID)
+}
+
+// String 返回锁在日志和错误信息中的表示。
+func (l *SingletonLock) String() string {
+    return fmt.Sprintf("%s (host %s, pid %d)", l.Path, l.Hostname, l.PID)
+}
+
+// StaleLockError 表示用户数据目录中残留着已失效的 SingletonLock，此时启动浏览器会报告配置文件正被使用。
+// 调用 Instance.CleanStaleLock 清理后即可重新启动。
+type StaleLockError struct {
+    Lock *SingletonLock
+}
+
+func (e *StaleLockError) Error() string {
+    return fmt.Sprintf("stale singleton lock %s, the profile was not shut down cleanly", e.Lock)
+}
+
+// ErrLockNotStale 表示试图清理的 SingletonLock 仍被存活的浏览器进程持有。
+var ErrLockNotStale = errors.New("singleton lock is held by a running browser")
+
+// removeStaleSingletonLock 删除用户数据目录中已失效的 SingletonLock 及其相关文件。
+// 目录中没有锁时返回 false, nil；锁仍然有效时返回 ErrLockNotStale。
+func removeStaleSingletonLock(userDataDir string) (bool, error) {
+    lock, err := ReadSingletonLock(userDataDir)
+    if err != nil || lock == nil {
+        return false, err
+    }
+    if !lock.IsStale() {
+        return false, fmt.Errorf("%w: %s", ErrLockNotStale, lock)
+    }
+    for _, name := range singletonFiles {
+        path := filepath.Join(userDataDir, name)
+        if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+            return false, fmt.Errorf("failed to remove %s: %w", path, err)
+        }
+    }
+    log.Printf("[singleton] removed stale lock. lock=%v", lock)
+    return true, nil
+}
@@ -0,0 +1,111 @@
+package chrome
+
+import (
+    "log"
+    "sync"
+    "time"
+)
+
+// State 是 Instance 的生命周期状态。
+//
+// 状态转换：
+//   - Stopped/Crashed -> Starting -> Running：由本程序启动，包括按重启策略自动重启；
+//   - Running/Unknown -> Stopping -> Stopped：由本程序停止，停止失败时回到原状态；
+//   - Running -> Stopped/Crashed：浏览器自行退出，退出码非 0 或被信号结束时为 Crashed；
+//   - Stopped/Crashed <-> Unknown：检测到浏览器由其他途径启动或退出。
+type State int
+
+const (
+    StateStopped  State = iota // 没有浏览器进程使用此配置
+    StateStarting              // 正在启动浏览器进程
+    StateRunning               // 由本程序启动的浏览器正在运行
+    StateStopping              // 已请求浏览器退出，等待所有进程退出
+    StateCrashed               // 由本程序启动的浏览器异常退出
+    StateUnknown               // 浏览器正在运行，但不是由本程序启动的（例如桌面快捷方式或重启前的本程序）
+)
+
+func (s State) String() string {
+    switch s {
+    case StateStopped:
+        return "stopped"
+    case StateStarting:
+        return "starting"
+    case StateRunning:
+        return "running"
+    case StateStopping:
+        return "stopping"
+    case StateCrashed:
+        return "crashed"
+    case StateUnknown:
+        return "unknown"
+    default:
+        return "invalid"
+    }
+}
+
+// Active 返回此状态下是否有浏览器进程在使用配置。
+func (s State) Active() bool {
+    switch s {
+    case StateStarting, StateRunning, StateStopping, StateUnknown:
+        return true
+    default:
+        return false
+    }
+}
+
+// noExitCode 是 Event.ExitCode 在没有进程退出码时的取值。
+const noExitCode = -1
+
+// Event 描述一次状态转换。
+type Event struct {
+    ID       string    // 配置 ID
+    From     State     // 转换前的状态
+    To       State     // 转换后的状态
+    Time     time.Time // 转换发生的时间
+    PID      int       // 相关的浏览器主进程号，未知时为 0
+    ExitCode int       // 由本程序启动的进程退出时的退出码，被信号结束或不适用时为 -1
+    Detail   string    // 补充说明，例如停止时最终生效的步骤
+    Err      error     // 导致此次转换的错误，例如启动失败、停止失败或异常退出
+}
+
+// eventBuffer 是每个订阅者通道的缓冲大小，订阅者处理不及时、缓冲已满时新的事件会被丢弃。
+const eventBuffer = 64
+
+// broadcaster 将事件分发给所有订阅者。发布永不阻塞，因此可以在持有其他锁时调用。
+type broadcaster struct {
+    subs map[chan Event]struct{} // 当前的订阅者
+    mu   sync.Mutex              // 保护 subs
+}
+
+// subscribe 注册一个订阅者，返回接收事件的通道和取消订阅的函数。
+// 取消订阅后通道会被关闭；取消函数可以重复调用。
+func (b *broadcaster) subscribe() (<-chan Event, func()) {
+    ch := make(chan Event, eventBuffer)
+    b.mu.Lock()
+    if b.subs == nil {
+        b.subs = make(map[chan Event]struct{})
+    }
+    b.subs[ch] = struct{}{}
+    b.mu.Unlock()
+
+    cancel := sync.OnceFunc(func() {
+        b.mu.Lock()
+        defer b.mu.Unlock()
+        delete(b.subs, ch)
+        close(ch)
+    })
+    return ch, cancel
+}
+
+// publish 将事件发送给所有订阅者。
+func (b *broadcaster) publish(ev Event) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    for ch := range b.subs {
+        select {
+        case ch <- ev:
+        default:
+            log.Printf("[event] subscriber is full, event dropped. id=%v, from=%v, to=%v", ev.ID, ev.From, ev.To)
+        }
+    }
+}
@@ -0,0 +1,255 @@
+package chrome
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "runtime"
+    "slices"
+    "strconv"
+    "strings"
+    "syscall"
+    "time"
+)
+
+// killWait 是强制结束进程后等待它们消失的时间。
+const killWait = 3 * time.Second
+
+// pollInterval 是等待进程退出时检查进程是否存在的间隔。
+const pollInterval = 100 * time.Millisecond
+
+// StopResult 表示停止实例时最终生效的步骤。
+type StopResult int
+
+const (
+    StopAlreadyExited StopResult = iota // 发送信号前进程已经退出
+    StopGraceful                        // 浏览器收到终止信号后在期限内自行退出
+    StopKilled                          // 超过期限后被强制结束，浏览器下次启动时可能提示未正常关闭
+    StopBrowserClose                    // 浏览器通过 DevTools 的 Browser.close 正常关闭，会话状态得以保存
+)
+
+func (r StopResult) String() string {
+    switch r {
+    case StopAlreadyExited:
+        return "already exited"
+    case StopGraceful:
+        return "exited gracefully"
+    case StopKilled:
+        return "killed after deadline"
+    case StopBrowserClose:
+        return "closed via devtools"
+    default:
+        return "unknown"
+    }
+}
+
+// stopProcessTree 停止 roots 中的进程及其所有子孙进程（渲染进程、GPU 进程等）。
+// closeBrowser 不为 nil 时先通过它请求浏览器自行关闭（DevTools 的 Browser.close），并在 timeout 内等待整棵进程树退出；
+// 之后（或没有 closeBrowser 时）请求浏览器主进程正常退出（Unix 上发送 SIGTERM，Windows 上不带 /F 执行 taskkill），
+// 在 timeout 内等待整棵进程树退出，超时后强制结束仍然存在的进程。
+func stopProcessTree(roots []int, timeout time.Duration, closeBrowser func() error) (StopResult, error) {
+    // 子进程在父进程退出后会被重新挂到 init 下，因此必须在发送信号前确定整棵进程树
+    tree := processTree(roots)
+    if len(alivePIDs(tree)) == 0 {
+        return StopAlreadyExited, nil
+    }
+
+    if closeBrowser != nil {
+        if err := closeBrowser(); err != nil {
+            log.Printf("[stop] close via devtools failed, falling back to signals. pids=%v, err=%v", roots, err)
+        } else if waitExit(tree, timeout) {
+            return StopBrowserClose, nil
+        } else {
+            log.Printf("[stop] browser did not exit after Browser.close, falling back to signals. timeout=%v, pids=%v", timeout, alivePIDs(tree))
+        }
+    }
+
+    var errs []error
+    for _, pid := range alivePIDs(roots) {
+        if err := terminateProcess(pid); err != nil {
+            errs = append(errs, fmt.Errorf("failed to terminate pid %d: %w", pid, err))
+        }
+    }
+    if waitExit(tree, timeout) {
+        return StopGraceful, nil
+    }
+
+    // 等待期间浏览器可能又启动了新的子进程，强制结束前重新收集一次
+    for _, pid := range processTree(tree) {
+        if !slices.Contains(tree, pid) {
+            tree = append(tree, pid)
+        }
+    }
+    remaining := alivePIDs(tree)
+    log.Printf("[stop] deadline exceeded, killing. timeout=%v, pids=%v", timeout, remaining)
+    for _, pid := range remaining {
+        if err := killProcess(pid); err != nil {
+            errs = append(errs, fmt.Errorf("failed to kill pid %d: %w", pid, err))
+        }
+    }
+    if waitExit(tree, killWait) {
+        return StopKilled, nil
+    }
+    errs = append(errs, fmt.Errorf("processes %v are still alive after being killed", alivePIDs(tree)))
+    return StopKilled, errors.Join(errs...)
+}
+
+// waitExit 等待 pids 中的进程全部退出，返回是否在 timeout 内全部退出。
+func waitExit(pids []int, timeout time.Duration) bool {
+    deadline := time.Now().Add(timeout)
+    for {
+        if len(alivePIDs(pids)) == 0 {
+            return true
+        }
+        if time.Now().After(deadline) {
+            return false
+        }
+        time.Sleep(pollInterval)
+    }
+}
+
+// alivePIDs 返回 pids 中仍然存在的进程。
+func alivePIDs(pids []int) []int {
+    var alive []int
+    for _, pid := range pids {
+        if processAlive(pid) {
+            alive = append(alive, pid)
+        }
+    }
+    return alive
+}
+
+// processTree 返回 roots 及其所有子孙进程的进程号。无法获取进程列表时只返回 roots。
+func processTree(roots []int) []int {
+    parents, err := processParents()
+    if err != nil {
+        log.Printf("[stop] list processes failed, children will not be addressed. err=%v", err)
+        return slices.Clone(roots)
+    }
+    children := make(map[int][]int)
+    for pid, ppid := range parents {
+        children[ppid] = append(children[ppid], pid)
+    }
+
+    tree := slices.Clone(roots)
+    for i := 0; i < len(tree); i++ {
+        for _, child := range children[tree[i]] {
+            if !slices.Contains(tree, child) {
+                tree = append(tree, child)
+            }
+        }
+    }
+    return tree
+}
+
+// processParents 返回系统中所有进程的父进程号（进程号 -> 父进程号）。
+// 优先使用 defaultProcessLister，否则通过 ps 获取；Windows 上由 taskkill /T 处理进程树，不需要此信息。
+func processParents() (map[int]int, error) {
+    if defaultProcessLister != nil {
+        processes, err := defaultProcessLister.Processes()
+        if err == nil {
+            parents := make(map[int]int, len(processes))
+            for _, proc := range processes {
+                parents[proc.PID] = proc.PPID
+            }
+            return parents, nil
+        }
+    }
+    if runtime.GOOS == "windows" {
+        return nil, nil
+    }
+
+    output, err := exec.Command("ps", "-A", "-o", "pid=,ppid=").Output()
+    if err != nil {
+        return nil, fmt.Errorf("failed to list processes: %w", err)
+    }
+    parents := make(map[int]int)
+    for _, line := range strings.Split(string(output), "\n") {
+        fields := strings.Fields(line)
+        if len(fields) != 2 {
+            continue
+        }
+        pid, err1 := strconv.Atoi(fields[0])
+        ppid, err2 := strconv.Atoi(fields[1])
+        if err1 == nil && err2 == nil {
+            parents[pid] = ppid
+        }
+    }
+    return parents, nil
+}
+
+// terminateProcess 请求进程正常退出。
+// Windows 上不带 /F 的 taskkill 会向进程树中的窗口发送关闭消息，与 Unix 上的 SIGTERM 相当。
+func terminateProcess(pid int) error {
+    if runtime.GOOS == "windows" {
+        return taskkill(pid, false)
+    }
+    return ignoreFinished(signalProcess(pid, syscall.SIGTERM))
+}
+
+// killProcess 强制结束进程。
+func killProcess(pid int) error {
+    if runtime.GOOS == "windows" {
+        return taskkill(pid, true)
+    }
+    return ignoreFinished(signalProcess(pid, syscall.SIGKILL))
+}
+
+// taskkill 结束 Windows 上的进程及其子进程，force 为 true 时强制结束。
+func taskkill(pid int, force bool) error {
+    args := []string{"/PID", strconv.Itoa(pid), "/T"}
+    if force {
+        args = append(args, "/F")
+    }
+    err := exec.Command("taskkill", args...).Run()
+    if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 128 {
+        return nil // 进程已不存在
+    }
+    return err
+}
+
+// ignoreFinished 忽略向已退出的进程发送信号时的错误。
+func ignoreFinished(err error) error {
+    if errors.Is(err, os.ErrProcessDone) || errors.Is(err, syscall.ESRCH) {
+        return nil
+    }
+    return err
+}
+
+// signalProcess 向本机上指定进程号的进程发送信号。
+func signalProcess(pid int, sig os.Signal) error {
+    process, err := os.FindProcess(pid)
+    if err != nil {
+        return err
+    }
+    return process.Signal(sig)
+}
+
+// processAlive 检查本机上指定进程号的进程是否存在。
+// Unix 上发送 0 号信号只做权限和存在性检查，EPERM 说明进程存在但属于其他用户；
+// 已退出但尚未被回收的僵尸进程同样能收到信号，Linux 上通过 /proc 排除它们。
+// Windows 上 os.FindProcess 会打开进程句柄，进程不存在时返回错误。
+func processAlive(pid int) bool {
+    if runtime.GOOS == "windows" {
+        process, err := os.FindProcess(pid)
+        if err != nil {
+            return false
+        }
+        process.Release()
+        return true
+    }
+
+    err := signalProcess(pid, syscall.Signal(0))
+    if err != nil && !errors.Is(err, syscall.EPERM) {
+        return false
+    }
+    if procfs, ok := defaultProcessLister.(*ProcFS); ok {
+        if stat, err := readProcStat(filepath.Join(procfs.Root, strconv.Itoa(pid))); err == nil && stat.state == 'Z' {
+            return false
+        }
+    }
+    return true
+}
@@ -0,0 +1,64 @@
+package chrome
+
+import (
+    "chromes/cdp"
+    "context"
+)
+
+// withCDP 连接实例的远程调试端点并执行 fn，结束后关闭连接。
+func (ci *Instance) withCDP(ctx context.Context, fn func(*cdp.Client) error) error {
+    endpoint, err := ci.DevTools(ctx)
+    if err != nil {
+        return err
+    }
+    client, err := cdp.Dial(ctx, endpoint.WebSocketDebuggerURL)
+    if err != nil {
+        return err
+    }
+    defer client.Close()
+    return fn(client)
+}
+
+// Tabs 返回正在运行的实例中打开的标签页，不包括扩展页面和 Service Worker 等其他调试目标。
+// 实例需要开启远程调试（config.ChromeConfig.DevTools）。
+func (ci *Instance) Tabs(ctx context.Context) ([]cdp.TargetInfo, error) {
+    var tabs []cdp.TargetInfo
+    err := ci.withCDP(ctx, func(client *cdp.Client) error {
+        targets, err := client.Targets(ctx)
+        if err != nil {
+            return err
+        }
+        for _, target := range targets {
+            if target.IsTab() {
+                tabs = append(tabs, target)
+            }
+        }
+        return nil
+    })
+    return tabs, err
+}
+
+// OpenTab 在实例中打开一个新标签页并加载 url，返回新标签页的目标 ID。
+func (ci *Instance) OpenTab(ctx context.Context, url string) (string, error) {
+    var targetID string
+    err := ci.withCDP(ctx, func(client *cdp.Client) error {
+        var err error
+        targetID, err = client.CreateTarget(ctx, url)
+        return err
+    })
+    return targetID, err
+}
+
+// ActivateTab 激活实例中的标签页并将其窗口置于前台。
+func (ci *Instance) ActivateTab(ctx context.Context, targetID string) error {
+    return ci.withCDP(ctx, func(client *cdp.Client) error {
+        return client.ActivateTarget(ctx, targetID)
+    })
+}
+
+// CloseTab 关闭实例中的标签页。
+func (ci *Instance) CloseTab(ctx context.Context, targetID string) error {
+    return ci.withCDP(ctx, func(client *cdp.Client) error {
+        return client.CloseTarget(ctx, targetID)
+    })
+}
@@ -0,0 +1,137 @@
+package chrome
+
+import (
+    "chromes/cdp"
+    "chromes/config"
+    "context"
+    "fmt"
+    "log"
+    "strings"
+    "time"
+)
+
+// CaptureWorkspace 通过远程调试端点读取正在运行的实例中的窗口和标签页，保存为名为 name 的工作区。
+// 窗口按第一次出现的顺序排列；CDP 不提供标签页在窗口内的顺序，标签页按浏览器返回的目标顺序保存。
+func (ci *Instance) CaptureWorkspace(ctx context.Context, name string) (*config.Workspace, error) {
+    ws := &config.Workspace{Name: name, Created: time.Now()}
+    err := ci.withCDP(ctx, func(client *cdp.Client) error {
+        targets, err := client.Targets(ctx)
+        if err != nil {
+            return err
+        }
+        windows := make(map[int]int) // 窗口 ID -> ws.Windows 中的下标
+        for _, target := range targets {
+            if !target.IsTab() || target.URL == "" || strings.HasPrefix(target.URL, "devtools://") {
+                continue
+            }
+            windowID, bounds, err := client.WindowForTarget(ctx, target.TargetID)
+            if err != nil {
+                return err
+            }
+            index, ok := windows[windowID]
+            if !ok {
+                index = len(ws.Windows)
+                windows[windowID] = index
+                ws.Windows = append(ws.Windows, config.WorkspaceWindow{
+                    Left:   bounds.Left,
+                    Top:    bounds.Top,
+                    Width:  bounds.Width,
+                    Height: bounds.Height,
+                    State:  bounds.WindowState,
+                })
+            }
+            ws.Windows[index].Tabs = append(ws.Windows[index].Tabs, config.WorkspaceTab{URL: target.URL, Title: target.Title})
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    if ws.TabCount() == 0 {
+        return nil, fmt.Errorf("chrome instance %s has no open tabs", ci.Config())
+    }
+    return ws, nil
+}
+
+// OpenWorkspace 在实例中重新打开工作区的窗口和标签页，并恢复窗口的位置和状态。
+// 实例未运行时先启动它并等待远程调试端点就绪，打开工作区后关闭浏览器启动时自带的标签页。
+// 配置没有开启远程调试时返回 ErrDevToolsDisabled，不会启动浏览器。
+func (ci *Instance) OpenWorkspace(ctx context.Context, ws *config.Workspace) error {
+    var initial []cdp.TargetInfo
+    if state := ci.State(); state != StateRunning && state != StateUnknown {
+        // Start 以有界面模式启动，之后是否开启远程调试只取决于配置
+        ci.mu.Lock()
+        enabled := ci.config.DevTools && ci.config.UserDataDir != ""
+        ci.mu.Unlock()
+        if !enabled {
+            return ErrDevToolsDisabled
+        }
+        if err := ci.Start(); err != nil {
+            return err
+        }
+        if _, err := ci.WaitDevTools(ctx); err != nil {
+            return err
+        }
+        tabs, err := ci.Tabs(ctx)
+        if err != nil {
+            return err
+        }
+        initial = tabs
+    }
+
+    err := ci.withCDP(ctx, func(client *cdp.Client) error {
+        for _, window := range ws.Windows {
+            if len(window.Tabs) == 0 {
+                continue
+            }
+            if err := openWorkspaceWindow(ctx, client, window); err != nil {
+                return err
+            }
+        }
+        for _, target := range initial {
+            if err := client.CloseTarget(ctx, target.TargetID); err != nil {
+                log.Printf("[chrome] failed to close initial tab. id=%v, url=%v, err=%v", ci.ID(), target.URL, err)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Errorf("failed to open workspace '%s': %w", ws.Name, err)
+    }
+    return nil
+}
+
+// openWorkspaceWindow 在新窗口中打开工作区窗口的标签页并恢复窗口的位置和状态。
+func openWorkspaceWindow(ctx context.Context, client *cdp.Client, window config.WorkspaceWindow) error {
+    first, err := client.NewWindow(ctx, window.Tabs[0].URL)
+    if err != nil {
+        return err
+    }
+    // 新标签页会打开在最近激活的窗口中，先激活新窗口以保证其余标签页打开在这里
+    if err := client.ActivateTarget(ctx, first); err != nil {
+        return err
+    }
+    for _, tab := range window.Tabs[1:] {
+        if _, err := client.CreateTarget(ctx, tab.URL); err != nil {
+            return err
+        }
+    }
+    if err := client.ActivateTarget(ctx, first); err != nil {
+        return err
+    }
+
+    if window.Width <= 0 || window.Height <= 0 {
+        return nil
+    }
+    windowID, _, err := client.WindowForTarget(ctx, first)
+    if err != nil {
+        return err
+    }
+    return client.SetWindowBounds(ctx, windowID, cdp.Bounds{
+        Left:        window.Left,
+        Top:         window.Top,
+        Width:       window.Width,
+        Height:      window.Height,
+        WindowState: window.State,
+    })
+}
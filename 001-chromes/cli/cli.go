@@ -0,0 +1,279 @@
+package cli
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "strings"
+
+    "chromes/api"
+    "chromes/chrome"
+    "chromes/config"
+)
+
+// 命令的退出码。脚本可以依赖这些取值，新增的情况只会使用新的取值。
+const (
+    ExitOK       = 0 // 成功
+    ExitError    = 1 // 执行失败，例如浏览器启动失败、配置保存失败
+    ExitUsage    = 2 // 命令行参数错误
+    ExitNotFound = 3 // 找不到指定的配置
+    ExitConflict = 4 // 实例的状态不允许此操作，例如启动已在运行的实例、停止没有运行的实例
+)
+
+// command 是一个子命令。
+type command struct {
+    name    string
+    args    string // 参数的用法，例如 "<配置>"
+    summary string
+    run     func(out *output, args []string) error
+}
+
+// commands 是所有子命令，按用法说明中的顺序排列。
+var commands = []command{
+    {"list", "", "列出所有配置及其运行状态", runList},
+    {"status", "[配置...]", "显示配置的详细状态，不指定配置时显示全部", runStatus},
+    {"add", "--name <名称> --dir <数据目录> [选项]", "新增配置", runAdd},
+    {"remove", "<配置>", "删除配置（不删除数据目录）", runRemove},
+    {"edit", "<配置> [选项]", "修改配置，只修改指定的选项", runEdit},
+    {"start", "<配置>", "启动实例", runStart},
+    {"stop", "<配置>", "停止实例，等待浏览器及其子进程退出", runStop},
+    {"restart", "<配置>", "停止（如果正在运行）并重新启动实例", runRestart},
+    {"logs", "<配置>", "显示实例的浏览器日志", runLogs},
+    {"open", "[--profile 配置] <网址>...", "在实例中打开网址，不指定配置时按链接路由选择", runOpen},
+    {"launch", "<配置>", "启动实例，已在运行时打开新窗口，供桌面启动器使用", runLaunch},
+    {"default-browser", "[--unset]", "把本程序注册为默认浏览器（Linux）", runDefaultBrowser},
+    {"render", "<配置> <网址> <输出文件>", "以无界面模式把网址渲染为 PNG 或 PDF", runRender},
+}
+
+// Run 执行 args 指定的子命令（不包括程序名），返回进程的退出码。
+// 所有命令都支持 --json 以 JSON 格式输出结果，以及 --verbose 输出运行日志。
+func Run(args []string) int {
+    if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+        printUsage(os.Stderr)
+        if len(args) == 0 {
+            return ExitUsage
+        }
+        return ExitOK
+    }
+    for _, cmd := range commands {
+        if cmd.name == args[0] {
+            out := &output{stdout: os.Stdout, stderr: os.Stderr}
+            return out.exit(cmd.run(out, args[1:]))
+        }
+    }
+    fmt.Fprintf(os.Stderr, "未知的命令 %s\n\n", args[0])
+    printUsage(os.Stderr)
+    return ExitUsage
+}
+
+// IsCommand 返回 name 是否为子命令的名称，用于判断程序是否以命令行方式运行。
+func IsCommand(name string) bool {
+    if name == "help" || name == "-h" || name == "--help" {
+        return true
+    }
+    for _, cmd := range commands {
+        if cmd.name == name {
+            return true
+        }
+    }
+    return false
+}
+
+// printUsage 输出所有子命令的用法。
+func printUsage(w io.Writer) {
+    fmt.Fprintln(w, "用法: chromes <命令> [选项] [参数]")
+    fmt.Fprintln(w)
+    fmt.Fprintln(w, "命令:")
+    for _, cmd := range commands {
+        fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.summary)
+    }
+    fmt.Fprintln(w)
+    fmt.Fprintln(w, "配置可以用名称或 ID 指定。使用 chromes <命令> --help 查看命令的选项。")
+    fmt.Fprintf(w, "退出码: %d 成功，%d 执行失败，%d 参数错误，%d 找不到配置，%d 实例状态冲突。\n", ExitOK, ExitError, ExitUsage, ExitNotFound, ExitConflict)
+}
+
+// exitError 是带有退出码的错误。
+type exitError struct {
+    code int
+    err  error
+}
+
+func (e *exitError) Error() string {
+    return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+    return e.err
+}
+
+// fail 返回以 code 退出的错误。
+func fail(code int, format string, args ...any) error {
+    return &exitError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+// errUsage 表示参数错误，用法已经输出，不需要再输出错误信息。
+var errUsage = &exitError{code: ExitUsage, err: errors.New("invalid arguments")}
+
+// output 是命令的公共选项和输出位置。
+type output struct {
+    json    bool // 以 JSON 格式输出结果和错误
+    verbose bool // 输出 config 和 chrome 包的运行日志，默认丢弃
+    stdout  io.Writer
+    stderr  io.Writer
+}
+
+// flags 创建子命令的选项集合，并注册所有命令共有的 --json 和 --verbose。
+func (o *output) flags(name string, args string, summary string) *flag.FlagSet {
+    flags := flag.NewFlagSet(name, flag.ContinueOnError)
+    flags.SetOutput(o.stderr)
+    flags.Usage = func() {
+        fmt.Fprintf(o.stderr, "用法: chromes %s [选项] %s\n\n%s\n\n选项:\n", name, args, summary)
+        flags.PrintDefaults()
+    }
+    flags.BoolVar(&o.json, "json", false, "以 JSON 格式输出")
+    flags.BoolVar(&o.verbose, "verbose", false, "输出运行日志")
+    return flags
+}
+
+// parse 解析子命令的选项，返回位置参数，并检查其个数在 min 和 max 之间（max 为 -1 时不限）。
+// 选项和位置参数可以交错，例如 "start work --json" 和 "start --json work"；"--" 之后的参数都是位置参数。
+func (o *output) parse(flags *flag.FlagSet, args []string, min int, max int) ([]string, error) {
+    var positional []string
+    for {
+        if err := flags.Parse(args); err != nil {
+            if errors.Is(err, flag.ErrHelp) {
+                return nil, &exitError{code: ExitOK, err: err}
+            }
+            return nil, errUsage
+        }
+        rest := flags.Args()
+        if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+            positional = append(positional, rest...)
+            break
+        }
+        if len(rest) == 0 {
+            break
+        }
+        positional = append(positional, rest[0])
+        args = rest[1:]
+    }
+    if len(positional) < min || (max >= 0 && len(positional) > max) {
+        flags.Usage()
+        return nil, errUsage
+    }
+    if !o.verbose {
+        log.SetOutput(io.Discard)
+    }
+    return positional, nil
+}
+
+// print 输出结果：--json 时把 v 编码为一行 JSON，否则调用 text 输出给人阅读的文本。
+func (o *output) print(v any, text func(w io.Writer)) {
+    if o.json {
+        json.NewEncoder(o.stdout).Encode(v)
+        return
+    }
+    text(o.stdout)
+}
+
+// exit 输出命令返回的错误并返回对应的退出码。
+func (o *output) exit(err error) int {
+    if err == nil {
+        return ExitOK
+    }
+    code := ExitError
+    var exitErr *exitError
+    if errors.As(err, &exitErr) {
+        code = exitErr.code
+        if exitErr == errUsage || code == ExitOK {
+            return code // 用法已经输出
+        }
+    }
+    if o.json {
+        json.NewEncoder(o.stderr).Encode(map[string]any{"error": err.Error(), "code": code})
+    } else {
+        fmt.Fprintf(o.stderr, "错误: %v\n", err)
+    }
+    return code
+}
+
+// stringList 是可以重复指定的字符串选项，例如多个 --flag。
+type stringList []string
+
+func (l *stringList) String() string {
+    return strings.Join(*l, " ")
+}
+
+func (l *stringList) Set(value string) error {
+    *l = append(*l, value)
+    return nil
+}
+
+// environment 是命令访问的配置和浏览器：已加载的配置列表和浏览器注册表。
+type environment struct {
+    settings *config.Settings
+    configs  []*config.ChromeConfig
+    registry *chrome.Registry
+}
+
+// load 加载全局设置和配置列表。配置文件损坏时仍使用能够加载的配置，并输出警告。
+func (o *output) load() *environment {
+    settings, settingsErr := config.LoadSettings()
+    configs, err := config.LoadConfigs()
+    if err == nil {
+        err = settingsErr
+    }
+    if err != nil && !o.json {
+        fmt.Fprintf(o.stderr, "警告: 加载配置失败，只使用能够加载的配置: %v\n", err)
+    }
+    return &environment{settings: settings, configs: configs, registry: chrome.NewRegistry(settings)}
+}
+
+// find 按 ID 或名称查找配置，名称对应多个配置时要求使用 ID。
+func (e *environment) find(name string) (*config.ChromeConfig, error) {
+    if cfg := config.FindConfig(e.configs, name); cfg != nil {
+        return cfg, nil
+    }
+    var ids []string
+    var match *config.ChromeConfig
+    for _, cfg := range e.configs {
+        if cfg.Name == name {
+            match = cfg
+            ids = append(ids, cfg.ID)
+        }
+    }
+    switch len(ids) {
+    case 0:
+        return nil, fail(ExitNotFound, "profile %s not found", name)
+    case 1:
+        return match, nil
+    default:
+        return nil, fail(ExitUsage, "more than one profile is named %s, use one of the ids: %s", name, strings.Join(ids, ", "))
+    }
+}
+
+// instance 查找配置并创建对应的实例，实例会检测浏览器是否已在运行（包括由图形界面启动的）。
+func (e *environment) instance(name string) (*chrome.Instance, error) {
+    cfg, err := e.find(name)
+    if err != nil {
+        return nil, err
+    }
+    return chrome.NewInstance(cfg, e.registry), nil
+}
+
+// connect 连接正在运行的管理器，管理器没有运行时返回 nil，此时命令直接读写配置文件、控制浏览器进程。
+// 管理器在运行时修改配置和控制实例的命令必须转交给它，否则它持有的配置列表和实例会与命令的修改不一致。
+func connect() (*api.Client, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), forwardTimeout)
+    defer cancel()
+    client, err := api.Connect(ctx)
+    if errors.Is(err, api.ErrNotRunning) {
+        return nil, nil
+    }
+    return client, err
+}
@@ -0,0 +1,312 @@
+package cli
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "os/signal"
+
+    "chromes/api"
+    "chromes/chrome"
+)
+
+// startOptions 是 start 和 restart 命令共有的选项。
+type startOptions struct {
+    headless   bool // 以无界面模式启动
+    cleanStale bool // 清理残留的 SingletonLock 后启动
+    wait       bool // 等待浏览器退出，期间按配置的重启策略自动重启
+}
+
+// lifecycleResult 是 start、stop 和 restart 命令的输出。
+type lifecycleResult struct {
+    profileInfo
+    Stop     string `json:"stop,omitempty"`      // 停止时最终生效的步骤，例如 "exited gracefully"、"killed"
+    ExitCode *int   `json:"exit_code,omitempty"` // 使用 --wait 时浏览器最后一次退出的退出码，未知时省略
+}
+
+func runStart(out *output, args []string) error {
+    var opts startOptions
+    flags := out.flags("start", "<配置>", "启动实例。默认在浏览器启动后立即返回，浏览器继续在后台运行，之后可以由图形界面或 stop 命令接管。")
+    opts.register(flags)
+    args, err := out.parse(flags, args, 1, 1)
+    if err != nil {
+        return err
+    }
+    client, err := connect()
+    if err != nil {
+        return err
+    }
+    env := out.load()
+    if client != nil {
+        return forwardStart(out, env, client, args[0], opts, "")
+    }
+    instance, err := env.instance(args[0])
+    if err != nil {
+        return err
+    }
+    return start(out, env, instance, opts, "")
+}
+
+func runStop(out *output, args []string) error {
+    flags := out.flags("stop", "<配置>", "停止实例：先请求浏览器正常退出，超过全局设置的期限后强制结束，等待浏览器及其子进程全部退出。")
+    args, err := out.parse(flags, args, 1, 1)
+    if err != nil {
+        return err
+    }
+    client, err := connect()
+    if err != nil {
+        return err
+    }
+    env := out.load()
+    if client != nil {
+        resp, err := client.Stop(context.Background(), args[0])
+        if err != nil {
+            return apiError(err)
+        }
+        out.print(lifecycleResult{profileInfo: newRemoteProfileInfo(env, resp.Profile), Stop: resp.Result}, func(w io.Writer) {
+            fmt.Fprintf(w, "已停止 %s (%s)\n", &resp.Profile.ChromeConfig, resp.Result)
+        })
+        return nil
+    }
+    instance, err := env.instance(args[0])
+    if err != nil {
+        return err
+    }
+    if !instance.IsRunning() {
+        return fail(ExitConflict, "chrome instance %s is not running", instance.Config())
+    }
+    result, err := instance.Stop()
+    if err != nil {
+        return err
+    }
+    out.print(lifecycleResult{profileInfo: newProfileInfo(env, instance), Stop: result.String()}, func(w io.Writer) {
+        fmt.Fprintf(w, "已停止 %s (%s)\n", instance.Config(), result)
+    })
+    return nil
+}
+
+func runRestart(out *output, args []string) error {
+    var opts startOptions
+    flags := out.flags("restart", "<配置>", "停止（如果正在运行）并重新启动实例。")
+    opts.register(flags)
+    args, err := out.parse(flags, args, 1, 1)
+    if err != nil {
+        return err
+    }
+    client, err := connect()
+    if err != nil {
+        return err
+    }
+    env := out.load()
+    if client != nil {
+        // 管理器停止没有运行的实例时返回冲突，此时直接启动
+        var stopped string
+        resp, err := client.Stop(context.Background(), args[0])
+        var apiErr *api.Error
+        switch {
+        case err == nil:
+            stopped = resp.Result
+        case !errors.As(err, &apiErr) || apiErr.Code != api.CodeConflict:
+            return apiError(err)
+        }
+        return forwardStart(out, env, client, args[0], opts, stopped)
+    }
+    instance, err := env.instance(args[0])
+    if err != nil {
+        return err
+    }
+    var stopped string
+    if instance.IsRunning() {
+        result, err := instance.Stop()
+        if err != nil {
+            return err
+        }
+        stopped = result.String()
+    }
+    return start(out, env, instance, opts, stopped)
+}
+
+// register 把启动选项注册到 flags 中。
+func (o *startOptions) register(flags *flag.FlagSet) {
+    flags.BoolVar(&o.headless, "headless", false, "以无界面模式启动（--headless=new），总是开启远程调试")
+    flags.BoolVar(&o.cleanStale, "clean-stale-lock", false, "数据目录中残留着已失效的 SingletonLock 时清理后启动")
+    flags.BoolVar(&o.wait, "wait", false, "等待浏览器退出后再返回，期间按配置的重启策略自动重启；中断时停止浏览器")
+}
+
+// start 启动实例并输出结果，stopped 是 restart 命令停止浏览器时最终生效的步骤。
+func start(out *output, env *environment, instance *chrome.Instance, opts startOptions, stopped string) error {
+    cfg := instance.Config()
+    if instance.IsRunning() {
+        return fail(ExitConflict, "chrome instance %s is already running (pid %d)", cfg, instance.PID())
+    }
+    events, unsubscribe := instance.Subscribe()
+    defer unsubscribe()
+
+    startInstance := instance.Start
+    if opts.headless {
+        startInstance = instance.StartHeadless
+    }
+    err := startInstance()
+    var staleErr *chrome.StaleLockError
+    if errors.As(err, &staleErr) && opts.cleanStale {
+        if _, err = instance.CleanStaleLock(); err == nil {
+            err = startInstance()
+        }
+    }
+    var inUseErr *chrome.InUseError
+    switch {
+    case errors.As(err, &staleErr):
+        return fail(ExitConflict, "%w (use --clean-stale-lock to remove it)", err)
+    case errors.As(err, &inUseErr):
+        return fail(ExitConflict, "%w", err)
+    case err != nil:
+        return err
+    }
+
+    result := lifecycleResult{profileInfo: newProfileInfo(env, instance), Stop: stopped}
+    if !opts.wait {
+        out.print(result, func(w io.Writer) {
+            fmt.Fprintf(w, "已启动 %s (pid %d)\n", cfg, result.PID)
+        })
+        return nil
+    }
+
+    if !out.json {
+        fmt.Fprintf(out.stdout, "已启动 %s (pid %d)，等待浏览器退出…\n", cfg, result.PID)
+    }
+    ev, err := waitExit(instance, events)
+    if err != nil {
+        return err
+    }
+    result.profileInfo = newProfileInfo(env, instance)
+    if ev.ExitCode >= 0 {
+        result.ExitCode = &ev.ExitCode
+    }
+    out.print(result, func(w io.Writer) {
+        fmt.Fprintf(w, "%s 已退出 (%s)\n", cfg, ev.To)
+    })
+    if ev.To == chrome.StateCrashed {
+        return fail(ExitError, "%w", ev.Err)
+    }
+    return nil
+}
+
+// waitExit 等待浏览器退出且没有计划中的自动重启，返回最后一次退出的事件。
+// 收到中断信号时停止浏览器，等它退出后返回。
+func waitExit(instance *chrome.Instance, events <-chan chrome.Event) (chrome.Event, error) {
+    ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer cancel()
+    interrupted := ctx.Done()
+    var last chrome.Event
+    for {
+        select {
+        case last = <-events:
+            if !last.To.Active() && !instance.RestartStatus().Pending() {
+                return last, nil
+            }
+        case <-interrupted:
+            interrupted = nil
+            cancel() // 再次中断时直接退出
+            if !instance.IsRunning() {
+                instance.CancelRestart() // 正在等待自动重启
+                return last, nil
+            }
+            if _, err := instance.Stop(); err != nil {
+                return last, err
+            }
+        }
+    }
+}
+
+// forwardStart 由正在运行的管理器启动实例并输出结果，使用 --wait 时通过管理器的事件流等待浏览器退出。
+func forwardStart(out *output, env *environment, client *api.Client, ref string, opts startOptions, stopped string) error {
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    var events <-chan api.Event
+    if opts.wait {
+        // 在启动前订阅，避免错过浏览器很快退出的事件
+        var err error
+        if events, err = client.Events(ctx); err != nil {
+            return apiError(err)
+        }
+    }
+    profile, err := client.Start(ctx, ref, api.StartRequest{Headless: opts.headless, CleanStaleLock: opts.cleanStale})
+    if err != nil {
+        return apiError(err)
+    }
+
+    cfg := &profile.ChromeConfig
+    result := lifecycleResult{profileInfo: newRemoteProfileInfo(env, profile), Stop: stopped}
+    if !opts.wait {
+        out.print(result, func(w io.Writer) {
+            fmt.Fprintf(w, "已启动 %s (pid %d)\n", cfg, result.PID)
+        })
+        return nil
+    }
+
+    if !out.json {
+        fmt.Fprintf(out.stdout, "已启动 %s (pid %d)，等待浏览器退出…\n", cfg, result.PID)
+    }
+    ev, err := waitRemoteExit(ctx, client, profile.ID, events)
+    if err != nil {
+        return err
+    }
+    if profile, err = client.Profile(ctx, profile.ID); err == nil {
+        result.profileInfo = newRemoteProfileInfo(env, profile)
+    }
+    if ev.ExitCode >= 0 {
+        result.ExitCode = &ev.ExitCode
+    }
+    out.print(result, func(w io.Writer) {
+        fmt.Fprintf(w, "%s 已退出 (%s)\n", cfg, ev.To)
+    })
+    if ev.To == chrome.StateCrashed.String() {
+        return fail(ExitError, "%s", ev.Error)
+    }
+    return nil
+}
+
+// waitRemoteExit 通过管理器的事件流等待浏览器退出且没有计划中的自动重启，返回最后一次退出的事件。
+// 收到中断信号时请求管理器停止浏览器，等它退出后返回。
+func waitRemoteExit(ctx context.Context, client *api.Client, id string, events <-chan api.Event) (api.Event, error) {
+    ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
+    defer cancel()
+    interrupted := ctx.Done()
+    var last api.Event
+    for {
+        select {
+        case ev, ok := <-events:
+            if !ok {
+                return last, errors.New("lost connection to the manager while waiting for the browser to exit")
+            }
+            if ev.ID != id {
+                continue
+            }
+            last = ev
+            if ev.To != chrome.StateStopped.String() && ev.To != chrome.StateCrashed.String() {
+                continue
+            }
+            profile, err := client.Profile(context.Background(), id)
+            if err != nil {
+                return last, apiError(err)
+            }
+            if !profile.RestartPending {
+                return last, nil
+            }
+        case <-interrupted:
+            interrupted = nil
+            cancel() // 再次中断时直接退出
+            _, err := client.Stop(context.Background(), id)
+            var apiErr *api.Error
+            if errors.As(err, &apiErr) && apiErr.Code == api.CodeConflict {
+                return last, nil // 浏览器已经退出，正在等待自动重启
+            }
+            if err != nil {
+                return last, apiError(err)
+            }
+        }
+    }
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "math"
+    "os"
+    "os/signal"
+    "strings"
+    "time"
+
+    "chromes/chrome"
+)
+
+// followInterval 是 logs --follow 检查日志文件新内容的间隔。
+const followInterval = 500 * time.Millisecond
+
+// logLine 是 logs 命令以 JSON 输出时的一行日志。
+type logLine struct {
+    Line     string `json:"line"`
+    Severity string `json:"severity"` // 没有级别前缀的续行沿用上一行的级别
+}
+
+// severityNames 是 --level 可以使用的严重级别。
+var severityNames = map[string]chrome.Severity{
+    "all":     chrome.SeverityUnknown,
+    "verbose": chrome.SeverityVerbose,
+    "info":    chrome.SeverityInfo,
+    "warning": chrome.SeverityWarning,
+    "error":   chrome.SeverityError,
+    "fatal":   chrome.SeverityFatal,
+}
+
+func runLogs(out *output, args []string) error {
+    flags := out.flags("logs", "<配置>", "显示实例的浏览器日志（标准输出和标准错误）。--json 时每行输出一个 JSON 对象。")
+    lines := flags.Int("n", 100, "显示最后的行数，0 表示全部")
+    follow := flags.Bool("follow", false, "持续输出新写入的日志，直到中断")
+    flags.BoolVar(follow, "f", false, "同 --follow")
+    level := flags.String("level", "all", "只显示不低于此级别的行：all、verbose、info、warning、error 或 fatal")
+    args, err := out.parse(flags, args, 1, 1)
+    if err != nil {
+        return err
+    }
+    minSeverity, ok := severityNames[strings.ToLower(*level)]
+    if !ok || *lines < 0 {
+        flags.Usage()
+        return errUsage
+    }
+    cfg, err := out.load().find(args[0])
+    if err != nil {
+        return err
+    }
+
+    path := chrome.LogPath(cfg.ID)
+    if _, err := os.Stat(path); os.IsNotExist(err) && !*follow {
+        if !out.json {
+            fmt.Fprintf(out.stderr, "%s 还没有日志 (%s)\n", cfg, path)
+        }
+        return nil
+    }
+    tail := chrome.NewLogTail(path, math.MaxInt64) // 日志文件会被轮转，大小有上限，可以整个读取
+    lastSeverity := chrome.SeverityUnknown
+    // write 输出不低于 minSeverity 的行，keep 大于 0 时只输出最后 keep 行
+    write := func(texts []string, keep int) {
+        var filtered []logLine
+        for _, text := range texts {
+            severity := chrome.ParseSeverity(text)
+            if severity == chrome.SeverityUnknown {
+                severity = lastSeverity
+            }
+            lastSeverity = severity
+            if severity >= minSeverity {
+                filtered = append(filtered, logLine{Line: text, Severity: severity.String()})
+            }
+        }
+        if keep > 0 && len(filtered) > keep {
+            filtered = filtered[len(filtered)-keep:]
+        }
+        for _, line := range filtered {
+            out.print(line, func(w io.Writer) {
+                fmt.Fprintln(w, line.Line)
+            })
+        }
+    }
+
+    texts, err := tail.Read()
+    if err != nil {
+        return err
+    }
+    write(texts, *lines)
+    if !*follow {
+        return nil
+    }
+
+    ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer cancel()
+    ticker := time.NewTicker(followInterval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return nil
+        case <-ticker.C:
+            texts, err := tail.Read()
+            if err != nil {
+                return err
+            }
+            write(texts, 0)
+        }
+    }
+}
@@ -0,0 +1,346 @@
+package cli
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "runtime"
+    "slices"
+    "strings"
+    "time"
+
+    "chromes/api"
+    "chromes/chrome"
+    "chromes/config"
+    "chromes/xdg"
+)
+
+// forwardTimeout 是连接正在运行的管理器的期限，包括等待刚启动的管理器开始监听；open 命令的转交也在此期限内完成。
+const forwardTimeout = 15 * time.Second
+
+// StartManager 在后台启动图形界面的管理器，由包含图形界面的程序设置。
+// open 命令中有网址需要由用户选择配置、而管理器没有运行时调用；为 nil 时（例如只有命令行的版本）改用后备配置。
+var StartManager func() error
+
+// openResult 是 open 命令的输出。
+type openResult struct {
+    Routes    []api.Route `json:"routes"`    // 每个网址在哪个配置中打开
+    Forwarded bool        `json:"forwarded"` // 是否转交给了正在运行的管理器
+}
+
+func runOpen(out *output, args []string) error {
+    var profile, source string
+    flags := out.flags("open", "<网址>...", `在浏览器中打开网址，实例没有运行时以这些网址启动它。
+指定 --profile 时在该配置中打开，否则按链接路由的规则选择配置。
+图形界面的管理器正在运行时把请求转交给它。`)
+    flags.StringVar(&profile, "profile", "", "打开网址的配置（名称或 ID），不指定时按链接路由选择")
+    flags.StringVar(&source, "source", "", "打开网址的来源程序，用于匹配来源规则；不指定时在 Linux 上根据父进程推断")
+    urls, err := out.parse(flags, args, 1, -1)
+    if err != nil {
+        return err
+    }
+    if err := chrome.ValidateURLs(urls); err != nil {
+        return fail(ExitUsage, "%v", err)
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), forwardTimeout)
+    defer cancel()
+    client, err := api.Connect(ctx)
+    if err != nil && !errors.Is(err, api.ErrNotRunning) {
+        return err
+    }
+    if profile != "" {
+        if client != nil {
+            return forwardOpen(ctx, out, client, profile, urls)
+        }
+        return openIn(out, profile, urls)
+    }
+
+    if source == "" {
+        source = detectSource()
+    }
+    if client != nil {
+        return forwardRoute(ctx, out, client, urls, source)
+    }
+    return route(ctx, out, urls, source)
+}
+
+// openIn 在指定的配置中打开网址。
+func openIn(out *output, profile string, urls []string) error {
+    instance, err := out.load().instance(profile)
+    if err != nil {
+        return err
+    }
+    cfg := instance.Config()
+    if instance.IsStopping() {
+        return fail(ExitConflict, "chrome instance %s is stopping", cfg)
+    }
+    if err := instance.Open(urls...); err != nil {
+        return err
+    }
+    printOpen(out, newOpenResult(urls, cfg.ID, false))
+    return nil
+}
+
+// route 在没有管理器运行时按链接路由打开网址。
+// 有网址需要由用户选择配置时启动管理器并转交给它，无法启动管理器时使用后备配置。
+func route(ctx context.Context, out *output, urls []string, source string) error {
+    env := out.load()
+    router := env.settings.RouterOrEmpty()
+    result := openResult{}
+    var order []string
+    groups := make(map[string][]int) // 配置 ID -> 在其中打开的网址在 result.Routes 中的序号
+    for _, rawURL := range urls {
+        cfg, rule := router.Route(env.configs, rawURL, source)
+        if router.NeedsChooser(rule) && StartManager != nil {
+            if err := StartManager(); err != nil {
+                return fmt.Errorf("failed to start the manager to choose a profile: %w", err)
+            }
+            client, err := api.WaitForManager(ctx)
+            if err != nil {
+                return err
+            }
+            return forwardRoute(ctx, out, client, urls, source)
+        }
+        if cfg == nil {
+            return fail(ExitNotFound, "no profile to open %s", rawURL)
+        }
+        if _, ok := groups[cfg.ID]; !ok {
+            order = append(order, cfg.ID)
+        }
+        groups[cfg.ID] = append(groups[cfg.ID], len(result.Routes))
+        result.Routes = append(result.Routes, api.Route{URL: rawURL, Profile: cfg.ID, Rule: rule})
+    }
+
+    // 与管理器相同，某个配置打开失败时继续打开其他配置的网址，失败记录在对应的路由结果中
+    for _, id := range order {
+        instance := chrome.NewInstance(config.FindConfig(env.configs, id), env.registry)
+        var urls []string
+        for _, index := range groups[id] {
+            urls = append(urls, result.Routes[index].URL)
+        }
+        var err error
+        if instance.IsStopping() {
+            err = fmt.Errorf("chrome instance %s is stopping", instance.Config())
+        } else {
+            err = instance.Open(urls...)
+        }
+        if err != nil {
+            for _, index := range groups[id] {
+                result.Routes[index].Error = err.Error()
+            }
+        }
+    }
+    printOpen(out, result)
+    return routeError(result.Routes)
+}
+
+// forwardOpen 把窗口显示到前台后，由正在运行的管理器在指定的配置中打开网址。
+func forwardOpen(ctx context.Context, out *output, client *api.Client, profile string, urls []string) error {
+    if err := client.ShowWindow(ctx); err != nil {
+        fmt.Fprintf(out.stderr, "无法显示管理器的窗口: %v\n", err)
+    }
+    p, err := client.Open(ctx, profile, urls...)
+    if err != nil {
+        return apiError(err)
+    }
+    printOpen(out, newOpenResult(urls, p.ID, true))
+    return nil
+}
+
+// forwardRoute 由正在运行的管理器按链接路由打开网址。
+func forwardRoute(ctx context.Context, out *output, client *api.Client, urls []string, source string) error {
+    resp, err := client.Route(ctx, api.RouteRequest{URLs: urls, Source: source})
+    if err != nil {
+        return apiError(err)
+    }
+    printOpen(out, openResult{Routes: resp.Routes, Forwarded: true})
+    return routeError(resp.Routes)
+}
+
+// routeError 返回路由结果中打开失败的网址对应的错误，全部成功时返回 nil。
+func routeError(routes []api.Route) error {
+    var failed []string
+    for _, route := range routes {
+        if route.Error != "" {
+            failed = append(failed, route.URL)
+        }
+    }
+    if len(failed) == 0 {
+        return nil
+    }
+    return fail(ExitError, "failed to open %d of %d urls: %s", len(failed), len(routes), strings.Join(failed, ", "))
+}
+
+// newOpenResult 返回所有网址都在同一个配置中打开的结果。
+func newOpenResult(urls []string, profile string, forwarded bool) openResult {
+    result := openResult{Forwarded: forwarded}
+    for _, rawURL := range urls {
+        result.Routes = append(result.Routes, api.Route{URL: rawURL, Profile: profile, Rule: -1})
+    }
+    return result
+}
+
+// printOpen 输出 open 命令的结果。
+func printOpen(out *output, result openResult) {
+    out.print(result, func(w io.Writer) {
+        for _, route := range result.Routes {
+            if route.Chooser {
+                fmt.Fprintf(w, "%s -> 在管理器中选择配置\n", route.URL)
+            } else if route.Error != "" {
+                fmt.Fprintf(w, "%s -> [%s] 打开失败: %s\n", route.URL, route.Profile, route.Error)
+            } else {
+                fmt.Fprintf(w, "%s -> [%s]\n", route.URL, route.Profile)
+            }
+        }
+        if result.Forwarded {
+            fmt.Fprintln(w, "已转交给正在运行的管理器")
+        }
+    })
+}
+
+// apiError 把控制接口返回的错误转换为对应退出码的错误。
+func apiError(err error) error {
+    var apiErr *api.Error
+    if !errors.As(err, &apiErr) {
+        return err
+    }
+    switch apiErr.Code {
+    case api.CodeNotFound:
+        return fail(ExitNotFound, "%w", err)
+    case api.CodeConflict:
+        return fail(ExitConflict, "%w", err)
+    }
+    return err
+}
+
+// sourceSkipped 是推断来源程序时跳过的中间程序：打开网址的工具、shell 和会话管理进程。
+var sourceSkipped = []string{
+    "xdg-open", "gio", "gio-launch-desktop", "gnome-open", "kde-open", "kde-open5", "exo-open", "xdg-desktop-portal",
+    "sh", "bash", "dash", "zsh", "fish", "env", "systemd",
+}
+
+// detectSource 沿着父进程向上查找第一个不在 sourceSkipped 中的程序，作为打开网址的来源程序。
+// 只在 Linux 上通过 /proc 推断，找不到时（例如由桌面门户或 systemd 转交）返回空。
+func detectSource() string {
+    if runtime.GOOS != "linux" {
+        return ""
+    }
+    procfs := &chrome.ProcFS{Root: "/proc"}
+    pid := os.Getppid()
+    for depth := 0; depth < 8 && pid > 1; depth++ {
+        process, err := procfs.Process(pid)
+        if err != nil || len(process.Args) == 0 {
+            return ""
+        }
+        name := filepath.Base(process.Args[0])
+        if !slices.Contains(sourceSkipped, name) {
+            return name
+        }
+        pid = process.PPID
+    }
+    return ""
+}
+
+// defaultBrowserResult 是 default-browser 命令的输出。
+type defaultBrowserResult struct {
+    DefaultBrowser bool   `json:"default_browser"` // 本程序当前是否为默认浏览器
+    DesktopFile    string `json:"desktop_file"`
+}
+
+func runDefaultBrowser(out *output, args []string) error {
+    var unset bool
+    flags := out.flags("default-browser", "", `把本程序注册为当前用户的默认浏览器（仅 Linux）：写入 .desktop 文件并修改 mimeapps.list，
+之后打开的链接通过 open 命令按链接路由分配给配置。`)
+    flags.BoolVar(&unset, "unset", false, "取消注册")
+    if _, err := out.parse(flags, args, 0, 0); err != nil {
+        return err
+    }
+    if unset {
+        if err := xdg.UnregisterBrowser(); err != nil {
+            return err
+        }
+    } else {
+        executable, err := os.Executable()
+        if err != nil {
+            return err
+        }
+        if err := xdg.RegisterBrowser(executable); err != nil {
+            return err
+        }
+    }
+    result := defaultBrowserResult{DefaultBrowser: xdg.IsDefaultBrowser(), DesktopFile: filepath.Join(xdg.ApplicationsDir(), xdg.BrowserDesktopID)}
+    out.print(result, func(w io.Writer) {
+        if result.DefaultBrowser {
+            fmt.Fprintf(w, "已设为默认浏览器: %s\n", result.DesktopFile)
+        } else {
+            fmt.Fprintln(w, "已取消默认浏览器")
+        }
+    })
+    return nil
+}
+
+// launchResult 是 launch 命令的输出。
+type launchResult struct {
+    ID        string `json:"id"`
+    Name      string `json:"name"`
+    Forwarded bool   `json:"forwarded"` // 是否转交给了正在运行的管理器
+}
+
+func runLaunch(out *output, args []string) error {
+    flags := out.flags("launch", "<配置>", `启动配置的浏览器，已在运行时打开一个新窗口。
+供每个配置的桌面启动器使用，图形界面的管理器正在运行时转交给它。`)
+    args, err := out.parse(flags, args, 1, 1)
+    if err != nil {
+        return err
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), forwardTimeout)
+    defer cancel()
+    client, err := api.Connect(ctx)
+    if err != nil && !errors.Is(err, api.ErrNotRunning) {
+        return err
+    }
+    result := launchResult{Forwarded: client != nil}
+    if client != nil {
+        p, err := client.Open(ctx, args[0])
+        if err != nil {
+            return apiError(err)
+        }
+        result.ID, result.Name = p.ID, p.Name
+    } else {
+        instance, err := out.load().instance(args[0])
+        if err != nil {
+            return err
+        }
+        cfg := instance.Config()
+        if instance.IsStopping() {
+            return fail(ExitConflict, "chrome instance %s is stopping", cfg)
+        }
+        if err := instance.Open(); err != nil {
+            return err
+        }
+        result.ID, result.Name = cfg.ID, cfg.Name
+    }
+    out.print(result, func(w io.Writer) {
+        fmt.Fprintf(w, "已启动 %s [%s]\n", result.Name, result.ID)
+    })
+    return nil
+}
+
+// syncLaunchers 在配置列表变化后更新每个配置的桌面启动器，失败时只输出警告。
+func (o *output) syncLaunchers(configs []*config.ChromeConfig) {
+    if !xdg.Supported() {
+        return
+    }
+    executable, err := os.Executable()
+    if err == nil {
+        err = xdg.SyncLaunchers(configs, executable)
+    }
+    if err != nil && !o.json {
+        fmt.Fprintf(o.stderr, "警告: 更新桌面启动器失败: %v\n", err)
+    }
+}
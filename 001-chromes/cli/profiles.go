@@ -0,0 +1,395 @@
+package cli
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "io"
+    "strings"
+    "text/tabwriter"
+    "time"
+
+    "chromes/api"
+    "chromes/chrome"
+    "chromes/config"
+)
+
+// profileInfo 是 list 命令输出的一个配置，字段名是 JSON 输出的一部分，只增不改。
+type profileInfo struct {
+    ID          string `json:"id"`
+    Name        string `json:"name"`
+    Default     bool   `json:"default"`
+    UserDataDir string `json:"user_data_dir"`
+    Browser     string `json:"browser"` // 实际使用的浏览器名称，不可用时为空
+    State       string `json:"state"`
+    PID         int    `json:"pid,omitempty"`
+}
+
+// profileStatus 是 status 命令输出的一个配置，在 profileInfo 的基础上包含启动参数、日志和远程调试等信息。
+type profileStatus struct {
+    profileInfo
+    BrowserPath string   `json:"browser_path,omitempty"`
+    Flags       []string `json:"flags"` // 与全局默认参数合并后的额外启动参数
+    LogLevel    int      `json:"log_level"`
+    LogPath     string   `json:"log_path"`
+    Restart     string   `json:"restart"`
+    DevTools    bool     `json:"devtools"`
+    DebugPort   int      `json:"debug_port,omitempty"`
+    DevToolsURL string   `json:"devtools_url,omitempty"` // 浏览器级 WebSocket 端点，运行中且开启远程调试时才有
+    Workspaces  []string `json:"workspaces,omitempty"`
+    Error       string   `json:"error,omitempty"` // 浏览器不可用等问题
+}
+
+// newProfileInfo 返回实例的配置和当前状态。
+func newProfileInfo(env *environment, instance *chrome.Instance) profileInfo {
+    cfg := instance.Config()
+    info := profileInfo{
+        ID:          cfg.ID,
+        Name:        cfg.Name,
+        Default:     cfg.IsDefault,
+        UserDataDir: cfg.UserDataDir,
+        State:       instance.State().String(),
+        PID:         instance.PID(),
+    }
+    if browser, err := env.registry.Resolve(cfg); err == nil {
+        info.Browser = browser.Name
+    }
+    return info
+}
+
+// newRemoteProfileInfo 返回正在运行的管理器报告的配置和状态。
+func newRemoteProfileInfo(env *environment, profile *api.Profile) profileInfo {
+    info := profileInfo{
+        ID:          profile.ID,
+        Name:        profile.Name,
+        Default:     profile.Default,
+        UserDataDir: profile.UserDataDir,
+        State:       profile.State,
+        PID:         profile.PID,
+    }
+    if browser, err := env.registry.Resolve(&profile.ChromeConfig); err == nil {
+        info.Browser = browser.Name
+    }
+    return info
+}
+
+func runList(out *output, args []string) error {
+    flags := out.flags("list", "", "列出所有配置及其运行状态。")
+    if _, err := out.parse(flags, args, 0, 0); err != nil {
+        return err
+    }
+    env := out.load()
+    infos := make([]profileInfo, 0, len(env.configs))
+    for _, cfg := range env.configs {
+        infos = append(infos, newProfileInfo(env, chrome.NewInstance(cfg, env.registry)))
+    }
+    out.print(infos, func(w io.Writer) {
+        tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+        fmt.Fprintln(tw, "ID\t名称\t状态\t浏览器\t数据目录")
+        for _, info := range infos {
+            state := info.State
+            if info.PID > 0 {
+                state = fmt.Sprintf("%s (%d)", state, info.PID)
+            }
+            dir := info.UserDataDir
+            if info.Default {
+                dir = "(默认路径)"
+            }
+            fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", info.ID, info.Name, state, info.Browser, dir)
+        }
+        tw.Flush()
+    })
+    return nil
+}
+
+func runStatus(out *output, args []string) error {
+    flags := out.flags("status", "[配置...]", "显示配置的详细状态，不指定配置时显示全部。")
+    names, err := out.parse(flags, args, 0, -1)
+    if err != nil {
+        return err
+    }
+    env := out.load()
+    configs := env.configs
+    if len(names) > 0 {
+        configs = nil
+        for _, name := range names {
+            cfg, err := env.find(name)
+            if err != nil {
+                return err
+            }
+            configs = append(configs, cfg)
+        }
+    }
+
+    statuses := make([]profileStatus, 0, len(configs))
+    for _, cfg := range configs {
+        instance := chrome.NewInstance(cfg, env.registry)
+        status := profileStatus{
+            profileInfo: newProfileInfo(env, instance),
+            Flags:       instance.Flags(),
+            LogLevel:    cfg.LogLevel,
+            LogPath:     chrome.LogPath(cfg.ID),
+            Restart:     cfg.RestartPolicy(),
+            DevTools:    cfg.DevTools,
+            DebugPort:   cfg.DebugPort,
+        }
+        if browser, err := env.registry.Resolve(cfg); err == nil {
+            status.BrowserPath = browser.Path
+        } else {
+            status.Error = err.Error()
+        }
+        if cfg.DevTools && instance.IsRunning() {
+            ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+            if endpoint, err := instance.DevTools(ctx); err == nil {
+                status.DevToolsURL = endpoint.WebSocketDebuggerURL
+            }
+            cancel()
+        }
+        for _, ws := range cfg.Workspaces {
+            status.Workspaces = append(status.Workspaces, ws.Name)
+        }
+        statuses = append(statuses, status)
+    }
+
+    out.print(statuses, func(w io.Writer) {
+        for i, status := range statuses {
+            if i > 0 {
+                fmt.Fprintln(w)
+            }
+            fmt.Fprintf(w, "%s [%s]\n", status.Name, status.ID)
+            tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+            state := status.State
+            if status.PID > 0 {
+                state = fmt.Sprintf("%s (pid %d)", state, status.PID)
+            }
+            fmt.Fprintf(tw, "  状态:\t%s\n", state)
+            if status.Default {
+                fmt.Fprintf(tw, "  数据目录:\t(默认路径)\n")
+            } else {
+                fmt.Fprintf(tw, "  数据目录:\t%s\n", status.UserDataDir)
+            }
+            fmt.Fprintf(tw, "  浏览器:\t%s %s\n", status.Browser, status.BrowserPath)
+            fmt.Fprintf(tw, "  启动参数:\t%s\n", strings.Join(status.Flags, " "))
+            fmt.Fprintf(tw, "  日志:\t%s (级别 %d)\n", status.LogPath, status.LogLevel)
+            fmt.Fprintf(tw, "  自动重启:\t%s\n", status.Restart)
+            if status.DevTools {
+                port := "自动分配"
+                if status.DebugPort > 0 {
+                    port = fmt.Sprint(status.DebugPort)
+                }
+                fmt.Fprintf(tw, "  远程调试:\t端口 %s %s\n", port, status.DevToolsURL)
+            }
+            if len(status.Workspaces) > 0 {
+                fmt.Fprintf(tw, "  工作区:\t%s\n", strings.Join(status.Workspaces, ", "))
+            }
+            if status.Error != "" {
+                fmt.Fprintf(tw, "  错误:\t%s\n", status.Error)
+            }
+            tw.Flush()
+        }
+    })
+    return nil
+}
+
+// profileFlags 是 add 和 edit 命令共有的配置选项。
+type profileFlags struct {
+    name      string
+    dir       string
+    browser   string
+    flags     stringList
+    logLevel  int
+    restart   string
+    devtools  bool
+    debugPort int
+}
+
+// register 把配置选项注册到 flags 中，默认值取自 cfg。
+func (p *profileFlags) register(flags *flag.FlagSet, cfg *config.ChromeConfig) {
+    flags.StringVar(&p.name, "name", cfg.Name, "配置名称")
+    flags.StringVar(&p.dir, "dir", cfg.UserDataDir, "用户数据目录")
+    flags.StringVar(&p.browser, "browser", cfg.Browser, "浏览器安装的 ID，为空时使用全局默认浏览器")
+    flags.Var(&p.flags, "flag", "额外的启动参数，可以重复指定，例如 --flag=--incognito")
+    flags.IntVar(&p.logLevel, "log-level", cfg.LogLevel, "浏览器日志级别：0 不开启，1 开启，N>1 时使用 --v=N-1")
+    flags.StringVar(&p.restart, "restart", cfg.RestartPolicy(), "自动重启策略："+config.RestartNever+"、"+config.RestartOnFailure+" 或 "+config.RestartAlways)
+    flags.BoolVar(&p.devtools, "devtools", cfg.DevTools, "以 --remote-debugging-port 启动")
+    flags.IntVar(&p.debugPort, "debug-port", cfg.DebugPort, "固定的远程调试端口，0 表示每次自动分配")
+}
+
+// apply 把命令行中指定了的选项写入 cfg，没有指定的保持不变。
+func (p *profileFlags) apply(flags *flag.FlagSet, cfg *config.ChromeConfig) {
+    flags.Visit(func(f *flag.Flag) {
+        switch f.Name {
+        case "name":
+            cfg.Name = p.name
+        case "dir":
+            cfg.UserDataDir = p.dir
+        case "browser":
+            cfg.Browser = p.browser
+        case "flag":
+            cfg.Flags = []string(p.flags)
+        case "log-level":
+            cfg.LogLevel = p.logLevel
+        case "restart":
+            cfg.Restart = p.restart
+        case "devtools":
+            cfg.DevTools = p.devtools
+        case "debug-port":
+            cfg.DebugPort = p.debugPort
+        }
+    })
+}
+
+func runAdd(out *output, args []string) error {
+    var p profileFlags
+    flags := out.flags("add", "--name <名称> --dir <数据目录> [选项]", "新增配置。")
+    p.register(flags, &config.ChromeConfig{})
+    if _, err := out.parse(flags, args, 0, 0); err != nil {
+        return err
+    }
+    if p.name == "" || p.dir == "" {
+        flags.Usage()
+        return errUsage
+    }
+
+    client, err := connect()
+    if err != nil {
+        return err
+    }
+    env := out.load()
+    cfg := &config.ChromeConfig{}
+    p.apply(flags, cfg)
+    if client != nil {
+        profile, err := client.AddProfile(context.Background(), cfg)
+        if err != nil {
+            return apiError(err)
+        }
+        out.print(newRemoteProfileInfo(env, profile), func(w io.Writer) {
+            fmt.Fprintf(w, "已添加配置 %s\n", &profile.ChromeConfig)
+        })
+        return nil
+    }
+    configs, err := config.AddConfig(cfg, env.configs)
+    if err != nil {
+        return err
+    }
+    out.syncLaunchers(configs)
+    info := newProfileInfo(env, chrome.NewInstance(cfg, env.registry))
+    out.print(info, func(w io.Writer) {
+        fmt.Fprintf(w, "已添加配置 %s\n", cfg)
+    })
+    return nil
+}
+
+func runRemove(out *output, args []string) error {
+    flags := out.flags("remove", "<配置>", "删除配置。数据目录保留在磁盘上；实例正在运行时拒绝删除。")
+    args, err := out.parse(flags, args, 1, 1)
+    if err != nil {
+        return err
+    }
+    client, err := connect()
+    if err != nil {
+        return err
+    }
+    var cfg *config.ChromeConfig
+    if client != nil {
+        if cfg, err = forwardRemove(client, args[0]); err != nil {
+            return err
+        }
+    } else {
+        env := out.load()
+        instance, err := env.instance(args[0])
+        if err != nil {
+            return err
+        }
+        cfg = instance.Config()
+        if instance.IsRunning() {
+            return fail(ExitConflict, "chrome instance %s is running, stop it before removing the profile", cfg)
+        }
+        configs, err := config.RemoveConfig(cfg.ID, env.configs)
+        if err != nil {
+            return err
+        }
+        out.syncLaunchers(configs)
+    }
+    out.print(map[string]string{"id": cfg.ID, "name": cfg.Name}, func(w io.Writer) {
+        fmt.Fprintf(w, "已删除配置 %s\n", cfg)
+    })
+    return nil
+}
+
+// forwardRemove 由正在运行的管理器删除配置，返回被删除的配置。
+func forwardRemove(client *api.Client, ref string) (*config.ChromeConfig, error) {
+    ctx := context.Background()
+    profile, err := client.Profile(ctx, ref)
+    if err != nil {
+        return nil, apiError(err)
+    }
+    if err := client.RemoveProfile(ctx, profile.ID); err != nil {
+        return nil, apiError(err)
+    }
+    return &profile.ChromeConfig, nil
+}
+
+func runEdit(out *output, args []string) error {
+    var p profileFlags
+    var move, clearFlags bool
+    flags := out.flags("edit", "<配置> [选项]", "修改配置，只修改命令行中指定的选项；指定 --flag 时替换全部额外启动参数。")
+    p.register(flags, &config.ChromeConfig{})
+    flags.BoolVar(&move, "move", false, "修改数据目录时把现有的数据目录移动到新位置，实例需要已停止")
+    flags.BoolVar(&clearFlags, "clear-flags", false, "清除所有额外启动参数")
+    args, err := out.parse(flags, args, 1, 1)
+    if err != nil {
+        return err
+    }
+
+    client, err := connect()
+    if err != nil {
+        return err
+    }
+    env := out.load()
+    // edit 只修改指定的选项，其余选项取自当前的配置
+    update := func(cfg *config.ChromeConfig) (config.ChromeConfig, bool) {
+        updated := *cfg
+        p.apply(flags, &updated)
+        if clearFlags {
+            updated.Flags = nil
+        }
+        return updated, move && updated.UserDataDir != cfg.UserDataDir
+    }
+
+    if client != nil {
+        ctx := context.Background()
+        profile, err := client.Profile(ctx, args[0])
+        if err != nil {
+            return apiError(err)
+        }
+        updated, moveData := update(&profile.ChromeConfig)
+        if profile, err = client.UpdateProfile(ctx, profile.ID, &updated, moveData); err != nil {
+            return apiError(err)
+        }
+        out.print(newRemoteProfileInfo(env, profile), func(w io.Writer) {
+            fmt.Fprintf(w, "已更新配置 %s\n", &profile.ChromeConfig)
+        })
+        return nil
+    }
+
+    instance, err := env.instance(args[0])
+    if err != nil {
+        return err
+    }
+    cfg := instance.Config()
+    updated, moveData := update(cfg)
+    if moveData && instance.IsRunning() {
+        return fail(ExitConflict, "chrome instance %s is running, stop it before moving its data directory", cfg)
+    }
+    configs, err := config.UpdateConfig(cfg.ID, &updated, moveData, env.configs)
+    if err != nil {
+        return err
+    }
+    out.syncLaunchers(configs)
+    info := newProfileInfo(env, chrome.NewInstance(&updated, env.registry))
+    out.print(info, func(w io.Writer) {
+        fmt.Fprintf(w, "已更新配置 %s\n", &updated)
+    })
+    return nil
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "chromes/chrome"
+)
+
+// renderResult 是 render 命令的输出。
+type renderResult struct {
+    ID     string `json:"id"`
+    URL    string `json:"url"`
+    Output string `json:"output"`
+    Format string `json:"format"`
+    Size   int    `json:"size"` // 输出文件的字节数
+}
+
+func runRender(out *output, args []string) error {
+    flags := out.flags("render", "<配置> <网址> <输出文件>", `以无界面模式启动配置对应的浏览器，借助其中的 Cookie 和登录状态打开网址，
+渲染为 PNG 截图或 PDF 后写入输出文件，完成后关闭浏览器。
+配置的数据目录已被其他浏览器打开时拒绝执行。`)
+    var opts chrome.RenderOptions
+    flags.StringVar(&opts.Format, "format", "", "输出格式 png 或 pdf（默认按输出文件的扩展名，其他扩展名为 png）")
+    flags.IntVar(&opts.Width, "width", 1280, "视口宽度（CSS 像素）")
+    flags.IntVar(&opts.Height, "height", 800, "视口高度（CSS 像素）")
+    flags.StringVar(&opts.WaitUntil, "wait", chrome.WaitLoad, "渲染前等待的条件: load、domcontentloaded 或 none")
+    flags.DurationVar(&opts.Timeout, "timeout", 30*time.Second, "从打开网址到完成渲染的期限")
+    args, err := out.parse(flags, args, 3, 3)
+    if err != nil {
+        return err
+    }
+    url, output := args[1], args[2]
+    if opts.Format == "" {
+        opts.Format = chrome.RenderPNG
+        if strings.EqualFold(filepath.Ext(output), ".pdf") {
+            opts.Format = chrome.RenderPDF
+        }
+    }
+
+    instance, err := out.load().instance(args[0])
+    if err != nil {
+        return err
+    }
+    cfg := instance.Config()
+    if err := instance.StartHeadless(); err != nil {
+        var inUseErr *chrome.InUseError
+        if errors.As(err, &inUseErr) || instance.IsRunning() {
+            return fail(ExitConflict, "%w", err)
+        }
+        return err
+    }
+    data, err := instance.Render(context.Background(), url, opts)
+    if instance.IsRunning() {
+        if _, stopErr := instance.Stop(); stopErr != nil {
+            fmt.Fprintf(out.stderr, "警告: 关闭 %s 失败: %v\n", cfg, stopErr)
+        }
+    }
+    if err != nil {
+        return err
+    }
+    if err := os.WriteFile(output, data, 0644); err != nil {
+        return err
+    }
+    result := renderResult{ID: cfg.ID, URL: url, Output: output, Format: opts.Format, Size: len(data)}
+    out.print(result, func(w io.Writer) {
+        fmt.Fprintf(w, "已将 %s 渲染到 %s (%d 字节)\n", url, output, len(data))
+    })
+    return nil
+}
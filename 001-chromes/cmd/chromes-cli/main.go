@@ -0,0 +1,16 @@
+// chromes-cli 是不包含图形界面的命令行版本，不依赖 Fyne 和 CGO，可以在没有显示器的机器上构建和运行：
+//
+//	CGO_ENABLED=0 go build -o chromes ./cmd/chromes-cli
+//
+// 命令与图形界面版本的 "chromes <命令>" 相同，见 cli 包。
+package main
+
+import (
+    "os"
+
+    "chromes/cli"
+)
+
+func main() {
+    os.Exit(cli.Run(os.Args[1:]))
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+    "encoding/json"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+)
+
+// MaxBackups 定义了保留的配置文件备份数量，超出后删除最旧的备份。
+const MaxBackups = 10
+
+// backupTimeLayout 是备份和隔离文件名中使用的时间格式，按字典序排序即为时间顺序。
+const backupTimeLayout = "20060102-150405.000"
+
+// backupDir 定义了配置文件备份所在的目录。
+var backupDir = filepath.Join(Dir(), "backups")
+
+// LoadError 表示配置文件读取或解析失败。
+// 解析失败的文件会被移动到 QuarantinePath，原文件位置随后可以安全地重新写入。
+type LoadError struct {
+    Path           string // 加载失败的文件路径
+    QuarantinePath string // 损坏文件被隔离后的路径，未隔离时为空
+    Err            error  // 底层错误
+}
+
+func (e *LoadError) Error() string {
+    if e.QuarantinePath != "" {
+        return fmt.Sprintf("failed to load %s: %v (the file was moved to %s)", e.Path, e.Err, e.QuarantinePath)
+    }
+    return fmt.Sprintf("failed to load %s: %v", e.Path, e.Err)
+}
+
+func (e *LoadError) Unwrap() error {
+    return e.Err
+}
+
+// Backup 描述一个配置文件备份。
+type Backup struct {
+    Path    string    // 备份文件路径
+    Time    time.Time // 备份时间
+    Entries int       // 备份中的配置项数量，无法解析时为 -1
+}
+
+// WriteFileAtomic 先写入同目录下的临时文件再重命名，保证目标文件要么是旧内容，要么是完整的新内容。
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+    if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+        return err
+    }
+    tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+    if err != nil {
+        return err
+    }
+    tmpName := tmp.Name()
+    defer os.Remove(tmpName) // 重命名成功后此调用无效果
+
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Sync(); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    if err := os.Chmod(tmpName, perm); err != nil {
+        return err
+    }
+    return os.Rename(tmpName, path)
+}
+
+// quarantineFile 将无法解析的文件重命名为 "<name>.corrupt-<时间>"，返回新路径。
+func quarantineFile(path string) (string, error) {
+    target := path + ".corrupt-" + time.Now().Format(backupTimeLayout)
+    if err := os.Rename(path, target); err != nil {
+        return "", err
+    }
+    log.Printf("[load] quarantined. path=%v, target=%v", path, target)
+    return target, nil
+}
+
+// writeConfigFile 原子地写入配置文件，并将写入的内容同时保存为一个备份。
+// 这样最新的备份总是最后一次成功保存的版本，即使配置文件随后被损坏也可以恢复。
+func writeConfigFile(data []byte) error {
+    if err := WriteFileAtomic(configFile, data, 0640); err != nil {
+        return err
+    }
+    if err := backupConfigData(data); err != nil {
+        // 备份失败不影响本次保存的结果
+        log.Printf("[backup] failed. err=%v", err)
+    }
+    return nil
+}
+
+// backupConfigData 将配置内容写入备份目录，并清理超出 MaxBackups 的旧备份。
+func backupConfigData(data []byte) error {
+    name := "configs-" + time.Now().Format(backupTimeLayout) + ".json"
+    if err := WriteFileAtomic(filepath.Join(backupDir, name), data, 0640); err != nil {
+        return err
+    }
+
+    backups, err := ListBackups()
+    if err != nil {
+        return err
+    }
+    for i := MaxBackups; i < len(backups); i++ {
+        if err := os.Remove(backups[i].Path); err != nil {
+            log.Printf("[backup] remove failed. path=%v, err=%v", backups[i].Path, err)
+        }
+    }
+    return nil
+}
+
+// ListBackups 返回所有配置文件备份，最新的排在前面。
+func ListBackups() ([]Backup, error) {
+    entries, err := os.ReadDir(backupDir)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil, nil
+        }
+        return nil, err
+    }
+
+    var backups []Backup
+    for _, entry := range entries {
+        name := entry.Name()
+        if entry.IsDir() || !strings.HasPrefix(name, "configs-") || !strings.HasSuffix(name, ".json") {
+            continue
+        }
+        stamp := strings.TrimSuffix(strings.TrimPrefix(name, "configs-"), ".json")
+        t, err := time.ParseInLocation(backupTimeLayout, stamp, time.Local)
+        if err != nil {
+            continue
+        }
+        path := filepath.Join(backupDir, name)
+        backups = append(backups, Backup{Path: path, Time: t, Entries: countBackupEntries(path)})
+    }
+    sort.Slice(backups, func(i, j int) bool {
+        return backups[i].Time.After(backups[j].Time)
+    })
+    return backups, nil
+}
+
+// countBackupEntries 返回备份文件中的配置项数量，无法解析时返回 -1。
+func countBackupEntries(path string) int {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return -1
+    }
+    doc, _, _ := decodeDocument(data, nil)
+    if doc == nil {
+        return -1
+    }
+    return len(doc.Profiles)
+}
+
+// RestoreBackup 用指定的备份替换当前配置文件，并返回重新加载后的配置列表。
+// 备份列表保持不变，恢复后仍可以切换到其他备份。
+// 版本 2 之前的备份中的配置项没有 ID，与当前配置文件中用户数据目录相同的配置项沿用当前的 ID，
+// 以免运行时记录、桌面启动器和链接路由规则因为迁移时生成的新 ID 失去关联。
+func RestoreBackup(path string) ([]*ChromeConfig, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read backup %s: %w", path, err)
+    }
+    doc, version, err := decodeDocument(data, nil)
+    if err != nil {
+        return nil, fmt.Errorf("backup %s is not a valid config file: %w", path, err)
+    }
+    if version < 2 {
+        keepCurrentIDs(doc)
+    }
+    if data, err = json.MarshalIndent(doc, "", "  "); err != nil {
+        return nil, err
+    }
+
+    if err := WriteFileAtomic(configFile, data, 0640); err != nil {
+        return nil, fmt.Errorf("failed to restore backup %s: %w", path, err)
+    }
+    log.Printf("[restore] restored. path=%v, backup=%v", configFile, path)
+    return LoadConfigs()
+}
+
+// keepCurrentIDs 让 doc 中的配置项沿用当前配置文件中用户数据目录相同的配置项的 ID。
+// 当前配置文件无法读取时保留迁移生成的 ID。
+func keepCurrentIDs(doc *Document) {
+    data, err := os.ReadFile(configFile)
+    if err != nil {
+        log.Printf("[restore] current config unavailable, keeping generated ids. path=%v, err=%v", configFile, err)
+        return
+    }
+    current, _, err := decodeDocument(data, nil)
+    if err != nil {
+        log.Printf("[restore] current config unreadable, keeping generated ids. path=%v, err=%v", configFile, err)
+        return
+    }
+
+    ids := make(map[string]string) // 用户数据目录的绝对路径 -> 当前的配置 ID
+    for _, profile := range current.Profiles {
+        if dir, err := filepath.Abs(profile.UserDataDir); err == nil && profile.UserDataDir != "" && profile.ID != "" {
+            ids[dir] = profile.ID
+        }
+    }
+    for _, profile := range doc.Profiles {
+        dir, err := filepath.Abs(profile.UserDataDir)
+        if err != nil || profile.UserDataDir == "" {
+            continue
+        }
+        if id, ok := ids[dir]; ok {
+            log.Printf("[restore] kept id. name=%v, dir=%v, id=%v", profile.Name, dir, id)
+            profile.ID = id
+            delete(ids, dir) // 同一个目录出现多次时只有第一个沿用
+        }
+    }
+    ensureProfileIDs(doc)
+}
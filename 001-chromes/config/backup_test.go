@@ -0,0 +1,112 @@
+package config
+
+import (
+    "path/filepath"
+    "testing"
+)
+
+// profileIDsByDir 返回配置列表中用户数据目录到配置 ID 的映射，不包含默认实例。
+func profileIDsByDir(configs []*ChromeConfig) map[string]string {
+    ids := make(map[string]string)
+    for _, cfg := range configs {
+        if cfg.ID != DefaultChromeConfigID {
+            ids[cfg.UserDataDir] = cfg.ID
+        }
+    }
+    return ids
+}
+
+func TestRestoreLegacyBackupKeepsCurrentIDs(t *testing.T) {
+    dir := useTempConfigDir(t)
+    writeTestFile(t, configFile, `{"version":2,"settings":{},"profiles":[
+        {"id":"work-id","name":"work","user_data_dir":"/p/work"},
+        {"id":"home-id","name":"home","user_data_dir":"/p/home"}
+    ]}`)
+
+    for _, tt := range []struct {
+        name   string
+        backup string
+    }{
+        {"version 0", `[{"name":"work","user_data_dir":"/p/work"},{"name":"new","user_data_dir":"/p/new"},{"name":"work copy","user_data_dir":"/p/work"}]`},
+        {"version 1", `{"version":1,"settings":{},"profiles":[{"name":"work","user_data_dir":"/p/work"},{"name":"new","user_data_dir":"/p/new"},{"name":"work copy","user_data_dir":"/p/work"}]}`},
+    } {
+        t.Run(tt.name, func(t *testing.T) {
+            path := filepath.Join(dir, "backup.json")
+            writeTestFile(t, path, tt.backup)
+            configs, err := RestoreBackup(path)
+            if err != nil {
+                t.Fatal(err)
+            }
+            var work, workCopy, added *ChromeConfig
+            for _, cfg := range configs {
+                switch cfg.Name {
+                case "work":
+                    work = cfg
+                case "work copy":
+                    workCopy = cfg
+                case "new":
+                    added = cfg
+                }
+            }
+            if work == nil || workCopy == nil || added == nil {
+                t.Fatalf("restored configs missing: %+v", configs)
+            }
+            if work.ID != "work-id" {
+                t.Errorf("work got id %q, want the current id", work.ID)
+            }
+            if workCopy.ID == "" || workCopy.ID == "work-id" {
+                t.Errorf("second profile with the same directory got id %q", workCopy.ID)
+            }
+            if added.ID == "" || added.ID == "home-id" {
+                t.Errorf("new profile got id %q", added.ID)
+            }
+
+            // 恢复后的文件写入了沿用的 ID，再次加载保持不变
+            reloaded, err := LoadConfigs()
+            if err != nil {
+                t.Fatal(err)
+            }
+            if cfg := FindConfig(reloaded, "work-id"); cfg == nil || cfg.Name != "work" {
+                t.Errorf("kept id lost after reloading: %+v", cfg)
+            }
+            if version, _ := readTestDocument(t); version != CurrentSchemaVersion {
+                t.Errorf("restored file has version %d", version)
+            }
+
+            // 还原到包含当前 ID 的文件，供下一个子测试使用
+            writeTestFile(t, configFile, `{"version":2,"settings":{},"profiles":[
+                {"id":"work-id","name":"work","user_data_dir":"/p/work"},
+                {"id":"home-id","name":"home","user_data_dir":"/p/home"}
+            ]}`)
+        })
+    }
+}
+
+func TestRestoreCurrentBackupKeepsBackupIDs(t *testing.T) {
+    dir := useTempConfigDir(t)
+    writeTestFile(t, configFile, `{"version":2,"settings":{},"profiles":[{"id":"current","name":"work","user_data_dir":"/p/work"}]}`)
+    path := filepath.Join(dir, "backup.json")
+    writeTestFile(t, path, `{"version":2,"settings":{},"profiles":[{"id":"from-backup","name":"work","user_data_dir":"/p/work"}]}`)
+
+    configs, err := RestoreBackup(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if ids := profileIDsByDir(configs); ids["/p/work"] != "from-backup" {
+        t.Errorf("got ids %v, want the backup's own id", ids)
+    }
+}
+
+func TestRestoreLegacyBackupWithoutCurrentFile(t *testing.T) {
+    dir := useTempConfigDir(t)
+    path := filepath.Join(dir, "backup.json")
+    writeTestFile(t, path, `[{"name":"work","user_data_dir":"/p/work"}]`)
+
+    configs, err := RestoreBackup(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if ids := profileIDsByDir(configs); ids["/p/work"] == "" {
+        t.Errorf("no id generated: %v", ids)
+    }
+}
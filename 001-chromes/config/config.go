@@ -1,7 +1,8 @@
 package config
 
 import (
-    "encoding/json"
+    "crypto/rand"
+    "encoding/hex"
     "fmt"
     "log"
     "os"
@@ -13,18 +14,94 @@ import (
 // ChromeConfig 存储每个 Chrome 实例的基本配置信息。
 // 这些信息用于启动和识别特定的 Chrome 浏览器会话。
 // 运行时状态（如进程命令、运行状态标志和互斥锁）由 `chrome.ChromeInstance` 管理。
+// 配置由不可变的 ID 标识，名称只用于显示，可以随时修改。
 type ChromeConfig struct {
-    Name        string `json:"name"`          // 配置的名称，用于用户界面显示和识别
-    UserDataDir string `json:"user_data_dir"` // Chrome 用户数据目录的路径，用于隔离不同的浏览器实例
-    IsDefault   bool   `json:"-"`             // 标记是否为默认实例，不序列化到json
+    ID          string       `json:"id"`                   // 配置的唯一标识，创建时生成，之后不再改变
+    Name        string       `json:"name"`                 // 配置的名称，用于用户界面显示和识别
+    UserDataDir string       `json:"user_data_dir"`        // Chrome 用户数据目录的路径，用于隔离不同的浏览器实例
+    Browser     string       `json:"browser,omitempty"`    // 使用的浏览器安装 ID，为空时使用全局默认浏览器
+    Flags       []string     `json:"flags,omitempty"`      // 额外的启动参数，与全局默认参数合并后使用
+    LogLevel    int          `json:"log_level,omitempty"`  // 浏览器自身的日志级别：0 不开启，1 开启 --enable-logging，N>1 时额外使用 --v=N-1
+    Restart     string       `json:"restart,omitempty"`    // 浏览器退出后的自动重启策略，为空时等同于 RestartNever
+    DevTools    bool         `json:"devtools,omitempty"`   // 是否以 --remote-debugging-port 启动，供 Puppeteer/Playwright 等工具连接
+    DebugPort   int          `json:"debug_port,omitempty"` // 固定的远程调试端口，为 0 时每次启动自动分配空闲端口
+    Workspaces  []*Workspace `json:"workspaces,omitempty"` // 保存的工作区（窗口和标签页），通过远程调试端点保存和重新打开
+    IsDefault   bool         `json:"-"`                    // 标记是否为默认实例，不序列化到json
+}
+
+// 配置项的自动重启策略（ChromeConfig.Restart）。通过本程序停止的浏览器不会被重启。
+const (
+    RestartNever     = "never"      // 不自动重启
+    RestartOnFailure = "on-failure" // 仅在由本程序启动的浏览器异常退出（退出码非 0 或被信号结束）时重启
+    RestartAlways    = "always"     // 浏览器以任何方式退出后都重启，包括从窗口菜单正常退出
+)
+
+// RestartPolicy 返回配置的自动重启策略，未设置时为 RestartNever。
+func (c *ChromeConfig) RestartPolicy() string {
+    if c.Restart == "" {
+        return RestartNever
+    }
+    return c.Restart
 }
 
 // configFile 定义了存储 Chrome 配置的 JSON 文件的名称和相对路径。
 var configFile = getDefaultConfigFile() // 修改为调用函数获取路径
 
+// Dir 返回配置文件所在的目录，运行时记录、日志等程序数据也存放在这里。
+func Dir() string {
+    return filepath.Dir(configFile)
+}
+
 // DefaultChromeConfigName 定义了默认 Chrome 实例的名称
 const DefaultChromeConfigName = "[默认配置]"
 
+// DefaultChromeConfigID 定义了默认 Chrome 实例的固定 ID，用户配置不能使用它。
+const DefaultChromeConfigID = "default"
+
+// NewConfigID 生成一个新的配置 ID（16 位十六进制随机字符串）。
+func NewConfigID() string {
+    b := make([]byte, 8)
+    if _, err := rand.Read(b); err != nil {
+        panic(fmt.Sprintf("crypto/rand failed: %v", err))
+    }
+    return hex.EncodeToString(b)
+}
+
+// String 返回配置在日志和错误信息中的表示，同时包含名称和 ID。
+func (c *ChromeConfig) String() string {
+    return fmt.Sprintf("%s [%s]", c.Name, c.ID)
+}
+
+// WindowClass 返回配置的浏览器窗口使用的窗口类（X11 的 WM_CLASS，Wayland 的 app_id），
+// 使不同配置的窗口在任务栏中分开分组，并与各自的桌面启动器对应。默认实例沿用浏览器自身的窗口类，返回空字符串。
+func (c *ChromeConfig) WindowClass() string {
+    if c.IsDefault {
+        return ""
+    }
+    return "chromes-" + c.ID
+}
+
+// FindConfig 按 ID 查找配置，未找到时返回 nil。
+func FindConfig(configs []*ChromeConfig, id string) *ChromeConfig {
+    for _, cfg := range configs {
+        if cfg.ID == id {
+            return cfg
+        }
+    }
+    return nil
+}
+
+// FindConfigByName 按名称查找配置，未找到时返回 nil。名称不保证长期不变，
+// 只适合用于用户输入等便捷场景，程序内部应使用 ID。
+func FindConfigByName(configs []*ChromeConfig, name string) *ChromeConfig {
+    for _, cfg := range configs {
+        if cfg.Name == name {
+            return cfg
+        }
+    }
+    return nil
+}
+
 // GetDefaultUserDataDir 返回当前操作系统的默认 Chrome 用户数据目录。
 // 注意：这些路径是常见的默认值，可能因 Chrome 版本或安装方式而异。
 func GetDefaultUserDataDir() string {
@@ -94,39 +171,32 @@ func getDefaultConfigFile() string {
 
 // LoadConfigs 从 JSON 文件加载 Chrome 配置列表。
 // 总是会在列表开头添加一个代表默认 Chrome 实例的配置。
-func LoadConfigs() []*ChromeConfig {
+// 旧格式的配置文件会自动迁移到当前格式（见 CurrentSchemaVersion）。
+// 读取或解析失败时返回 *LoadError，同时返回只包含默认实例的列表，确保程序基本可用：
+// 解析失败的文件会被隔离，无法读取的文件则会阻止后续保存，避免覆盖用户的配置。
+// 文件由更新版本的程序写入时，返回其中的配置但禁止保存（错误包装了 ErrNewerSchema）。
+func LoadConfigs() ([]*ChromeConfig, error) {
     defaultInstance := &ChromeConfig{
+        ID:          DefaultChromeConfigID,
         Name:        DefaultChromeConfigName, //  "Default"
         UserDataDir: "",                      // 空字符串表示默认实例
         IsDefault:   true,
     }
 
-    data, err := os.ReadFile(configFile)
-    if err != nil {
-        if os.IsNotExist(err) {
-            // 配置文件不存在时，只返回默认实例
-            return []*ChromeConfig{defaultInstance}
-        }
-        log.Printf("[load] read failed. path=%v, err=%v", configFile, err)
-        // 读取失败也返回默认实例，确保程序基本可用
-        return []*ChromeConfig{defaultInstance}
-    }
-    log.Printf("[load] read success. path=%v, size=%d bytes", configFile, len(data))
-
-    var userConfigs []*ChromeConfig
-    if err = json.Unmarshal(data, &userConfigs); err != nil {
-        log.Printf("[load] json failed. path=%v, err=%v", configFile, err)
-        // 解析失败也返回默认实例
-        return []*ChromeConfig{defaultInstance}
+    doc, loadErr := loadDocument()
+    if doc == nil {
+        return []*ChromeConfig{defaultInstance}, loadErr
     }
+    userConfigs := doc.Profiles
 
     // 校验加载的配置，确保没有用户配置的 UserDataDir 与实际的默认路径冲突
     // 或者 Name 与 DefaultChromeConfigName 冲突
     actualDefaultDir := GetDefaultUserDataDir()
     validUserConfigs := make([]*ChromeConfig, 0, len(userConfigs))
     for _, cfg := range userConfigs {
+        // 保留给默认实例的 ID 已在加载时被 ensureProfileIDs 替换，这里只需检查名称
         if cfg.Name == DefaultChromeConfigName {
-            log.Printf("Warning: Config name '%s' is reserved for the default instance and will be ignored from file.", cfg.Name)
+            log.Printf("Warning: Config '%s' [%s] uses the name reserved for the default instance and will be ignored from file.", cfg.Name, cfg.ID)
             continue
         }
         // 将路径转换为绝对路径并进行比较
@@ -141,6 +211,16 @@ func LoadConfigs() []*ChromeConfig {
             log.Printf("Warning: Config '%s' has an empty UserDataDir and will be ignored.", cfg.Name)
             continue
         }
+        // 忽略不合法的额外参数（例如管理器保留的参数），保留配置本身
+        validFlags := make([]string, 0, len(cfg.Flags))
+        for _, flag := range cfg.Flags {
+            if err := ValidateFlag(flag); err != nil {
+                log.Printf("Warning: Config '%s' flag ignored: %v", cfg.Name, err)
+                continue
+            }
+            validFlags = append(validFlags, flag)
+        }
+        cfg.Flags = validFlags
         cfg.IsDefault = false // 明确标记非默认
         validUserConfigs = append(validUserConfigs, cfg)
     }
@@ -150,12 +230,13 @@ func LoadConfigs() []*ChromeConfig {
     allConfigs = append(allConfigs, defaultInstance)
     allConfigs = append(allConfigs, validUserConfigs...)
 
-    return allConfigs
+    return allConfigs, loadErr
 }
 
 // SaveConfigs 将 Chrome 配置列表保存到 JSON 文件。
 // "默认"实例不会被保存到文件中。
 // 在保存前会检查是否有自定义配置与默认路径冲突。
+// 通过临时文件加重命名的方式原子地替换文件，并保留最近 MaxBackups 个版本的备份。
 func SaveConfigs(cfgs []*ChromeConfig) error {
     userConfigs := make([]*ChromeConfig, 0, len(cfgs))
     actualDefaultDir := GetDefaultUserDataDir()
@@ -170,6 +251,9 @@ func SaveConfigs(cfgs []*ChromeConfig) error {
         if cfg.UserDataDir == "" {
             return fmt.Errorf("user-defined config '%s' cannot have an empty UserDataDir", cfg.Name)
         }
+        if cfg.ID == "" || cfg.ID == DefaultChromeConfigID {
+            return fmt.Errorf("user-defined config '%s' has an invalid id '%s'", cfg.Name, cfg.ID)
+        }
 
         // 检查是否与实际的默认路径冲突
         absCfgPath, errCfg := filepath.Abs(cfg.UserDataDir)
@@ -178,29 +262,46 @@ func SaveConfigs(cfgs []*ChromeConfig) error {
         if actualDefaultDir != "" && errCfg == nil && errDef == nil && strings.EqualFold(absCfgPath, absDefaultPath) {
             return fmt.Errorf("config '%s' cannot use the default Chrome profile path: %s", cfg.Name, cfg.UserDataDir)
         }
+        if err := ValidateFlags(cfg.Flags); err != nil {
+            return fmt.Errorf("config '%s': %w", cfg.Name, err)
+        }
         userConfigs = append(userConfigs, cfg)
     }
 
-    data, err := json.MarshalIndent(userConfigs, "", "  ")
-    if err != nil {
-        return err
-    }
-    // 确保目录存在 (如果 configFile 包含子目录)
-    // Ensure directory exists (if configFile includes subdirectories)
-    if err := os.MkdirAll(filepath.Dir(configFile), 0750); err != nil {
-        return err
-    }
-    return os.WriteFile(configFile, data, 0640)
+    return updateDocument(func(doc *Document) {
+        doc.Profiles = userConfigs
+    })
 }
 
-// AddConfig 向配置列表中添加一个新的 ChromeConfig，并保存。
-// 会检查 name 和 user_data_dir 是否重复，以及 user_data_dir 是否为默认路径。
-func AddConfig(name string, userDataDir string, currentConfigs []*ChromeConfig) ([]*ChromeConfig, error) {
+// validateConfig 检查配置的名称、数据目录和启动参数是否合法，且与 currentConfigs 中的其他配置不冲突。
+// self 为正在被修改的配置（新增时为 nil），比较重复时会跳过它。
+func validateConfig(newConfig *ChromeConfig, currentConfigs []*ChromeConfig, self *ChromeConfig) error {
+    name, userDataDir := newConfig.Name, newConfig.UserDataDir
+    if strings.TrimSpace(name) == "" {
+        return fmt.Errorf("config name cannot be empty")
+    }
     if name == DefaultChromeConfigName {
-        return currentConfigs, fmt.Errorf("cannot add config with reserved name '%s'", DefaultChromeConfigName)
+        return fmt.Errorf("cannot use reserved config name '%s'", DefaultChromeConfigName)
+    }
+    if newConfig.ID == DefaultChromeConfigID {
+        return fmt.Errorf("cannot use reserved config id '%s'", DefaultChromeConfigID)
     }
     if strings.TrimSpace(userDataDir) == "" {
-        return currentConfigs, fmt.Errorf("user data directory cannot be empty for a custom profile")
+        return fmt.Errorf("user data directory cannot be empty for a custom profile")
+    }
+    if err := ValidateFlags(newConfig.Flags); err != nil {
+        return err
+    }
+    if newConfig.LogLevel < 0 {
+        return fmt.Errorf("invalid log level %d", newConfig.LogLevel)
+    }
+    switch newConfig.RestartPolicy() {
+    case RestartNever, RestartOnFailure, RestartAlways:
+    default:
+        return fmt.Errorf("invalid restart policy '%s'", newConfig.Restart)
+    }
+    if newConfig.DebugPort < 0 || newConfig.DebugPort > 65535 {
+        return fmt.Errorf("invalid remote debugging port %d", newConfig.DebugPort)
     }
 
     actualDefaultDir := GetDefaultUserDataDir()
@@ -208,24 +309,43 @@ func AddConfig(name string, userDataDir string, currentConfigs []*ChromeConfig)
     absDefaultPath, errDef := filepath.Abs(actualDefaultDir)
 
     if actualDefaultDir != "" && errNew == nil && errDef == nil && strings.EqualFold(absNewPath, absDefaultPath) {
-        return currentConfigs, fmt.Errorf("the user data directory '%s' is reserved for the default Chrome profile", userDataDir)
+        return fmt.Errorf("the user data directory '%s' is reserved for the default Chrome profile", userDataDir)
     }
 
     for _, cfg := range currentConfigs {
-        if cfg.IsDefault {
-            continue // 跳过与默认实例的比较，因为它的 UserDataDir 是 ""
+        if cfg.IsDefault || cfg == self {
+            continue // 跳过与默认实例的比较，因为它的 UserDataDir 是 ""；也跳过被修改的配置自身
+        }
+        if newConfig.ID != "" && cfg.ID == newConfig.ID {
+            return fmt.Errorf("config id '%s' already exists", newConfig.ID)
         }
         if cfg.Name == name {
-            return currentConfigs, fmt.Errorf("config name '%s' already exists", name)
+            return fmt.Errorf("config name '%s' already exists", name)
+        }
+        if newConfig.DevTools && newConfig.DebugPort > 0 && cfg.DevTools && cfg.DebugPort == newConfig.DebugPort {
+            return fmt.Errorf("remote debugging port %d is already used by config '%s'", newConfig.DebugPort, cfg.Name)
         }
         // 比较绝对路径以避免大小写和相对路径问题
         absExistingPath, errExisting := filepath.Abs(cfg.UserDataDir)
         if errExisting == nil && errNew == nil && strings.EqualFold(absExistingPath, absNewPath) {
-            return currentConfigs, fmt.Errorf("user data directory '%s' (resolved to '%s') already exists in config '%s'", userDataDir, absNewPath, cfg.Name)
+            return fmt.Errorf("user data directory '%s' (resolved to '%s') already exists in config '%s'", userDataDir, absNewPath, cfg.Name)
         }
     }
+    return nil
+}
+
+// AddConfig 向配置列表中添加一个新的 ChromeConfig，并保存。
+// 会检查 name 和 user_data_dir 是否重复，以及 user_data_dir 是否为默认路径。
+// newConfig.ID 为空时会自动生成。
+func AddConfig(newConfig *ChromeConfig, currentConfigs []*ChromeConfig) ([]*ChromeConfig, error) {
+    if err := validateConfig(newConfig, currentConfigs, nil); err != nil {
+        return currentConfigs, err
+    }
+    if newConfig.ID == "" {
+        newConfig.ID = NewConfigID()
+    }
 
-    newConfig := &ChromeConfig{Name: name, UserDataDir: userDataDir, IsDefault: false}
+    newConfig.IsDefault = false
     updatedConfigs := append(currentConfigs, newConfig)
 
     if err := SaveConfigs(updatedConfigs); err != nil {
@@ -234,25 +354,88 @@ func AddConfig(name string, userDataDir string, currentConfigs []*ChromeConfig)
     return updatedConfigs, nil
 }
 
-// RemoveConfig 从配置列表中移除指定名称的 ChromeConfig，并保存。
+// UpdateConfig 用 updated 替换 ID 为 id 的配置（可以修改名称、数据目录和其他属性），并保存。
+// 配置的 ID 不可修改，updated.ID 会被设置为 id。
+// 会执行与 AddConfig 相同的重复和保留路径校验，但不会与被修改的配置自身比较。
+// moveData 为 true 且数据目录发生变化时，会先把原数据目录移动到新位置（见 MoveUserDataDir），
+// 保存失败时再把目录移回原处。调用方需要确保对应的浏览器实例没有在运行。
+func UpdateConfig(id string, updated *ChromeConfig, moveData bool, currentConfigs []*ChromeConfig) ([]*ChromeConfig, error) {
+    if id == DefaultChromeConfigID {
+        return currentConfigs, fmt.Errorf("cannot edit the default Chrome instance")
+    }
+
+    index := -1
+    for i, cfg := range currentConfigs {
+        if !cfg.IsDefault && cfg.ID == id {
+            index = i
+            break
+        }
+    }
+    if index < 0 {
+        return currentConfigs, fmt.Errorf("config id '%s' not found", id)
+    }
+    old := currentConfigs[index]
+    updated.ID = old.ID
+
+    if err := validateConfig(updated, currentConfigs, old); err != nil {
+        return currentConfigs, err
+    }
+
+    moved := false
+    if moveData && !samePath(old.UserDataDir, updated.UserDataDir) {
+        if err := MoveUserDataDir(old.UserDataDir, updated.UserDataDir); err != nil {
+            return currentConfigs, fmt.Errorf("failed to move user data directory: %w", err)
+        }
+        moved = true
+    }
+
+    updated.IsDefault = false
+    updatedConfigs := make([]*ChromeConfig, len(currentConfigs))
+    copy(updatedConfigs, currentConfigs)
+    updatedConfigs[index] = updated
+
+    if err := SaveConfigs(updatedConfigs); err != nil {
+        if moved {
+            // 配置没有保存成功，把数据目录移回原处，保持配置与磁盘一致
+            if rollbackErr := MoveUserDataDir(updated.UserDataDir, old.UserDataDir); rollbackErr != nil {
+                log.Printf("[update] rollback move failed. from=%v, to=%v, err=%v", updated.UserDataDir, old.UserDataDir, rollbackErr)
+                return currentConfigs, fmt.Errorf("failed to save configs after updating: %w (moving the data directory back also failed: %v)", err, rollbackErr)
+            }
+        }
+        return currentConfigs, fmt.Errorf("failed to save configs after updating: %w", err)
+    }
+    return updatedConfigs, nil
+}
+
+// samePath 判断两个路径解析为绝对路径后是否完全相同。
+func samePath(a, b string) bool {
+    absA, errA := filepath.Abs(a)
+    absB, errB := filepath.Abs(b)
+    if errA != nil || errB != nil {
+        return a == b
+    }
+    return absA == absB
+}
+
+// RemoveConfig 从配置列表中移除指定 ID 的 ChromeConfig，并保存。
 // 不允许删除 "Default" 实例。
-func RemoveConfig(name string, currentConfigs []*ChromeConfig) ([]*ChromeConfig, error) {
-    if name == DefaultChromeConfigName {
+func RemoveConfig(id string, currentConfigs []*ChromeConfig) ([]*ChromeConfig, error) {
+    if id == DefaultChromeConfigID {
         return currentConfigs, fmt.Errorf("cannot remove the default Chrome instance")
     }
 
     found := false
     updatedConfigs := make([]*ChromeConfig, 0)
     for _, cfg := range currentConfigs {
-        if cfg.Name == name {
+        if cfg.ID == id {
             found = true
             continue // Skip this config
         }
         updatedConfigs = append(updatedConfigs, cfg)
     }
 
-    if (!found) {
-        return currentConfigs, fmt.Errorf("config name '%s' not found", name)
+    if !found {
+        return currentConfigs, fmt.Errorf("config id '%s' not found", id)
     }
 
     if err := SaveConfigs(updatedConfigs); err != nil {
@@ -0,0 +1,115 @@
+package config
+
+import (
+    "fmt"
+    "slices"
+    "strings"
+)
+
+// reservedFlags 是由管理器自身负责设置的命令行参数，不允许在配置中出现。
+var reservedFlags = []string{
+    "user-data-dir",
+    "remote-debugging-port", // 通过配置项的 devtools / debug_port 开启
+    "class",                 // Linux 上每个配置使用各自的窗口类，见 ChromeConfig.WindowClass
+}
+
+// featureListFlags 是值为逗号分隔特性列表的参数，合并时取并集而不是覆盖。
+var featureListFlags = map[string]bool{
+    "enable-features":  true,
+    "disable-features": true,
+}
+
+// splitFlag 将 "--name=value" 形式的参数拆分为小写的参数名和值。
+func splitFlag(flag string) (name string, value string) {
+    name, value, _ = strings.Cut(strings.TrimLeft(flag, "-"), "=")
+    return strings.ToLower(name), value
+}
+
+// ValidateFlag 检查单个额外启动参数是否合法：必须以 "--" 开头、有参数名，且不能是管理器保留的参数。
+func ValidateFlag(flag string) error {
+    if !strings.HasPrefix(flag, "--") {
+        return fmt.Errorf("invalid flag '%s': flags must start with '--'", flag)
+    }
+    name, _ := splitFlag(flag)
+    if name == "" {
+        return fmt.Errorf("invalid flag '%s': missing flag name", flag)
+    }
+    for _, reserved := range reservedFlags {
+        if name == reserved {
+            return fmt.Errorf("flag '--%s' is managed by chromes and cannot be set manually", reserved)
+        }
+    }
+    return nil
+}
+
+// ValidateFlags 检查一组额外启动参数，返回遇到的第一个错误。
+func ValidateFlags(flags []string) error {
+    for _, flag := range flags {
+        if err := ValidateFlag(flag); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// MergeFlags 合并全局默认参数和配置自身的参数，返回实际传给浏览器的参数列表。
+// 规则：
+//   - 同名参数以配置自身的为准（同一列表内后出现的覆盖先出现的）；
+//   - --enable-features / --disable-features 的特性列表取并集，
+//     配置自身启用的特性会从全局禁用列表中移除，反之亦然。
+//
+// 结果按参数名首次出现的顺序排列，被覆盖的全局参数保留其原有位置。
+func MergeFlags(defaults []string, overrides []string) []string {
+    order := make([]string, 0, len(defaults)+len(overrides)) // 参数名按首次出现的顺序
+    values := make(map[string]string)                        // 参数名 -> 最终的原始参数
+    features := map[string][]string{}                        // 特性参数名 -> 合并后的特性列表
+
+    addFeatures := func(name, value string, fromOverride bool) {
+        opposite := "disable-features"
+        if name == "disable-features" {
+            opposite = "enable-features"
+        }
+        for _, feature := range strings.Split(value, ",") {
+            feature = strings.TrimSpace(feature)
+            if feature == "" {
+                continue
+            }
+            if fromOverride {
+                features[opposite] = slices.DeleteFunc(features[opposite], func(f string) bool { return f == feature })
+            }
+            if !slices.Contains(features[name], feature) {
+                features[name] = append(features[name], feature)
+            }
+        }
+    }
+
+    apply := func(flags []string, fromOverride bool) {
+        for _, flag := range flags {
+            name, value := splitFlag(flag)
+            if name == "" {
+                continue
+            }
+            if _, seen := values[name]; !seen {
+                order = append(order, name)
+            }
+            values[name] = flag
+            if featureListFlags[name] {
+                addFeatures(name, value, fromOverride)
+            }
+        }
+    }
+    apply(defaults, false)
+    apply(overrides, true)
+
+    merged := make([]string, 0, len(order))
+    for _, name := range order {
+        if featureListFlags[name] {
+            if len(features[name]) > 0 {
+                merged = append(merged, "--"+name+"="+strings.Join(features[name], ","))
+            }
+            continue
+        }
+        merged = append(merged, values[name])
+    }
+    return merged
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+    "slices"
+    "testing"
+)
+
+func TestMergeFlags(t *testing.T) {
+    tests := []struct {
+        name      string
+        defaults  []string
+        overrides []string
+        want      []string
+    }{
+        {"empty", nil, nil, []string{}},
+        {"defaults only", []string{"--lang=en", "--incognito"}, nil, []string{"--lang=en", "--incognito"}},
+        {"overrides only", nil, []string{"--lang=de"}, []string{"--lang=de"}},
+        {
+            "override replaces default in place",
+            []string{"--lang=en", "--incognito", "--window-size=800,600"},
+            []string{"--window-size=1280,800", "--lang=de"},
+            []string{"--lang=de", "--incognito", "--window-size=1280,800"},
+        },
+        {"later flag in the same list wins", []string{"--lang=en", "--lang=fr"}, nil, []string{"--lang=fr"}},
+        {"names are case insensitive", []string{"--Lang=en"}, []string{"--lang=de"}, []string{"--lang=de"}},
+        {"new overrides are appended", []string{"--lang=en"}, []string{"--incognito"}, []string{"--lang=en", "--incognito"}},
+        {
+            "enabled features are merged",
+            []string{"--enable-features=A,B"},
+            []string{"--enable-features=B,C"},
+            []string{"--enable-features=A,B,C"},
+        },
+        {
+            "disabled features are merged",
+            []string{"--disable-features=A"},
+            []string{"--disable-features=B"},
+            []string{"--disable-features=A,B"},
+        },
+        {
+            "override enable removes global disable",
+            []string{"--disable-features=A,B"},
+            []string{"--enable-features=A"},
+            []string{"--disable-features=B", "--enable-features=A"},
+        },
+        {
+            "override disable removes global enable",
+            []string{"--enable-features=A,B", "--lang=en"},
+            []string{"--disable-features=B"},
+            []string{"--enable-features=A", "--lang=en", "--disable-features=B"},
+        },
+        {
+            "emptied feature list is dropped",
+            []string{"--disable-features=A"},
+            []string{"--enable-features=A"},
+            []string{"--enable-features=A"},
+        },
+        {
+            "defaults do not remove each other",
+            []string{"--enable-features=A", "--disable-features=A"},
+            nil,
+            []string{"--enable-features=A", "--disable-features=A"},
+        },
+        {
+            "blank features are ignored",
+            []string{"--enable-features=A,, B ,"},
+            nil,
+            []string{"--enable-features=A,B"},
+        },
+        {"flags without a name are skipped", []string{"--", "--=x"}, []string{"--lang=de"}, []string{"--lang=de"}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := MergeFlags(tt.defaults, tt.overrides); !slices.Equal(got, tt.want) {
+                t.Errorf("MergeFlags(%q, %q) = %q, want %q", tt.defaults, tt.overrides, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestValidateFlag(t *testing.T) {
+    for _, flag := range []string{"--lang=en", "--incognito", "--enable-features=A,B"} {
+        if err := ValidateFlag(flag); err != nil {
+            t.Errorf("ValidateFlag(%q): %v", flag, err)
+        }
+    }
+    for _, flag := range []string{
+        "lang=en",
+        "-incognito",
+        "--",
+        "--=value",
+        "--user-data-dir=/tmp/x",
+        "--Remote-Debugging-Port=9222",
+        "--class=x",
+    } {
+        if err := ValidateFlag(flag); err == nil {
+            t.Errorf("ValidateFlag(%q) accepted an invalid flag", flag)
+        }
+    }
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+    "fmt"
+    "io"
+    "io/fs"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// SingletonLockName 是浏览器在正在使用的用户数据目录中创建的锁文件名，chrome 包读取和清理锁时使用同一个名称。
+const SingletonLockName = "SingletonLock"
+
+// rename 用于移动目录，测试中替换它以模拟重命名失败（例如跨文件系统）。
+var rename = os.Rename
+
+// MoveUserDataDir 将用户数据目录从 src 移动到 dst，dst 必须不存在或为空目录。
+// 优先直接重命名；重命名失败（例如跨文件系统）时逐个复制文件后删除源目录，
+// 复制中途失败会删除已复制的内容，源目录保持不变。
+// 源目录中存在 SingletonLock（浏览器可能正在使用该目录）时拒绝移动；源目录不存在时不做任何事。
+func MoveUserDataDir(src, dst string) error {
+    absSrc, err := filepath.Abs(src)
+    if err != nil {
+        return fmt.Errorf("failed to get absolute path for %s: %w", src, err)
+    }
+    absDst, err := filepath.Abs(dst)
+    if err != nil {
+        return fmt.Errorf("failed to get absolute path for %s: %w", dst, err)
+    }
+    if absSrc == absDst {
+        return nil
+    }
+    if strings.HasPrefix(absDst, absSrc+string(filepath.Separator)) {
+        return fmt.Errorf("cannot move '%s' into its own subdirectory '%s'", absSrc, absDst)
+    }
+
+    info, err := os.Stat(absSrc)
+    if err != nil {
+        if os.IsNotExist(err) {
+            log.Printf("[move] source does not exist, nothing to move. src=%v", absSrc)
+            return nil
+        }
+        return err
+    }
+    if !info.IsDir() {
+        return fmt.Errorf("'%s' is not a directory", absSrc)
+    }
+    if _, err := os.Lstat(filepath.Join(absSrc, SingletonLockName)); err == nil {
+        return fmt.Errorf("'%s' is locked by a running browser (%s exists)", absSrc, SingletonLockName)
+    }
+
+    // 目标必须不存在或为空目录，空目录先删除以便直接重命名
+    if entries, err := os.ReadDir(absDst); err == nil {
+        if len(entries) > 0 {
+            return fmt.Errorf("destination '%s' already exists and is not empty", absDst)
+        }
+        if err := os.Remove(absDst); err != nil {
+            return err
+        }
+    } else if !os.IsNotExist(err) {
+        return fmt.Errorf("destination '%s' is not usable: %w", absDst, err)
+    }
+    if err := os.MkdirAll(filepath.Dir(absDst), 0750); err != nil {
+        return err
+    }
+
+    if err := rename(absSrc, absDst); err != nil {
+        log.Printf("[move] rename failed, falling back to copy. src=%v, dst=%v, err=%v", absSrc, absDst, err)
+    } else {
+        log.Printf("[move] renamed. src=%v, dst=%v", absSrc, absDst)
+        return nil
+    }
+
+    if err := copyDir(absSrc, absDst); err != nil {
+        // 回滚：删除已复制的部分，源目录保持不变
+        if removeErr := os.RemoveAll(absDst); removeErr != nil {
+            log.Printf("[move] rollback failed. dst=%v, err=%v", absDst, removeErr)
+        }
+        return fmt.Errorf("failed to copy '%s' to '%s': %w", absSrc, absDst, err)
+    }
+    if err := os.RemoveAll(absSrc); err != nil {
+        // 数据已完整复制到新位置，残留的源目录只需提示用户手动清理
+        log.Printf("[move] remove source failed. src=%v, err=%v", absSrc, err)
+    }
+    log.Printf("[move] copied. src=%v, dst=%v", absSrc, absDst)
+    return nil
+}
+
+// copyDir 递归复制目录，保留文件权限和符号链接，跳过套接字等特殊文件。
+func copyDir(src, dst string) error {
+    return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+        if err != nil {
+            return err
+        }
+        rel, err := filepath.Rel(src, path)
+        if err != nil {
+            return err
+        }
+        target := filepath.Join(dst, rel)
+
+        info, err := d.Info()
+        if err != nil {
+            return err
+        }
+        switch {
+        case d.IsDir():
+            return os.MkdirAll(target, info.Mode().Perm()|0700)
+        case info.Mode()&fs.ModeSymlink != 0:
+            link, err := os.Readlink(path)
+            if err != nil {
+                return err
+            }
+            return os.Symlink(link, target)
+        case info.Mode().IsRegular():
+            return copyFile(path, target, info.Mode().Perm())
+        default:
+            return nil
+        }
+    })
+}
+
+// copyFile 复制单个普通文件。
+func copyFile(src, dst string, perm fs.FileMode) error {
+    in, err := os.Open(src)
+    if err != nil {
+        return err
+    }
+    defer in.Close()
+
+    out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+    if err != nil {
+        return err
+    }
+    if _, err := io.Copy(out, in); err != nil {
+        out.Close()
+        return err
+    }
+    return out.Close()
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// writeTree 在 dir 下创建测试用的用户数据目录内容：普通文件、子目录中的文件和符号链接。
+func writeTree(t *testing.T, dir string) {
+    t.Helper()
+    if err := os.MkdirAll(filepath.Join(dir, "Default", "Cache"), 0700); err != nil {
+        t.Fatal(err)
+    }
+    writeTestFile(t, filepath.Join(dir, "Local State"), `{"profile":{}}`)
+    writeTestFile(t, filepath.Join(dir, "Default", "Preferences"), `{"homepage":"https://example.com"}`)
+    if err := os.Symlink("Preferences", filepath.Join(dir, "Default", "link")); err != nil {
+        t.Fatal(err)
+    }
+}
+
+// checkTree 检查 dir 中是否有 writeTree 创建的内容。
+func checkTree(t *testing.T, dir string) {
+    t.Helper()
+    data, err := os.ReadFile(filepath.Join(dir, "Default", "Preferences"))
+    if err != nil || string(data) != `{"homepage":"https://example.com"}` {
+        t.Errorf("Preferences in %s: %q, err=%v", dir, data, err)
+    }
+    if data, err := os.ReadFile(filepath.Join(dir, "Local State")); err != nil || string(data) != `{"profile":{}}` {
+        t.Errorf("Local State in %s: %q, err=%v", dir, data, err)
+    }
+    if link, err := os.Readlink(filepath.Join(dir, "Default", "link")); err != nil || link != "Preferences" {
+        t.Errorf("symlink in %s: %q, err=%v", dir, link, err)
+    }
+    if info, err := os.Stat(filepath.Join(dir, "Default", "Cache")); err != nil || !info.IsDir() {
+        t.Errorf("empty directory in %s not kept: err=%v", dir, err)
+    }
+}
+
+// failRename 让 MoveUserDataDir 的重命名失败，测试结束后恢复；hook 在返回错误前调用，可以为 nil。
+func failRename(t *testing.T, hook func(src, dst string)) {
+    t.Helper()
+    old := rename
+    rename = func(src, dst string) error {
+        if hook != nil {
+            hook(src, dst)
+        }
+        return &os.LinkError{Op: "rename", Old: src, New: dst, Err: errors.New("invalid cross-device link")}
+    }
+    t.Cleanup(func() { rename = old })
+}
+
+func TestMoveUserDataDirRename(t *testing.T) {
+    root := t.TempDir()
+    src, dst := filepath.Join(root, "old"), filepath.Join(root, "new", "profile")
+    writeTree(t, src)
+
+    if err := MoveUserDataDir(src, dst); err != nil {
+        t.Fatal(err)
+    }
+    checkTree(t, dst)
+    if _, err := os.Stat(src); !os.IsNotExist(err) {
+        t.Errorf("source still exists: err=%v", err)
+    }
+}
+
+func TestMoveUserDataDirCopyFallback(t *testing.T) {
+    root := t.TempDir()
+    src, dst := filepath.Join(root, "old"), filepath.Join(root, "new")
+    writeTree(t, src)
+    if err := os.Mkdir(dst, 0700); err != nil { // 空的目标目录可以使用
+        t.Fatal(err)
+    }
+    failRename(t, nil)
+
+    if err := MoveUserDataDir(src, dst); err != nil {
+        t.Fatal(err)
+    }
+    checkTree(t, dst)
+    if _, err := os.Stat(src); !os.IsNotExist(err) {
+        t.Errorf("source not removed after copying: err=%v", err)
+    }
+}
+
+func TestMoveUserDataDirRollsBackPartialCopy(t *testing.T) {
+    root := t.TempDir()
+    src, dst := filepath.Join(root, "old"), filepath.Join(root, "new")
+    writeTree(t, src)
+    // 重命名失败后目标中出现了同名文件（例如被其他程序写入），复制到一半时失败
+    failRename(t, func(_, dst string) {
+        if err := os.MkdirAll(filepath.Join(dst, "Default"), 0700); err != nil {
+            t.Fatal(err)
+        }
+        writeTestFile(t, filepath.Join(dst, "Default", "Preferences"), "conflict")
+    })
+
+    if err := MoveUserDataDir(src, dst); err == nil {
+        t.Fatal("expected the copy to fail")
+    }
+    if _, err := os.Stat(dst); !os.IsNotExist(err) {
+        t.Errorf("partial copy not removed: err=%v", err)
+    }
+    checkTree(t, src)
+}
+
+func TestMoveUserDataDirRefuses(t *testing.T) {
+    root := t.TempDir()
+    src := filepath.Join(root, "old")
+    writeTree(t, src)
+
+    notEmpty := filepath.Join(root, "busy")
+    if err := os.Mkdir(notEmpty, 0700); err != nil {
+        t.Fatal(err)
+    }
+    writeTestFile(t, filepath.Join(notEmpty, "file"), "x")
+    if err := MoveUserDataDir(src, notEmpty); err == nil {
+        t.Error("moved into a non-empty directory")
+    }
+    if err := MoveUserDataDir(src, filepath.Join(src, "Default", "nested")); err == nil {
+        t.Error("moved into its own subdirectory")
+    }
+
+    if err := os.Symlink("host-1234", filepath.Join(src, SingletonLockName)); err != nil {
+        t.Fatal(err)
+    }
+    if err := MoveUserDataDir(src, filepath.Join(root, "new")); err == nil {
+        t.Error("moved a directory locked by a browser")
+    }
+    checkTree(t, src)
+
+    // 源目录不存在时不做任何事
+    if err := MoveUserDataDir(filepath.Join(root, "missing"), filepath.Join(root, "other")); err != nil {
+        t.Errorf("missing source: %v", err)
+    }
+    if _, err := os.Stat(filepath.Join(root, "other")); !os.IsNotExist(err) {
+        t.Errorf("destination created for a missing source: err=%v", err)
+    }
+}
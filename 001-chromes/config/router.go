@@ -0,0 +1,170 @@
+package config
+
+import (
+    "fmt"
+    "net/url"
+    "path"
+    "regexp"
+    "slices"
+    "strings"
+    "sync"
+)
+
+// 路由规则的匹配方式。
+const (
+    MatchDomain = "domain" // 以通配符匹配网址的主机名，例如 "*.example.com"
+    MatchRegex  = "regex"  // 以正则表达式匹配完整的网址
+    MatchSource = "source" // 以通配符匹配打开网址的来源程序，来源未知时不匹配
+)
+
+// MatchKinds 是所有的匹配方式，按界面中的显示顺序排列。
+var MatchKinds = []string{MatchDomain, MatchRegex, MatchSource}
+
+// RouteRule 是一条链接路由规则：匹配的网址在 Profile 指定的配置中打开。
+type RouteRule struct {
+    Match   string `json:"match"`   // 匹配方式，取值见 MatchDomain 等常量
+    Pattern string `json:"pattern"` // 通配符或正则表达式
+    Profile string `json:"profile"` // 目标配置的 ID
+}
+
+// Router 是链接路由的设置：规则按顺序匹配，第一条匹配的规则决定打开网址的配置。
+type Router struct {
+    Rules    []*RouteRule `json:"rules,omitempty"`
+    Fallback string       `json:"fallback,omitempty"` // 没有规则匹配时使用的配置 ID，为空时使用默认实例
+    Chooser  bool         `json:"chooser,omitempty"`  // 没有规则匹配时让用户选择配置，而不是直接使用后备配置
+}
+
+// Matches 返回规则是否匹配 u，source 是打开网址的来源程序，未知时为空。
+// 域名和来源的通配符不区分大小写，"*.example.com" 同时匹配 "example.com" 本身。
+// 规则已经过 ValidateRouter 校验，无效的规则不匹配任何网址。
+func (r *RouteRule) Matches(u *url.URL, source string) bool {
+    switch r.Match {
+    case MatchDomain:
+        host := strings.ToLower(u.Hostname())
+        if host == "" {
+            return false
+        }
+        pattern := strings.ToLower(r.Pattern)
+        if matched, _ := path.Match(pattern, host); matched {
+            return true
+        }
+        return strings.HasPrefix(pattern, "*.") && host == pattern[2:]
+    case MatchRegex:
+        re, err := compileRegex(r.Pattern)
+        return err == nil && re.MatchString(u.String())
+    case MatchSource:
+        if source == "" {
+            return false
+        }
+        matched, _ := path.Match(strings.ToLower(r.Pattern), strings.ToLower(source))
+        return matched
+    }
+    return false
+}
+
+// compiledRegexes 缓存规则中的正则表达式的编译结果（*compiledRegex），键为模式，每个模式只编译一次。
+var compiledRegexes sync.Map
+
+type compiledRegex struct {
+    re  *regexp.Regexp
+    err error
+}
+
+// compileRegex 返回 pattern 编译后的正则表达式，编译结果（包括错误）会被缓存。
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+    if cached, ok := compiledRegexes.Load(pattern); ok {
+        c := cached.(*compiledRegex)
+        return c.re, c.err
+    }
+    re, err := regexp.Compile(pattern)
+    compiledRegexes.Store(pattern, &compiledRegex{re: re, err: err})
+    return re, err
+}
+
+// ValidateRouter 检查每条规则的匹配方式、模式和目标配置。
+func ValidateRouter(router *Router) error {
+    for i, rule := range router.Rules {
+        if strings.TrimSpace(rule.Pattern) == "" {
+            return fmt.Errorf("rule %d: pattern cannot be empty", i+1)
+        }
+        if rule.Profile == "" {
+            return fmt.Errorf("rule %d: no profile selected", i+1)
+        }
+        switch rule.Match {
+        case MatchDomain, MatchSource:
+            if _, err := path.Match(rule.Pattern, ""); err != nil {
+                return fmt.Errorf("rule %d: invalid pattern '%s': %w", i+1, rule.Pattern, err)
+            }
+        case MatchRegex:
+            if _, err := compileRegex(rule.Pattern); err != nil {
+                return fmt.Errorf("rule %d: invalid regular expression: %w", i+1, err)
+            }
+        default:
+            return fmt.Errorf("rule %d: unknown match kind '%s'", i+1, rule.Match)
+        }
+    }
+    return nil
+}
+
+// Route 返回 rawURL 应该在 configs 中的哪个配置打开，以及匹配的规则序号。
+// 目标配置已被删除的规则会被跳过；没有规则匹配时返回后备配置（后备配置不存在时为默认实例）和 -1。
+// configs 中没有默认实例且后备配置不存在时返回 nil。
+func (r *Router) Route(configs []*ChromeConfig, rawURL string, source string) (*ChromeConfig, int) {
+    if u, err := url.Parse(rawURL); err == nil {
+        for i, rule := range r.Rules {
+            if !rule.Matches(u, source) {
+                continue
+            }
+            if cfg := FindConfig(configs, rule.Profile); cfg != nil {
+                return cfg, i
+            }
+        }
+    }
+    if cfg := FindConfig(configs, r.Fallback); cfg != nil {
+        return cfg, -1
+    }
+    return FindConfig(configs, DefaultChromeConfigID), -1
+}
+
+// NeedsChooser 返回没有规则匹配的网址（Route 返回的序号为 -1）是否需要让用户选择配置。
+func (r *Router) NeedsChooser(rule int) bool {
+    return rule < 0 && r.Chooser
+}
+
+// SetRouter 设置链接路由并保存，规则会先经过 ValidateRouter 校验。
+func SetRouter(settings *Settings, router *Router) error {
+    if err := ValidateRouter(router); err != nil {
+        return err
+    }
+    old := settings.Router
+    settings.Router = router
+    if err := SaveSettings(settings); err != nil {
+        settings.Router = old
+        return fmt.Errorf("failed to save settings: %w", err)
+    }
+    return nil
+}
+
+// RouterOrEmpty 返回链接路由的设置，没有设置时返回空的路由（所有网址都使用默认实例）。
+func (s *Settings) RouterOrEmpty() *Router {
+    if s.Router == nil {
+        return &Router{}
+    }
+    return s.Router
+}
+
+// DomainRule 返回在 profile 中打开 rawURL 所在主机的规则，用于“记住选择”，rawURL 没有主机名时返回 nil。
+func DomainRule(rawURL string, profile string) *RouteRule {
+    u, err := url.Parse(rawURL)
+    if err != nil || u.Hostname() == "" {
+        return nil
+    }
+    return &RouteRule{Match: MatchDomain, Pattern: strings.ToLower(u.Hostname()), Profile: profile}
+}
+
+// WithRule 返回在末尾追加了 rule 的路由副本，原路由不变。
+func (r *Router) WithRule(rule *RouteRule) *Router {
+    updated := *r
+    updated.Rules = append(slices.Clone(r.Rules), rule)
+    return &updated
+}
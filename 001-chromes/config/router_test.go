@@ -0,0 +1,133 @@
+package config
+
+import (
+    "net/url"
+    "testing"
+)
+
+func TestRouteRuleMatches(t *testing.T) {
+    tests := []struct {
+        name   string
+        rule   RouteRule
+        url    string
+        source string
+        want   bool
+    }{
+        {"domain exact", RouteRule{Match: MatchDomain, Pattern: "example.com"}, "https://example.com/a", "", true},
+        {"domain case insensitive", RouteRule{Match: MatchDomain, Pattern: "Example.COM"}, "https://EXAMPLE.com", "", true},
+        {"domain other host", RouteRule{Match: MatchDomain, Pattern: "example.com"}, "https://example.org", "", false},
+        {"domain glob subdomain", RouteRule{Match: MatchDomain, Pattern: "*.example.com"}, "https://mail.example.com", "", true},
+        {"domain glob bare domain", RouteRule{Match: MatchDomain, Pattern: "*.example.com"}, "https://example.com", "", true},
+        {"domain glob suffix only", RouteRule{Match: MatchDomain, Pattern: "*.example.com"}, "https://badexample.com", "", false},
+        {"domain glob nested subdomain", RouteRule{Match: MatchDomain, Pattern: "*.example.com"}, "https://a.b.example.com", "", true},
+        {"domain ignores port", RouteRule{Match: MatchDomain, Pattern: "localhost"}, "http://localhost:8080/x", "", true},
+        {"domain without host", RouteRule{Match: MatchDomain, Pattern: "*"}, "mailto:someone@example.com", "", false},
+        {"regex full url", RouteRule{Match: MatchRegex, Pattern: `^https://github\.com/work-org/`}, "https://github.com/work-org/repo", "", true},
+        {"regex no match", RouteRule{Match: MatchRegex, Pattern: `^https://github\.com/work-org/`}, "https://github.com/other/repo", "", false},
+        {"regex invalid", RouteRule{Match: MatchRegex, Pattern: `(`}, "https://example.com", "", false},
+        {"source glob", RouteRule{Match: MatchSource, Pattern: "slack*"}, "https://example.com", "Slack", true},
+        {"source other program", RouteRule{Match: MatchSource, Pattern: "slack*"}, "https://example.com", "thunderbird", false},
+        {"source unknown", RouteRule{Match: MatchSource, Pattern: "*"}, "https://example.com", "", false},
+        {"unknown kind", RouteRule{Match: "path", Pattern: "*"}, "https://example.com", "", false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            u, err := url.Parse(tt.url)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got := tt.rule.Matches(u, tt.source); got != tt.want {
+                t.Errorf("Matches(%s, %q) = %v, want %v", tt.url, tt.source, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestRouterRoute(t *testing.T) {
+    defaultCfg := &ChromeConfig{ID: DefaultChromeConfigID}
+    work := &ChromeConfig{ID: "work"}
+    home := &ChromeConfig{ID: "home"}
+    configs := []*ChromeConfig{defaultCfg, work, home}
+    rules := []*RouteRule{
+        {Match: MatchDomain, Pattern: "*.deleted.example", Profile: "gone"},
+        {Match: MatchSource, Pattern: "slack", Profile: "work"},
+        {Match: MatchDomain, Pattern: "*.corp.example", Profile: "work"},
+        {Match: MatchRegex, Pattern: `^https://github\.com/`, Profile: "home"},
+        {Match: MatchDomain, Pattern: "*.example", Profile: "home"},
+    }
+
+    tests := []struct {
+        name     string
+        router   *Router
+        configs  []*ChromeConfig
+        url      string
+        source   string
+        want     *ChromeConfig
+        wantRule int
+        chooser  bool
+    }{
+        {"domain rule", &Router{Rules: rules}, configs, "https://wiki.corp.example", "", work, 2, false},
+        {"first match wins", &Router{Rules: rules}, configs, "https://corp.example", "", work, 2, false},
+        {"source rule before domain", &Router{Rules: rules}, configs, "https://github.com/x", "slack", work, 1, false},
+        {"regex rule", &Router{Rules: rules}, configs, "https://github.com/x", "", home, 3, false},
+        {"deleted profile skipped", &Router{Rules: rules}, configs, "https://a.deleted.example", "", home, 4, false},
+        {"no match uses default", &Router{Rules: rules}, configs, "https://other.org", "", defaultCfg, -1, false},
+        {"no match uses fallback", &Router{Rules: rules, Fallback: "work"}, configs, "https://other.org", "", work, -1, false},
+        {"deleted fallback uses default", &Router{Rules: rules, Fallback: "gone"}, configs, "https://other.org", "", defaultCfg, -1, false},
+        {"unparseable url uses fallback", &Router{Rules: rules, Fallback: "home"}, configs, "http://[::1", "", home, -1, false},
+        {"no default instance", &Router{}, []*ChromeConfig{work}, "https://other.org", "", nil, -1, false},
+        {"chooser without match", &Router{Rules: rules, Chooser: true}, configs, "https://other.org", "", defaultCfg, -1, true},
+        {"chooser with match", &Router{Rules: rules, Chooser: true}, configs, "https://github.com/x", "", home, 3, false},
+        {"empty router", &Router{}, configs, "https://example.com", "", defaultCfg, -1, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, rule := tt.router.Route(tt.configs, tt.url, tt.source)
+            if got != tt.want || rule != tt.wantRule {
+                t.Errorf("Route(%s, %q) = %v, %d; want %v, %d", tt.url, tt.source, got, rule, tt.want, tt.wantRule)
+            }
+            if chooser := tt.router.NeedsChooser(rule); chooser != tt.chooser {
+                t.Errorf("NeedsChooser(%d) = %v, want %v", rule, chooser, tt.chooser)
+            }
+        })
+    }
+}
+
+func TestValidateRouter(t *testing.T) {
+    valid := &Router{Rules: []*RouteRule{
+        {Match: MatchDomain, Pattern: "*.example.com", Profile: "a"},
+        {Match: MatchRegex, Pattern: `^https://`, Profile: "a"},
+        {Match: MatchSource, Pattern: "slack", Profile: "a"},
+    }}
+    if err := ValidateRouter(valid); err != nil {
+        t.Errorf("valid router rejected: %v", err)
+    }
+    for _, rule := range []*RouteRule{
+        {Match: MatchDomain, Pattern: " ", Profile: "a"},
+        {Match: MatchDomain, Pattern: "example.com"},
+        {Match: MatchDomain, Pattern: "[", Profile: "a"},
+        {Match: MatchRegex, Pattern: "(", Profile: "a"},
+        {Match: "path", Pattern: "*", Profile: "a"},
+    } {
+        if err := ValidateRouter(&Router{Rules: []*RouteRule{rule}}); err == nil {
+            t.Errorf("invalid rule accepted: %+v", rule)
+        }
+    }
+}
+
+func TestCompileRegexCachesResult(t *testing.T) {
+    first, err := compileRegex(`^https://cache\.example/`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    second, _ := compileRegex(`^https://cache\.example/`)
+    if first != second {
+        t.Error("pattern compiled twice")
+    }
+    if _, err := compileRegex("("); err == nil {
+        t.Error("invalid pattern compiled")
+    }
+    if _, err := compileRegex("("); err == nil {
+        t.Error("cached error lost")
+    }
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+)
+
+// CurrentSchemaVersion 是当前程序写入的配置文件格式版本，必须等于 len(migrations)。
+//
+// 版本历史：
+//   - 0：顶层为 `[{name, user_data_dir, ...}]` 数组，全局设置单独存放在 settings.json 中；
+//   - 1：顶层为 `{version, settings, profiles}` 文档，全局设置并入配置文件；
+//   - 2：每个配置项带有不可变的 `id`。
+const CurrentSchemaVersion = 2
+
+// ErrNewerSchema 表示配置文件由更新版本的程序写入。
+// 此时配置以只读方式加载，所有保存操作都会被拒绝，以免降级丢失新字段。
+var ErrNewerSchema = errors.New("config file was written by a newer version of chromes")
+
+// Document 是配置文件的顶层结构。
+type Document struct {
+    Version  int             `json:"version"`  // 配置文件格式版本
+    Settings *Settings       `json:"settings"` // 全局设置
+    Profiles []*ChromeConfig `json:"profiles"` // 用户配置项，不包含默认实例
+}
+
+// rawDocument 是迁移过程中使用的未解析文档，键为顶层字段名。
+type rawDocument map[string]json.RawMessage
+
+// migrations[i] 将版本 i 的文档升级到版本 i+1，依次执行即可从任意旧版本升级到当前版本。
+var migrations = []func(doc rawDocument) error{
+    migrateV0ToV1,
+    migrateV1ToV2,
+}
+
+// migrateV0ToV1 将旧的数组格式升级为带版本号的文档。
+// 调用前数组已被放入 "profiles" 字段，旧的 settings.json 内容（如有）已被放入 "settings" 字段。
+func migrateV0ToV1(doc rawDocument) error {
+    if _, ok := doc["profiles"]; !ok {
+        doc["profiles"] = json.RawMessage("[]")
+    }
+    if _, ok := doc["settings"]; !ok {
+        doc["settings"] = json.RawMessage("{}")
+    }
+    return nil
+}
+
+// migrateV1ToV2 为每个没有 ID 的配置项生成 ID。
+func migrateV1ToV2(doc rawDocument) error {
+    var profiles []map[string]json.RawMessage
+    if err := json.Unmarshal(doc["profiles"], &profiles); err != nil {
+        return err
+    }
+    for _, profile := range profiles {
+        var id string
+        if data, ok := profile["id"]; ok {
+            _ = json.Unmarshal(data, &id)
+        }
+        if id == "" {
+            profile["id"], _ = json.Marshal(NewConfigID())
+        }
+    }
+    data, err := json.Marshal(profiles)
+    if err != nil {
+        return err
+    }
+    doc["profiles"] = data
+    return nil
+}
+
+// ensureProfileIDs 为缺少 ID 或 ID 重复的配置项（例如手动编辑过的文件）重新生成 ID。
+// 返回是否修改了文档。
+func ensureProfileIDs(doc *Document) bool {
+    changed := false
+    seen := make(map[string]bool)
+    for _, profile := range doc.Profiles {
+        if profile.ID == "" || profile.ID == DefaultChromeConfigID || seen[profile.ID] {
+            old := profile.ID
+            profile.ID = NewConfigID()
+            log.Printf("[load] backfilled id. name=%v, old=%q, new=%v", profile.Name, old, profile.ID)
+            changed = true
+        }
+        seen[profile.ID] = true
+    }
+    return changed
+}
+
+// decodeDocument 解析配置文件内容，识别其格式版本并依次执行迁移，返回当前版本的文档和文件原本的版本。
+// legacySettings 是旧版 settings.json 的内容，仅在升级版本 0 的文档时使用，可以为 nil。
+// 文件版本高于 CurrentSchemaVersion 时尽量解析已知字段，并返回包装了 ErrNewerSchema 的错误。
+func decodeDocument(data []byte, legacySettings []byte) (*Document, int, error) {
+    var raw rawDocument
+    version := 0
+
+    trimmed := bytes.TrimSpace(data)
+    if bytes.HasPrefix(trimmed, []byte("[")) {
+        // 版本 0：顶层为配置数组
+        if !json.Valid(trimmed) {
+            return nil, 0, fmt.Errorf("invalid legacy config array")
+        }
+        raw = rawDocument{"profiles": json.RawMessage(trimmed)}
+    } else {
+        if err := json.Unmarshal(trimmed, &raw); err != nil {
+            return nil, 0, err
+        }
+        versionData, ok := raw["version"]
+        if !ok {
+            return nil, 0, fmt.Errorf("config document has no schema version")
+        }
+        if err := json.Unmarshal(versionData, &version); err != nil {
+            return nil, 0, fmt.Errorf("invalid schema version: %w", err)
+        }
+        if version < 1 {
+            return nil, version, fmt.Errorf("invalid schema version %d", version)
+        }
+    }
+
+    if version > CurrentSchemaVersion {
+        doc, err := unmarshalDocument(raw)
+        if err != nil {
+            return nil, version, fmt.Errorf("%w (version %d): %v", ErrNewerSchema, version, err)
+        }
+        return doc, version, fmt.Errorf("%w (file version %d, supported version %d)", ErrNewerSchema, version, CurrentSchemaVersion)
+    }
+
+    if version == 0 && legacySettings != nil {
+        raw["settings"] = json.RawMessage(legacySettings)
+    }
+    for v := version; v < CurrentSchemaVersion; v++ {
+        if err := migrations[v](raw); err != nil {
+            return nil, version, fmt.Errorf("failed to migrate config from version %d to %d: %w", v, v+1, err)
+        }
+        log.Printf("[load] migrated. from=%d, to=%d", v, v+1)
+    }
+    raw["version"] = json.RawMessage(fmt.Sprint(CurrentSchemaVersion))
+    doc, err := unmarshalDocument(raw)
+    return doc, version, err
+}
+
+// unmarshalDocument 将迁移后的文档解析为 Document，并补全缺省字段。
+func unmarshalDocument(raw rawDocument) (*Document, error) {
+    data, err := json.Marshal(raw)
+    if err != nil {
+        return nil, err
+    }
+    doc := &Document{}
+    if err := json.Unmarshal(data, doc); err != nil {
+        return nil, err
+    }
+    if doc.Settings == nil {
+        doc.Settings = &Settings{}
+    }
+    return doc, nil
+}
+
+// readLegacySettings 读取版本 0 时单独存放的 settings.json，文件不存在时返回 nil。
+func readLegacySettings() ([]byte, error) {
+    data, err := os.ReadFile(legacySettingsFile)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil, nil
+        }
+        return nil, err
+    }
+    if !json.Valid(data) {
+        log.Printf("[load] legacy settings ignored, invalid json. path=%v", legacySettingsFile)
+        return nil, nil
+    }
+    return data, nil
+}
+
+// saveBlocked 记录最近一次加载时导致配置文件不能被覆盖的错误：
+// 文件无法读取（且未能隔离），或者由更新版本的程序写入。SaveConfigs 和 SaveSettings 会拒绝写入。
+var saveBlocked error
+
+// loadDocument 读取并迁移配置文件。
+// 文件不存在时返回一个空的当前版本文档。解析失败的文件会被隔离并返回 *LoadError；
+// 无法读取或版本过新时会设置 saveBlocked，后者仍会返回尽量解析出的文档。
+// 文件经过迁移或补全了配置 ID 时会立即写回，保证生成的 ID 在之后的加载中保持不变。
+func loadDocument() (*Document, error) {
+    data, err := os.ReadFile(configFile)
+    exists := err == nil
+    if err != nil && !os.IsNotExist(err) {
+        log.Printf("[load] read failed. path=%v, err=%v", configFile, err)
+        saveBlocked = &LoadError{Path: configFile, Err: err}
+        return nil, saveBlocked
+    }
+    if os.IsNotExist(err) {
+        data = []byte("[]") // 视为空的版本 0 文件，以便合并旧的 settings.json
+    } else {
+        log.Printf("[load] read success. path=%v, size=%d bytes", configFile, len(data))
+    }
+
+    legacySettings, err := readLegacySettings()
+    if err != nil {
+        log.Printf("[load] legacy settings read failed. path=%v, err=%v", legacySettingsFile, err)
+    }
+
+    doc, fileVersion, err := decodeDocument(data, legacySettings)
+    if errors.Is(err, ErrNewerSchema) {
+        log.Printf("[load] read-only. path=%v, err=%v", configFile, err)
+        saveBlocked = &LoadError{Path: configFile, Err: err}
+        return doc, saveBlocked
+    }
+    if err != nil {
+        log.Printf("[load] json failed. path=%v, err=%v", configFile, err)
+        loadErr := &LoadError{Path: configFile, Err: err}
+        if loadErr.QuarantinePath, err = quarantineFile(configFile); err != nil {
+            // 无法移走损坏的文件时，禁止保存以免覆盖它
+            log.Printf("[load] quarantine failed. path=%v, err=%v", configFile, err)
+            saveBlocked = loadErr
+        } else {
+            saveBlocked = nil
+        }
+        return nil, loadErr
+    }
+    saveBlocked = nil
+
+    backfilled := ensureProfileIDs(doc)
+    migrated := fileVersion < CurrentSchemaVersion && (exists || legacySettings != nil)
+    if backfilled || migrated {
+        if err := writeDocument(doc); err != nil {
+            log.Printf("[load] write back failed. path=%v, err=%v", configFile, err)
+        }
+    }
+    return doc, nil
+}
+
+// updateDocument 读取当前的配置文件，用 update 修改后原子地写回。
+// 上一次加载失败且文件不能被覆盖时，直接返回错误。
+func updateDocument(update func(doc *Document)) error {
+    if saveBlocked != nil {
+        return fmt.Errorf("refusing to overwrite configs that could not be loaded: %w", saveBlocked)
+    }
+    doc, err := loadDocument()
+    if err != nil {
+        return fmt.Errorf("refusing to overwrite configs that could not be loaded: %w", err)
+    }
+    update(doc)
+    return writeDocument(doc)
+}
+
+// writeDocument 将文档以当前版本原子地写入配置文件。
+func writeDocument(doc *Document) error {
+    doc.Version = CurrentSchemaVersion
+    data, err := json.MarshalIndent(doc, "", "  ")
+    if err != nil {
+        return err
+    }
+    if err := writeConfigFile(data); err != nil {
+        return err
+    }
+
+    // 旧的 settings.json 已经并入配置文件，改名保留以免再次被合并
+    if err := os.Rename(legacySettingsFile, legacySettingsFile+".migrated"); err != nil && !os.IsNotExist(err) {
+        log.Printf("[save] rename legacy settings failed. path=%v, err=%v", legacySettingsFile, err)
+    }
+    return nil
+}
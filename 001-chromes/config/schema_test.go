@@ -0,0 +1,276 @@
+package config
+
+import (
+    "encoding/json"
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// useTempConfigDir 把配置文件、旧的 settings.json 和备份目录指向临时目录，测试结束后恢复。
+func useTempConfigDir(t *testing.T) string {
+    t.Helper()
+    dir := t.TempDir()
+    oldConfigFile, oldLegacy, oldBackupDir, oldBlocked := configFile, legacySettingsFile, backupDir, saveBlocked
+    configFile = filepath.Join(dir, "configs.json")
+    legacySettingsFile = filepath.Join(dir, "settings.json")
+    backupDir = filepath.Join(dir, "backups")
+    saveBlocked = nil
+    t.Cleanup(func() {
+        configFile, legacySettingsFile, backupDir, saveBlocked = oldConfigFile, oldLegacy, oldBackupDir, oldBlocked
+    })
+    return dir
+}
+
+func writeTestFile(t *testing.T, path string, data string) {
+    t.Helper()
+    if err := os.WriteFile(path, []byte(data), 0640); err != nil {
+        t.Fatal(err)
+    }
+}
+
+// readTestDocument 读取配置文件的原始内容，返回版本号和各配置项的 ID。
+func readTestDocument(t *testing.T) (int, []string) {
+    t.Helper()
+    data, err := os.ReadFile(configFile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var doc Document
+    if err := json.Unmarshal(data, &doc); err != nil {
+        t.Fatalf("config file is not a document: %v\n%s", err, data)
+    }
+    var ids []string
+    for _, profile := range doc.Profiles {
+        ids = append(ids, profile.ID)
+    }
+    return doc.Version, ids
+}
+
+func TestMigrateV0ToV1FillsMissingFields(t *testing.T) {
+    doc := rawDocument{}
+    if err := migrateV0ToV1(doc); err != nil {
+        t.Fatal(err)
+    }
+    if string(doc["profiles"]) != "[]" || string(doc["settings"]) != "{}" {
+        t.Fatalf("unexpected document: profiles=%s, settings=%s", doc["profiles"], doc["settings"])
+    }
+
+    doc = rawDocument{"profiles": json.RawMessage(`[{"name":"a"}]`), "settings": json.RawMessage(`{"stop_timeout":5}`)}
+    if err := migrateV0ToV1(doc); err != nil {
+        t.Fatal(err)
+    }
+    if string(doc["profiles"]) != `[{"name":"a"}]` || string(doc["settings"]) != `{"stop_timeout":5}` {
+        t.Fatalf("existing fields changed: profiles=%s, settings=%s", doc["profiles"], doc["settings"])
+    }
+}
+
+func TestMigrateV1ToV2KeepsExistingIDs(t *testing.T) {
+    doc := rawDocument{"profiles": json.RawMessage(`[{"id":"keep","name":"a"},{"name":"b"},{"id":"","name":"c"}]`)}
+    if err := migrateV1ToV2(doc); err != nil {
+        t.Fatal(err)
+    }
+    var profiles []ChromeConfig
+    if err := json.Unmarshal(doc["profiles"], &profiles); err != nil {
+        t.Fatal(err)
+    }
+    if len(profiles) != 3 {
+        t.Fatalf("got %d profiles, want 3", len(profiles))
+    }
+    if profiles[0].ID != "keep" {
+        t.Errorf("existing id replaced: got %q", profiles[0].ID)
+    }
+    if profiles[1].ID == "" || profiles[2].ID == "" || profiles[1].ID == profiles[2].ID {
+        t.Errorf("ids not backfilled: %q, %q", profiles[1].ID, profiles[2].ID)
+    }
+    if profiles[1].Name != "b" || profiles[2].Name != "c" {
+        t.Errorf("other fields changed: %+v", profiles)
+    }
+}
+
+func TestMigrateV1ToV2RejectsInvalidProfiles(t *testing.T) {
+    doc := rawDocument{"profiles": json.RawMessage(`{"name":"a"}`)}
+    if err := migrateV1ToV2(doc); err == nil {
+        t.Fatal("expected an error for a non-array profiles field")
+    }
+}
+
+func TestDecodeDocumentMergesLegacySettings(t *testing.T) {
+    data := []byte(`[{"name":"work","user_data_dir":"/p/work"}]`)
+    doc, version, err := decodeDocument(data, []byte(`{"default_browser":"legacy","default_flags":["--lang=en"]}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if version != 0 {
+        t.Errorf("got file version %d, want 0", version)
+    }
+    if doc.Settings.DefaultBrowser != "legacy" || len(doc.Settings.DefaultFlags) != 1 {
+        t.Errorf("legacy settings not merged: %+v", doc.Settings)
+    }
+    if len(doc.Profiles) != 1 || doc.Profiles[0].Name != "work" || doc.Profiles[0].ID == "" {
+        t.Errorf("unexpected profiles: %+v", doc.Profiles)
+    }
+}
+
+func TestDecodeDocumentIgnoresLegacySettingsForDocuments(t *testing.T) {
+    data := []byte(`{"version":1,"settings":{"default_browser":"doc"},"profiles":[]}`)
+    doc, version, err := decodeDocument(data, []byte(`{"default_browser":"legacy"}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if version != 1 || doc.Settings.DefaultBrowser != "doc" {
+        t.Errorf("got version %d, settings %+v", version, doc.Settings)
+    }
+}
+
+func TestDecodeDocumentErrors(t *testing.T) {
+    for _, data := range []string{
+        `[{"name":`,
+        `{"profiles":[]}`,
+        `{"version":"two"}`,
+        `{"version":0,"profiles":[]}`,
+        `not json`,
+    } {
+        if _, _, err := decodeDocument([]byte(data), nil); err == nil || errors.Is(err, ErrNewerSchema) {
+            t.Errorf("decodeDocument(%s): got err=%v, want a parse error", data, err)
+        }
+    }
+}
+
+func TestLoadDocumentMigratesLegacyFiles(t *testing.T) {
+    dir := useTempConfigDir(t)
+    writeTestFile(t, configFile, `[{"name":"work","user_data_dir":"/p/work"},{"id":"keep","name":"home","user_data_dir":"/p/home"}]`)
+    writeTestFile(t, legacySettingsFile, `{"default_browser":"legacy"}`)
+
+    doc, err := loadDocument()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if doc.Settings.DefaultBrowser != "legacy" {
+        t.Errorf("legacy settings not merged: %+v", doc.Settings)
+    }
+    if len(doc.Profiles) != 2 || doc.Profiles[0].ID == "" || doc.Profiles[1].ID != "keep" {
+        t.Fatalf("unexpected profiles: %+v", doc.Profiles)
+    }
+
+    // 迁移后的文档立即写回，旧的 settings.json 改名保留
+    version, ids := readTestDocument(t)
+    if version != CurrentSchemaVersion {
+        t.Errorf("written version %d, want %d", version, CurrentSchemaVersion)
+    }
+    if len(ids) != 2 || ids[0] != doc.Profiles[0].ID || ids[1] != "keep" {
+        t.Errorf("written ids %v do not match loaded ids", ids)
+    }
+    if _, err := os.Stat(legacySettingsFile); !os.IsNotExist(err) {
+        t.Errorf("legacy settings still present: err=%v", err)
+    }
+    if _, err := os.Stat(filepath.Join(dir, "settings.json.migrated")); err != nil {
+        t.Errorf("legacy settings not kept: %v", err)
+    }
+
+    // 再次加载时生成的 ID 保持不变，设置不会丢失
+    again, err := loadDocument()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if again.Profiles[0].ID != doc.Profiles[0].ID || again.Settings.DefaultBrowser != "legacy" {
+        t.Errorf("reload changed the document: %+v, settings %+v", again.Profiles[0], again.Settings)
+    }
+}
+
+func TestLoadDocumentWithoutFiles(t *testing.T) {
+    useTempConfigDir(t)
+    doc, err := loadDocument()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(doc.Profiles) != 0 || doc.Settings == nil {
+        t.Errorf("unexpected document: %+v", doc)
+    }
+    if _, err := os.Stat(configFile); !os.IsNotExist(err) {
+        t.Errorf("config file created without anything to migrate: err=%v", err)
+    }
+}
+
+func TestLoadDocumentBackfillsDuplicateIDs(t *testing.T) {
+    useTempConfigDir(t)
+    writeTestFile(t, configFile, `{"version":2,"settings":{},"profiles":[{"id":"same","name":"a","user_data_dir":"/p/a"},{"id":"same","name":"b","user_data_dir":"/p/b"}]}`)
+
+    doc, err := loadDocument()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if doc.Profiles[0].ID != "same" || doc.Profiles[1].ID == "same" || doc.Profiles[1].ID == "" {
+        t.Errorf("unexpected ids: %q, %q", doc.Profiles[0].ID, doc.Profiles[1].ID)
+    }
+    if _, ids := readTestDocument(t); len(ids) != 2 || ids[1] != doc.Profiles[1].ID {
+        t.Errorf("backfilled ids not written back: %v", ids)
+    }
+}
+
+func TestLoadDocumentNewerSchemaIsReadOnly(t *testing.T) {
+    useTempConfigDir(t)
+    original := `{"version":99,"settings":{"default_browser":"future"},"profiles":[{"id":"x","name":"work","user_data_dir":"/p/work"}],"future":true}`
+    writeTestFile(t, configFile, original)
+
+    doc, err := loadDocument()
+    if !errors.Is(err, ErrNewerSchema) {
+        t.Fatalf("got err=%v, want ErrNewerSchema", err)
+    }
+    if doc == nil || len(doc.Profiles) != 1 || doc.Profiles[0].ID != "x" || doc.Settings.DefaultBrowser != "future" {
+        t.Fatalf("known fields not loaded: %+v", doc)
+    }
+
+    configs, err := LoadConfigs()
+    if !errors.Is(err, ErrNewerSchema) || len(configs) != 2 {
+        t.Fatalf("LoadConfigs: got %d configs, err=%v", len(configs), err)
+    }
+    if err := SaveConfigs(configs); !errors.Is(err, ErrNewerSchema) {
+        t.Errorf("SaveConfigs: got err=%v, want ErrNewerSchema", err)
+    }
+    if err := SaveSettings(&Settings{}); !errors.Is(err, ErrNewerSchema) {
+        t.Errorf("SaveSettings: got err=%v, want ErrNewerSchema", err)
+    }
+    data, err := os.ReadFile(configFile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(data) != original {
+        t.Errorf("newer config file was modified:\n%s", data)
+    }
+}
+
+func TestLoadDocumentQuarantinesUnparseableFile(t *testing.T) {
+    dir := useTempConfigDir(t)
+    writeTestFile(t, configFile, `{"version":2,"profiles":[`)
+
+    doc, err := loadDocument()
+    if doc != nil {
+        t.Errorf("got a document from an unparseable file: %+v", doc)
+    }
+    var loadErr *LoadError
+    if !errors.As(err, &loadErr) {
+        t.Fatalf("got err=%v, want *LoadError", err)
+    }
+    if loadErr.QuarantinePath == "" || !strings.HasPrefix(filepath.Base(loadErr.QuarantinePath), "configs.json.corrupt-") {
+        t.Fatalf("unexpected quarantine path %q", loadErr.QuarantinePath)
+    }
+    data, err := os.ReadFile(loadErr.QuarantinePath)
+    if err != nil || string(data) != `{"version":2,"profiles":[` {
+        t.Errorf("quarantined file: %q, err=%v", data, err)
+    }
+    if _, err := os.Stat(configFile); !os.IsNotExist(err) {
+        t.Errorf("unparseable file left in place: err=%v", err)
+    }
+
+    // 隔离成功后可以重新保存
+    cfg := &ChromeConfig{ID: NewConfigID(), Name: "work", UserDataDir: filepath.Join(dir, "work")}
+    if err := SaveConfigs([]*ChromeConfig{cfg}); err != nil {
+        t.Fatalf("save after quarantine: %v", err)
+    }
+    if _, ids := readTestDocument(t); len(ids) != 1 || ids[0] != cfg.ID {
+        t.Errorf("saved ids %v", ids)
+    }
+}
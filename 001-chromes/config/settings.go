@@ -0,0 +1,189 @@
+package config
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "slices"
+    "strings"
+    "time"
+)
+
+// DefaultStopTimeout 是未设置 Settings.StopTimeout 时，停止实例时等待浏览器自行退出的时间，超过后强制结束。
+const DefaultStopTimeout = 10 * time.Second
+
+// DefaultReconcileInterval 是未设置 Settings.ReconcileInterval 时，后台重新检测所有实例运行状态的间隔。
+const DefaultReconcileInterval = 5 * time.Second
+
+// Browser 描述一个可用于启动配置的浏览器安装（Chrome、Chromium、Brave、Edge 等）。
+// 自动发现的安装由 chrome 包在运行时生成，手动注册的安装会作为全局设置持久化到配置文件中。
+type Browser struct {
+    ID         string `json:"id"`   // 安装的唯一标识，ChromeConfig.Browser 通过它引用
+    Name       string `json:"name"` // 用于界面显示的名称
+    Path       string `json:"path"` // 可执行文件路径
+    Discovered bool   `json:"-"`    // 标记是否为自动发现的安装，不序列化到json
+}
+
+// Settings 存储与具体配置项无关的全局设置。
+type Settings struct {
+    DefaultBrowser    string     `json:"default_browser,omitempty"`    // 全局默认浏览器的 ID，为空时使用第一个可用的安装
+    DefaultFlags      []string   `json:"default_flags,omitempty"`      // 所有配置继承的默认启动参数
+    Browsers          []*Browser `json:"browsers,omitempty"`           // 用户手动注册的浏览器安装
+    StopTimeout       int        `json:"stop_timeout,omitempty"`       // 停止实例时等待浏览器退出的秒数，为 0 时使用 DefaultStopTimeout
+    ReconcileInterval int        `json:"reconcile_interval,omitempty"` // 后台重新检测实例运行状态的秒数间隔，为 0 时使用 DefaultReconcileInterval
+    Router            *Router    `json:"router,omitempty"`             // 链接路由，把打开的网址按规则分配给配置，见 router.go
+}
+
+// StopDeadline 返回停止实例时等待浏览器自行退出的时间。
+func (s *Settings) StopDeadline() time.Duration {
+    if s.StopTimeout > 0 {
+        return time.Duration(s.StopTimeout) * time.Second
+    }
+    return DefaultStopTimeout
+}
+
+// ReconcilePeriod 返回后台重新检测实例运行状态的间隔。
+func (s *Settings) ReconcilePeriod() time.Duration {
+    if s.ReconcileInterval > 0 {
+        return time.Duration(s.ReconcileInterval) * time.Second
+    }
+    return DefaultReconcileInterval
+}
+
+// Clone 返回设置的深拷贝，修改副本不会影响原设置。
+func (s *Settings) Clone() *Settings {
+    clone := *s
+    clone.DefaultFlags = slices.Clone(s.DefaultFlags)
+    clone.Browsers = nil
+    for _, b := range s.Browsers {
+        copied := *b
+        clone.Browsers = append(clone.Browsers, &copied)
+    }
+    if s.Router != nil {
+        router := *s.Router
+        router.Rules = nil
+        for _, rule := range s.Router.Rules {
+            copied := *rule
+            router.Rules = append(router.Rules, &copied)
+        }
+        clone.Router = &router
+    }
+    return &clone
+}
+
+// legacySettingsFile 是版本 0 时单独存放全局设置的文件，加载时会被合并进配置文件。
+var legacySettingsFile = filepath.Join(Dir(), "settings.json")
+
+// LoadSettings 从配置文件中加载全局设置。
+// 加载失败时返回空设置以保证程序基本可用，并返回与 LoadConfigs 相同的错误。
+func LoadSettings() (*Settings, error) {
+    doc, err := loadDocument()
+    if doc == nil {
+        return &Settings{}, err
+    }
+    return doc.Settings, err
+}
+
+// SaveSettings 将全局设置保存到配置文件中，配置列表保持不变。
+func SaveSettings(settings *Settings) error {
+    return updateDocument(func(doc *Document) {
+        doc.Settings = settings
+    })
+}
+
+// FindBrowser 在手动注册的浏览器中按 ID 查找。
+func (s *Settings) FindBrowser(id string) *Browser {
+    for _, b := range s.Browsers {
+        if b.ID == id {
+            return b
+        }
+    }
+    return nil
+}
+
+// AddBrowser 手动注册一个浏览器安装，并保存设置。
+// 会检查 ID 是否重复以及可执行文件是否存在。
+func AddBrowser(settings *Settings, id, name, path string) error {
+    id = strings.TrimSpace(id)
+    if id == "" {
+        return fmt.Errorf("browser id cannot be empty")
+    }
+    if strings.TrimSpace(path) == "" {
+        return fmt.Errorf("browser executable path cannot be empty")
+    }
+    if settings.FindBrowser(id) != nil {
+        return fmt.Errorf("browser id '%s' already exists", id)
+    }
+    absPath, err := filepath.Abs(path)
+    if err != nil {
+        return fmt.Errorf("failed to get absolute path for %s: %w", path, err)
+    }
+    info, err := os.Stat(absPath)
+    if err != nil {
+        return fmt.Errorf("browser executable '%s' is not accessible: %w", absPath, err)
+    }
+    if info.IsDir() {
+        return fmt.Errorf("browser executable '%s' is a directory", absPath)
+    }
+    if strings.TrimSpace(name) == "" {
+        name = id
+    }
+
+    settings.Browsers = append(settings.Browsers, &Browser{ID: id, Name: name, Path: absPath})
+    if err := SaveSettings(settings); err != nil {
+        settings.Browsers = settings.Browsers[:len(settings.Browsers)-1]
+        return fmt.Errorf("failed to save settings after adding browser: %w", err)
+    }
+    return nil
+}
+
+// RemoveBrowser 删除一个手动注册的浏览器安装，并保存设置。
+// 如果它是全局默认浏览器，默认值会被清空。
+func RemoveBrowser(settings *Settings, id string) error {
+    updated := make([]*Browser, 0, len(settings.Browsers))
+    for _, b := range settings.Browsers {
+        if b.ID != id {
+            updated = append(updated, b)
+        }
+    }
+    if len(updated) == len(settings.Browsers) {
+        return fmt.Errorf("browser id '%s' not found", id)
+    }
+
+    old, oldDefault := settings.Browsers, settings.DefaultBrowser
+    settings.Browsers = updated
+    if settings.DefaultBrowser == id {
+        settings.DefaultBrowser = ""
+    }
+    if err := SaveSettings(settings); err != nil {
+        settings.Browsers, settings.DefaultBrowser = old, oldDefault
+        return fmt.Errorf("failed to save settings after removing browser: %w", err)
+    }
+    return nil
+}
+
+// SetDefaultBrowser 设置全局默认浏览器并保存。id 为空表示自动选择。
+func SetDefaultBrowser(settings *Settings, id string) error {
+    old := settings.DefaultBrowser
+    settings.DefaultBrowser = id
+    if err := SaveSettings(settings); err != nil {
+        settings.DefaultBrowser = old
+        return fmt.Errorf("failed to save settings: %w", err)
+    }
+    return nil
+}
+
+// SetDefaultFlags 设置所有配置继承的默认启动参数并保存。
+// 参数会先经过 ValidateFlags 校验。
+func SetDefaultFlags(settings *Settings, flags []string) error {
+    if err := ValidateFlags(flags); err != nil {
+        return err
+    }
+    old := settings.DefaultFlags
+    settings.DefaultFlags = flags
+    if err := SaveSettings(settings); err != nil {
+        settings.DefaultFlags = old
+        return fmt.Errorf("failed to save settings: %w", err)
+    }
+    return nil
+}
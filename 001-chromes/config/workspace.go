@@ -0,0 +1,144 @@
+package config
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "slices"
+    "strings"
+    "time"
+)
+
+// Workspace 是一个配置中一组已命名的窗口和标签页，可以在之后重新打开。
+type Workspace struct {
+    Name    string            `json:"name"`    // 工作区名称，在同一配置内唯一
+    Created time.Time         `json:"created"` // 保存的时间
+    Windows []WorkspaceWindow `json:"windows"` // 窗口及其中的标签页
+}
+
+// WorkspaceWindow 是工作区中的一个浏览器窗口。
+type WorkspaceWindow struct {
+    Left   int            `json:"left"`
+    Top    int            `json:"top"`
+    Width  int            `json:"width"`
+    Height int            `json:"height"`
+    State  string         `json:"state,omitempty"` // 窗口状态："normal"、"minimized"、"maximized" 或 "fullscreen"
+    Tabs   []WorkspaceTab `json:"tabs"`
+}
+
+// WorkspaceTab 是工作区中的一个标签页。
+type WorkspaceTab struct {
+    URL   string `json:"url"`
+    Title string `json:"title,omitempty"` // 保存时的标题，只用于显示
+}
+
+// TabCount 返回工作区中所有窗口的标签页总数。
+func (w *Workspace) TabCount() int {
+    n := 0
+    for _, window := range w.Windows {
+        n += len(window.Tabs)
+    }
+    return n
+}
+
+// validateWorkspace 检查工作区是否有名称且至少包含一个标签页。
+func validateWorkspace(ws *Workspace) error {
+    if strings.TrimSpace(ws.Name) == "" {
+        return fmt.Errorf("workspace name cannot be empty")
+    }
+    if ws.TabCount() == 0 {
+        return fmt.Errorf("workspace '%s' has no tabs", ws.Name)
+    }
+    return nil
+}
+
+// SaveWorkspace 将工作区保存到指定 ID 的配置中，同名的工作区会被替换。
+// 默认实例不保存在配置文件中，因此不支持工作区。
+func SaveWorkspace(id string, ws *Workspace, currentConfigs []*ChromeConfig) ([]*ChromeConfig, error) {
+    if err := validateWorkspace(ws); err != nil {
+        return currentConfigs, err
+    }
+    return updateWorkspaces(id, currentConfigs, func(workspaces []*Workspace) ([]*Workspace, error) {
+        if i := slices.IndexFunc(workspaces, func(w *Workspace) bool { return w.Name == ws.Name }); i >= 0 {
+            workspaces[i] = ws
+            return workspaces, nil
+        }
+        return append(workspaces, ws), nil
+    })
+}
+
+// RemoveWorkspace 从指定 ID 的配置中删除名为 name 的工作区。
+func RemoveWorkspace(id string, name string, currentConfigs []*ChromeConfig) ([]*ChromeConfig, error) {
+    return updateWorkspaces(id, currentConfigs, func(workspaces []*Workspace) ([]*Workspace, error) {
+        i := slices.IndexFunc(workspaces, func(w *Workspace) bool { return w.Name == name })
+        if i < 0 {
+            return nil, fmt.Errorf("workspace '%s' not found", name)
+        }
+        return slices.Delete(workspaces, i, i+1), nil
+    })
+}
+
+// updateWorkspaces 用 update 修改指定 ID 的配置的工作区列表并保存。
+// 修改作用在配置的副本上，保存失败时 currentConfigs 保持不变。
+func updateWorkspaces(id string, currentConfigs []*ChromeConfig, update func([]*Workspace) ([]*Workspace, error)) ([]*ChromeConfig, error) {
+    if id == DefaultChromeConfigID {
+        return currentConfigs, fmt.Errorf("workspaces are not supported for the default Chrome instance")
+    }
+    index := slices.IndexFunc(currentConfigs, func(cfg *ChromeConfig) bool { return !cfg.IsDefault && cfg.ID == id })
+    if index < 0 {
+        return currentConfigs, fmt.Errorf("config id '%s' not found", id)
+    }
+
+    updated := *currentConfigs[index]
+    workspaces, err := update(slices.Clone(updated.Workspaces))
+    if err != nil {
+        return currentConfigs, err
+    }
+    updated.Workspaces = workspaces
+    updatedConfigs := slices.Clone(currentConfigs)
+    updatedConfigs[index] = &updated
+    if err := SaveConfigs(updatedConfigs); err != nil {
+        return currentConfigs, fmt.Errorf("failed to save configs after updating workspaces: %w", err)
+    }
+    return updatedConfigs, nil
+}
+
+// FindWorkspace 返回配置中名为 name 的工作区，未找到时返回 nil。
+func (c *ChromeConfig) FindWorkspace(name string) *Workspace {
+    for _, ws := range c.Workspaces {
+        if ws.Name == name {
+            return ws
+        }
+    }
+    return nil
+}
+
+// ExportWorkspace 将工作区导出为 JSON 文件。
+func ExportWorkspace(ws *Workspace, path string) error {
+    data, err := json.MarshalIndent(ws, "", "  ")
+    if err != nil {
+        return err
+    }
+    return WriteFileAtomic(path, data, 0644)
+}
+
+// ImportWorkspace 从 ExportWorkspace 导出的 JSON 文件读取工作区。
+func ImportWorkspace(path string) (*Workspace, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    return ParseWorkspace(data)
+}
+
+// ParseWorkspace 解析工作区的 JSON 表示并校验。
+func ParseWorkspace(data []byte) (*Workspace, error) {
+    ws := &Workspace{}
+    if err := json.Unmarshal(data, ws); err != nil {
+        return nil, fmt.Errorf("invalid workspace: %w", err)
+    }
+    if err := validateWorkspace(ws); err != nil {
+        return nil, err
+    }
+    return ws, nil
+}
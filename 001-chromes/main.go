@@ -1,8 +1,19 @@
 package main
 
 import (
+    "context"
+    "errors"
+    "fmt"
     "image/color"
+    "io"
     "log"
+    "os"
+    "os/exec"
+    "runtime"
+    "slices"
+    "strconv"
+    "strings"
+    "time"
 
     "fyne.io/fyne/v2"
     "fyne.io/fyne/v2/app" // ignore errors here, use CGO_ENABLED=1 for build
@@ -11,58 +22,113 @@ import (
     "fyne.io/fyne/v2/dialog"
     "fyne.io/fyne/v2/widget"
 
+    "chromes/api"
+    "chromes/cdp"
     "chromes/chrome"
+    "chromes/cli"
     "chromes/config"
+    "chromes/xdg"
 )
 
 func main() {
-    var instances []*chrome.Instance
+    // 带有子命令时以命令行方式运行，不创建窗口（见 cli 包）
+    if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+        cli.StartManager = startManager
+        os.Exit(cli.Run(os.Args[1:]))
+    }
+
+    // 每个用户只运行一个管理器，避免两个窗口同时改写配置文件、控制同一批浏览器。
+    // 已有管理器在运行时把它的窗口显示到前台后退出
+    lock, err := api.AcquireLock(api.LockPath())
+    for attempt := 1; errors.Is(err, api.ErrLocked); attempt++ {
+        showErr := showRunningManager()
+        if showErr == nil {
+            log.Printf("管理器已在运行，已将其窗口显示到前台")
+            os.Exit(0)
+        }
+        // 持有锁的只是正在检查管理器是否在运行的命令行（见 api.Connect），重新获取锁后继续启动
+        if !errors.Is(showErr, api.ErrNotRunning) || attempt >= lockAttempts {
+            log.Printf("管理器已在运行，但无法显示它的窗口: %v", showErr)
+            os.Exit(1)
+        }
+        lock, err = api.AcquireLock(api.LockPath())
+    }
+    if err != nil {
+        log.Printf("获取单实例锁失败，继续启动: %v", err)
+    } else {
+        defer lock.Release()
+    }
+
     var configs []*config.ChromeConfig // 用于跟踪原始配置，主要用于保存
 
+    settings, settingsErr := config.LoadSettings() // 全局设置，包含手动注册的浏览器和默认浏览器
+    registry := chrome.NewRegistry(settings)       // 浏览器安装注册表
+    manager := chrome.NewManager(registry)         // 实例管理器，配置重新加载后保留运行中实例的进程对象
+
     myApp := app.New()
     w := myApp.NewWindow("Chromes -- Chrome 多开管理器")
 
-    // 重新加载实例并刷新列表的辅助函数
-    reloadInstancesAndRefreshList := func(list *widget.List) {
-        configs = config.LoadConfigs() // 重新加载配置，包含默认实例
-        newInstances := make([]*chrome.Instance, len(configs))
-        for i, cfg := range configs {
-            instance := chrome.NewInstance(cfg)
-            newInstances[i] = instance
-            if i == 0 && cfg.IsDefault { // 对默认实例的特殊日志
-                log.Printf("启动检查: 默认实例 %s 状态: %v", cfg.Name, instance.IsRunning())
+    // 显示配置加载错误，加载失败时只有默认实例可用，提示用户可以从备份恢复
+    showLoadError := func(err error) {
+        log.Printf("加载配置失败: %v", err)
+        if errors.Is(err, config.ErrNewerSchema) {
+            dialog.ShowError(fmt.Errorf("%w\n\n配置以只读方式加载，请使用更新版本的程序修改配置", err), w)
+            return
+        }
+        dialog.ShowError(fmt.Errorf("%w\n\n可以通过“备份与恢复”从最近的备份恢复配置", err), w)
+    }
+
+    // 重新加载实例并刷新列表的辅助函数，返回配置加载错误（此时列表中仍包含能够加载的配置）
+    reloadInstancesAndRefreshList := func(list *widget.List) error {
+        var err error
+        configs, err = config.LoadConfigs() // 重新加载配置，包含默认实例
+        manager.Reconcile(configs)          // 保留已有实例，创建新增的，移除已删除的
+        if err == nil && xdg.Supported() {
+            syncLaunchers(configs) // 只加载了部分配置时不同步，以免删除其余配置的启动器
+        }
+        for _, instance := range manager.Instances() {
+            cfg := instance.Config()
+            if cfg.IsDefault { // 对默认实例的特殊日志
+                log.Printf("启动检查: 默认实例 %s 状态: %v", cfg, instance.IsRunning())
             } else {
-                log.Printf("启动检查: 配置 %s (dir: %s) 状态: %v", cfg.Name, cfg.UserDataDir, instance.IsRunning())
+                log.Printf("启动检查: 配置 %s (dir: %s) 状态: %v", cfg, cfg.UserDataDir, instance.IsRunning())
             }
         }
-        instances = newInstances
         if list != nil {
             list.Refresh()
         }
+        return err
     }
 
     var list *widget.List
     list = widget.NewList(
-        func() int { return len(instances) },
+        func() int { return manager.Len() },
         func() fyne.CanvasObject { // CreateItem
             nameLabel := widget.NewLabel("配置名称")
             pathLabel := widget.NewLabel("工作目录")
             pathLabel.Wrapping = fyne.TextWrapWord
             pathLabel.TextStyle.Italic = true
+            restartLabel := widget.NewLabel("")
+            restartLabel.Importance = widget.WarningImportance
+            restartLabel.Wrapping = fyne.TextWrapWord
             statusText := canvas.NewText("已停止", color.Gray{Y: 128})
             statusText.TextSize = 12
             actionButton := widget.NewButton("启动", nil)
+            logButton := widget.NewButton("日志", nil)
+            devtoolsButton := widget.NewButton("调试", nil)
+            workspaceButton := widget.NewButton("工作区", nil)
+            editButton := widget.NewButton("编辑", nil)
             removeButton := widget.NewButton("删除", nil)
 
-            controls := container.NewHBox(statusText, actionButton, removeButton)
-            return container.NewBorder(nil, nil, nil, controls, container.NewVBox(nameLabel, pathLabel))
+            controls := container.NewHBox(statusText, actionButton, logButton, devtoolsButton, workspaceButton, editButton, removeButton)
+            return container.NewBorder(nil, nil, nil, controls, container.NewVBox(nameLabel, pathLabel, restartLabel))
         },
         func(id widget.ListItemID, item fyne.CanvasObject) { // UpdateItem
-            if id >= len(instances) {
-                log.Printf("Error: UpdateItem called with invalid id %d, instances len %d", id, len(instances))
+            instance := manager.At(id)
+            if instance == nil {
+                log.Printf("Error: UpdateItem called with invalid id %d, instances len %d", id, manager.Len())
                 return // 防止越界
             }
-            instance := instances[id]
             cfg := instance.Config() // 获取配置信息
 
             borderLayout := item.(*fyne.Container)
@@ -71,99 +137,270 @@ func main() {
 
             nameLabel := contentVBox.Objects[0].(*widget.Label)
             pathLabel := contentVBox.Objects[1].(*widget.Label)
+            restartLabel := contentVBox.Objects[2].(*widget.Label)
             statusText := controlsHBox.Objects[0].(*canvas.Text)
             actionButton := controlsHBox.Objects[1].(*widget.Button)
-            removeButton := controlsHBox.Objects[2].(*widget.Button)
+            logButton := controlsHBox.Objects[2].(*widget.Button)
+            devtoolsButton := controlsHBox.Objects[3].(*widget.Button)
+            workspaceButton := controlsHBox.Objects[4].(*widget.Button)
+            editButton := controlsHBox.Objects[5].(*widget.Button)
+            removeButton := controlsHBox.Objects[6].(*widget.Button)
 
-            nameLabel.SetText(cfg.Name)
+            if browser, err := registry.Resolve(cfg); err == nil {
+                nameLabel.SetText(cfg.Name + " · " + browser.Name)
+            } else {
+                nameLabel.SetText(cfg.Name + " · (浏览器不可用)")
+            }
+            logButton.OnTapped = func() {
+                showLogViewer(w, instance)
+            }
+            // 开启了远程调试的实例在运行时可以查看调试端点
+            if cfg.DevTools && (instance.State() == chrome.StateRunning || instance.State() == chrome.StateUnknown) {
+                devtoolsButton.Show()
+                devtoolsButton.OnTapped = func() {
+                    showDevToolsDialog(w, instance)
+                }
+            } else {
+                devtoolsButton.Hide()
+            }
+            // 工作区通过远程调试读取和打开标签页，保存在配置文件中，默认实例不支持
+            if cfg.DevTools && !cfg.IsDefault {
+                workspaceButton.Show()
+                workspaceButton.OnTapped = func() {
+                    showWorkspacesDialog(w, instance, func() {
+                        if err := reloadInstancesAndRefreshList(list); err != nil {
+                            showLoadError(err)
+                        }
+                    })
+                }
+            } else {
+                workspaceButton.Hide()
+            }
             if cfg.IsDefault {
                 pathLabel.SetText("(默认路径)")
+                editButton.Hide()   // 默认实例不可编辑
                 removeButton.Hide() // 隐藏默认实例的删除按钮
             } else {
                 pathLabel.SetText(cfg.UserDataDir)
+                editButton.Show()
+                editButton.OnTapped = func() {
+                    showEditConfigDialog(w, registry, instance, func() {
+                        if err := reloadInstancesAndRefreshList(list); err != nil {
+                            showLoadError(err)
+                        }
+                    })
+                }
                 removeButton.Show() // 显示非默认实例的删除按钮
                 removeButton.OnTapped = func() {
                     dialog.ShowConfirm("确认删除", "确定要删除配置 \""+cfg.Name+"\"吗？", func(confirm bool) {
                         if confirm {
-                            log.Printf("请求删除配置: %s", cfg.Name)
-                            var err error
+                            log.Printf("请求删除配置: %s", cfg)
                             // configs 在 reloadInstancesAndRefreshList 中已经从磁盘加载了最新的
                             // 我们需要传递当前的 configs 列表给 RemoveConfig
-                            currentConfigsForRemove := config.LoadConfigs() // 获取包含默认项的当前配置列表
-                            updatedConfigs, err := config.RemoveConfig(cfg.Name, currentConfigsForRemove)
+                            currentConfigsForRemove, err := config.LoadConfigs() // 获取包含默认项的当前配置列表
+                            if err != nil {
+                                log.Printf("删除配置 %s 失败: %v", cfg, err)
+                                dialog.ShowError(err, w)
+                                return
+                            }
+                            updatedConfigs, err := config.RemoveConfig(cfg.ID, currentConfigsForRemove)
                             if err != nil {
-                                log.Printf("删除配置 %s 失败: %v", cfg.Name, err)
+                                log.Printf("删除配置 %s 失败: %v", cfg, err)
                                 dialog.ShowError(err, w)
                                 return
                             }
                             // RemoveConfig 内部已经调用了 SaveConfigs
-                            log.Printf("配置 %s 已删除", cfg.Name)
-                            configs = updatedConfigs            // 更新内存中的 configs 列表
-                            reloadInstancesAndRefreshList(list) // 重新加载并刷新UI
+                            log.Printf("配置 %s 已删除", cfg)
+                            configs = updatedConfigs                                    // 更新内存中的 configs 列表
+                            if err := reloadInstancesAndRefreshList(list); err != nil { // 重新加载并刷新UI
+                                showLoadError(err)
+                            }
                         }
                     }, w)
                 }
             }
 
-            if instance.IsRunning() {
-                statusText.Text = "运行中"
-                statusText.Color = color.NRGBA{G: 180, A: 255}
-                actionButton.SetText("停止")
-                actionButton.OnTapped = func() {
-                    log.Printf("请求停止实例: %s (dir: %s)", cfg.Name, cfg.UserDataDir)
-                    if err := instance.Stop(); err != nil {
-                        log.Printf("停止 %s 失败: %v", cfg.Name, err)
-                        dialog.ShowError(err, w)
-                    } else {
-                        log.Printf("已发送停止命令给: %s", cfg.Name)
+            stopAction := func() {
+                log.Printf("请求停止实例: %s (dir: %s)", cfg, cfg.UserDataDir)
+                // 停止在后台进行，结果通过 manager 的事件通知
+                if err := manager.Stop(cfg.ID); err != nil {
+                    log.Printf("停止 %s 失败: %v", cfg, err)
+                    dialog.ShowError(err, w)
+                } else {
+                    log.Printf("已发送停止命令给: %s", cfg)
+                }
+            }
+            startAction := func() {
+                log.Printf("请求启动实例: %s (dir: %s)", cfg, cfg.UserDataDir)
+                if instance.IsRunning() {
+                    log.Printf("实例 %s 已经在运行中", cfg)
+                    dialog.ShowInformation("提示", "实例已经在运行中", w)
+                    return
+                }
+
+                // 进程退出由实例自行检测，并通过 manager 的事件刷新对应的列表项
+                if err := manager.Start(cfg.ID); err != nil {
+                    log.Printf("启动 %s 失败: %v", cfg, err)
+                    var staleErr *chrome.StaleLockError
+                    if errors.As(err, &staleErr) {
+                        showCleanStaleLockDialog(w, manager, instance, staleErr.Lock)
+                        return
                     }
-                    // UI 更新将依赖 IsRunning() 的状态，并在 list.RefreshItem() 时刷新
-                    list.RefreshItem(id) // 立即刷新此项UI
+                    dialog.ShowError(err, w)
+                }
+            }
+
+            // 自动重启的次数和最近一次异常退出的原因
+            restart := instance.RestartStatus()
+            if restart.Count > 0 || restart.LastFailure != "" {
+                text := fmt.Sprintf("已自动重启 %d 次", restart.Count)
+                if restart.LastFailure != "" {
+                    text += fmt.Sprintf(" · 最近一次异常: %s (%s)", restart.LastFailure, restart.LastFailureTime.Format("01-02 15:04:05"))
+                }
+                if restart.GaveUp {
+                    text += " · 重启过于频繁，已停止自动重启"
                 }
+                restartLabel.SetText(text)
+                restartLabel.Show()
             } else {
+                restartLabel.Hide()
+            }
+
+            actionButton.Enable()
+            pidSuffix := ""
+            if pid := instance.PID(); pid > 0 {
+                pidSuffix = fmt.Sprintf(" (pid %d)", pid)
+            }
+            switch instance.State() {
+            case chrome.StateStarting:
+                // 等待进程启动，完成前不允许再次操作
+                statusText.Text = "启动中…"
+                statusText.Color = color.NRGBA{R: 200, G: 140, A: 255}
+                actionButton.SetText("启动")
+                actionButton.Disable()
+            case chrome.StateStopping:
+                // 已请求浏览器退出，确认所有进程退出前不允许再次操作
+                statusText.Text = "停止中…"
+                statusText.Color = color.NRGBA{R: 200, G: 140, A: 255}
+                actionButton.SetText("停止")
+                actionButton.Disable()
+            case chrome.StateRunning:
+                statusText.Text = "运行中" + pidSuffix
+                statusText.Color = color.NRGBA{G: 180, A: 255}
+                actionButton.SetText("停止")
+                actionButton.OnTapped = stopAction
+            case chrome.StateUnknown:
+                statusText.Text = "运行中（外部启动）" + pidSuffix
+                statusText.Color = color.NRGBA{G: 140, B: 120, A: 255}
+                actionButton.SetText("停止")
+                actionButton.OnTapped = stopAction
+            case chrome.StateCrashed:
+                statusText.Text = "已崩溃"
+                statusText.Color = color.NRGBA{R: 200, A: 255}
+                actionButton.SetText("启动")
+                actionButton.OnTapped = startAction
+            default:
                 statusText.Text = "已停止"
                 statusText.Color = color.Gray{Y: 128}
                 actionButton.SetText("启动")
+                actionButton.OnTapped = startAction
+            }
+            if restart.Pending() {
+                // 等待自动重启期间可以取消，取消后再手动启动
+                statusText.Text += " · " + restart.Next.Format("15:04:05") + " 重启"
+                actionButton.SetText("取消重启")
                 actionButton.OnTapped = func() {
-                    log.Printf("请求启动实例: %s (dir: %s)", cfg.Name, cfg.UserDataDir)
-                    if instance.IsRunning() {
-                        log.Printf("实例 %s 已经在运行中", cfg.Name)
-                        dialog.ShowInformation("提示", "实例已经在运行中", w)
-                        return
-                    }
-
-                    if err := instance.Start(); err != nil {
-                        log.Printf("启动 %s 失败: %v", cfg.Name, err)
-                        dialog.ShowError(err, w)
-                        return
-                    }
-                    list.RefreshItem(id) // 立即刷新此项UI
-
-                    go func(monitoredInstance *chrome.Instance, itemID widget.ListItemID) {
-                        log.Printf("等待 %s (dir: %s) 进程退出...", monitoredInstance.Config().Name, monitoredInstance.Config().UserDataDir)
-                        waitErr := monitoredInstance.Wait()
-                        log.Printf("进程 %s (dir: %s) 已退出", monitoredInstance.Config().Name, monitoredInstance.Config().UserDataDir)
-                        if waitErr != nil {
-                            log.Printf("等待 %s 进程出错: %v", monitoredInstance.Config().Name, waitErr)
-                        }
-
-                        fyne.Do(func() {
-                            list.RefreshItem(itemID)
-                        })
-                    }(instance, id)
+                    log.Printf("取消自动重启: %s", cfg)
+                    instance.CancelRestart()
+                    list.RefreshItem(id)
                 }
             }
             // 确保所有组件都刷新
             nameLabel.Refresh()
             pathLabel.Refresh()
+            restartLabel.Refresh()
             statusText.Refresh()
             actionButton.Refresh()
+            logButton.Refresh()
+            devtoolsButton.Refresh()
+            workspaceButton.Refresh()
+            editButton.Refresh()
             removeButton.Refresh()
         },
     )
 
-    // 初始加载
-    reloadInstancesAndRefreshList(list)
+    // 订阅所有实例的状态变化（启动、停止、进程退出等），按配置 ID 找到当前的列表位置并刷新，
+    // 配置列表在此期间发生变化也不会刷新到错误的行
+    events, _ := manager.Subscribe()
+    go func() {
+        for ev := range events {
+            fyne.Do(func() {
+                if index := manager.Index(ev.ID); index >= 0 {
+                    list.RefreshItem(index)
+                }
+                instance := manager.Get(ev.ID)
+                if ev.From == chrome.StateStopping && ev.To == chrome.StateStopped && instance != nil && instance.LastStop() == chrome.StopKilled {
+                    // 强制结束的浏览器没有机会保存会话，下次启动时可能提示未正常关闭
+                    dialog.ShowInformation("浏览器被强制结束", fmt.Sprintf("%s 没有在期限内退出，已被强制结束。\n下次启动时浏览器可能提示未正常关闭，部分会话状态可能丢失。", instance.Config().Name), w)
+                }
+                if ev.Err == nil {
+                    return
+                }
+                switch {
+                case ev.From == chrome.StateStopping:
+                    dialog.ShowError(ev.Err, w) // 停止失败
+                case ev.To == chrome.StateCrashed && instance != nil && instance.RestartStatus().Pending():
+                    // 将按重启策略自动重启，异常信息显示在列表项中，不打断用户
+                case ev.To == chrome.StateCrashed:
+                    dialog.ShowError(fmt.Errorf("%w\n\n退出码: %d", ev.Err, ev.ExitCode), w)
+                }
+            })
+        }
+    }()
+
+    // 初始加载。全局设置与配置保存在同一个文件中，损坏的文件在加载设置时已被隔离，
+    // 此时配置列表的加载不会再报错，因此需要单独显示设置的加载错误
+    if err := reloadInstancesAndRefreshList(list); err != nil {
+        showLoadError(err)
+    } else if settingsErr != nil {
+        showLoadError(settingsErr)
+    }
+
+    // 后台周期性地重新检测所有实例，从窗口菜单退出或从桌面快捷方式启动的浏览器也会通过事件反映到列表中
+    go manager.Run(context.Background(), settings.ReconcilePeriod())
+
+    // 本地控制接口，供启动器、编辑器插件和脚本在图形界面运行时控制实例（见 api 包）
+    if listener, err := api.Listen(api.SocketPath()); err != nil {
+        log.Printf("控制接口启动失败: %v", err)
+    } else {
+        defer listener.Close()
+        server := api.NewServer(manager, api.Hooks{
+            Changed: func() {
+                fyne.Do(func() {
+                    if err := reloadInstancesAndRefreshList(list); err != nil {
+                        showLoadError(err)
+                    }
+                })
+            },
+            ShowWindow: func() {
+                fyne.Do(func() {
+                    w.Show()
+                    w.RequestFocus()
+                })
+            },
+            Choose: func(urls []string, _ string) {
+                fyne.Do(func() {
+                    showChooserDialog(w, manager, urls)
+                })
+            },
+        })
+        go func() {
+            if err := server.Serve(listener); err != nil {
+                log.Printf("控制接口已停止: %v", err)
+            }
+        }()
+        log.Printf("控制接口: %s", listener.Addr())
+    }
 
     nameEntry := widget.NewEntry()
     workdirEntry := widget.NewEntry()
@@ -183,6 +420,36 @@ func main() {
     // Combine workdirEntry and selectDirButton for the form item
     workdirInputWidget := container.NewBorder(nil, nil, nil, selectDirButton, workdirEntry)
 
+    // 浏览器选择，第一项表示使用全局默认浏览器
+    var browserIDs []string
+    browserSelect := widget.NewSelect(nil, nil)
+    refreshBrowserSelect := func() {
+        var labels []string
+        labels, browserIDs = browserOptions(registry)
+        browserSelect.Options = labels
+        browserSelect.SetSelectedIndex(0)
+    }
+    refreshBrowserSelect()
+    manageBrowsersButton := widget.NewButton("浏览器管理", func() {
+        showBrowsersDialog(w, registry, func() {
+            refreshBrowserSelect()
+            list.Refresh()
+        })
+    })
+    browserInputWidget := container.NewBorder(nil, nil, nil, manageBrowsersButton, browserSelect)
+
+    flagsEntry := widget.NewMultiLineEntry()
+    flagsEntry.SetPlaceHolder("每行一个，例如：--lang=zh-CN")
+    flagsEntry.SetMinRowsVisible(2)
+
+    logLevelSelect := widget.NewSelect(logLevelOptions(0), nil)
+    logLevelSelect.SetSelectedIndex(0)
+
+    restartSelect := widget.NewSelect(restartPolicyLabels, nil)
+    restartSelect.SetSelectedIndex(0)
+
+    devtoolsCheck, debugPortEntry, devtoolsInputWidget := newDevToolsInput(false, 0)
+
     // Update placeholders now that there are labels
     nameEntry.SetPlaceHolder("例如：我的项目")
     workdirEntry.SetPlaceHolder("粘贴路径或点击右侧按钮选择")
@@ -190,27 +457,55 @@ func main() {
     addForm := widget.NewForm(
         widget.NewFormItem("配置名称:", nameEntry),
         widget.NewFormItem("数据目录:", workdirInputWidget),
+        widget.NewFormItem("浏览器:", browserInputWidget),
+        widget.NewFormItem("启动参数:", flagsEntry),
+        widget.NewFormItem("浏览器日志:", logLevelSelect),
+        widget.NewFormItem("自动重启:", restartSelect),
+        widget.NewFormItem("远程调试:", devtoolsInputWidget),
     )
     addForm.SubmitText = "新增配置"
     addForm.OnSubmit = func() {
         name := nameEntry.Text
         workdir := workdirEntry.Text
+        browserID := ""
+        if idx := browserSelect.SelectedIndex(); idx > 0 {
+            browserID = browserIDs[idx]
+        }
 
         // 使用 config.AddConfig 进行添加和校验
         // AddConfig 需要当前的配置列表（包含默认实例）
-        currentConfigsForAdd := config.LoadConfigs()
-        updatedConfigs, err := config.AddConfig(name, workdir, currentConfigsForAdd)
+        debugPort, err := parseDebugPort(debugPortEntry.Text)
+        if err != nil {
+            dialog.ShowError(err, w)
+            return
+        }
+        currentConfigsForAdd, err := config.LoadConfigs()
+        if err != nil {
+            log.Printf("新增配置失败: %v", err)
+            dialog.ShowError(err, w)
+            return
+        }
+        newConfig := &config.ChromeConfig{Name: name, UserDataDir: workdir, Browser: browserID, Flags: parseFlagLines(flagsEntry.Text), LogLevel: logLevelSelect.SelectedIndex(), Restart: restartPolicies[restartSelect.SelectedIndex()], DevTools: devtoolsCheck.Checked, DebugPort: debugPort}
+        updatedConfigs, err := config.AddConfig(newConfig, currentConfigsForAdd)
         if err != nil {
             log.Printf("新增配置失败: %v", err)
             dialog.ShowError(err, w)
             return
         }
         // AddConfig 内部已经调用了 SaveConfigs
-        configs = updatedConfigs            // 更新内存中的 configs 列表
-        reloadInstancesAndRefreshList(list) // 重新加载并刷新UI
+        configs = updatedConfigs                                    // 更新内存中的 configs 列表
+        if err := reloadInstancesAndRefreshList(list); err != nil { // 重新加载并刷新UI
+            showLoadError(err)
+        }
 
         nameEntry.SetText("") // Clear fields after successful submission
         workdirEntry.SetText("")
+        browserSelect.SetSelectedIndex(0)
+        flagsEntry.SetText("")
+        logLevelSelect.SetSelectedIndex(0)
+        restartSelect.SetSelectedIndex(0)
+        devtoolsCheck.SetChecked(false)
+        debugPortEntry.SetText("")
         log.Println("新增配置成功:", name)
         dialog.ShowInformation("成功", "配置 \""+name+"\" 已添加", w)
     }
@@ -225,15 +520,1190 @@ func main() {
     // Use a Border layout: list label at top, scrollable list in the center, add form at the bottom
     scrollableList := container.NewScroll(list)
 
+    defaultFlagsButton := widget.NewButton("默认启动参数", func() {
+        showDefaultFlagsDialog(w, registry)
+    })
+    routerButton := widget.NewButton("链接路由", func() {
+        showRouterDialog(w, manager)
+    })
+    backupsButton := widget.NewButton("备份与恢复", func() {
+        showBackupsDialog(w, func() {
+            // 恢复的备份中也包含全局设置，替换注册表使用的设置，控制接口随之使用恢复的设置
+            if restored, err := config.LoadSettings(); err == nil {
+                registry.SetSettings(restored)
+            }
+            if err := reloadInstancesAndRefreshList(list); err != nil {
+                showLoadError(err)
+            }
+            refreshBrowserSelect()
+        })
+    })
+    header := container.NewBorder(nil, nil, nil, container.NewHBox(defaultFlagsButton, routerButton, backupsButton), widget.NewLabel("Chrome 配置列表："))
+
     content := container.NewBorder(
-        widget.NewLabel("Chrome 配置列表："), // Top
-        addConfigSection,                  // Bottom (using the new form-based section)
-        nil,                               // Left
-        nil,                               // Right
-        scrollableList,                    // Center object
+        header,           // Top
+        addConfigSection, // Bottom (using the new form-based section)
+        nil,              // Left
+        nil,              // Right
+        scrollableList,   // Center object
     )
 
     w.SetContent(content)
     w.Resize(fyne.NewSize(700, 600)) // 稍微调大一点高度以容纳删除按钮和路径换行
     w.ShowAndRun()
 }
+
+// forwardTimeout 是把再次启动转交给正在运行的管理器的期限，包括等待刚启动的管理器开始监听。
+const forwardTimeout = 15 * time.Second
+
+// lockAttempts 是获取单实例锁的次数上限，锁只被命令行短暂持有时重新获取。
+const lockAttempts = 3
+
+// showRunningManager 把正在运行的管理器的窗口显示到前台。没有管理器在运行时返回 api.ErrNotRunning。
+func showRunningManager() error {
+    ctx, cancel := context.WithTimeout(context.Background(), forwardTimeout)
+    defer cancel()
+    client, err := api.Connect(ctx)
+    if err != nil {
+        return err
+    }
+    return client.ShowWindow(ctx)
+}
+
+// startManager 在后台启动一个新的管理器（不带参数运行本程序），不等待它退出。
+func startManager() error {
+    executable, err := os.Executable()
+    if err != nil {
+        return err
+    }
+    cmd := exec.Command(executable)
+    if err := cmd.Start(); err != nil {
+        return err
+    }
+    log.Printf("已启动管理器: pid=%d", cmd.Process.Pid)
+    return cmd.Process.Release()
+}
+
+// syncLaunchers 使每个配置的桌面启动器与配置列表一致，失败时只记录日志。
+func syncLaunchers(configs []*config.ChromeConfig) {
+    executable, err := os.Executable()
+    if err == nil {
+        err = xdg.SyncLaunchers(configs, executable)
+    }
+    if err != nil {
+        log.Printf("更新桌面启动器失败: %v", err)
+    }
+}
+
+// browserOptions 返回浏览器选择框的显示文本和对应的浏览器 ID。
+// 第一项为空 ID，表示使用全局默认浏览器。
+func browserOptions(registry *chrome.Registry) ([]string, []string) {
+    defaultLabel := "全局默认"
+    if browser, err := registry.Default(); err == nil {
+        defaultLabel += " (" + browser.Name + ")"
+    }
+    labels := []string{defaultLabel}
+    ids := []string{""}
+    for _, browser := range registry.Browsers() {
+        labels = append(labels, browser.Name+" ["+browser.ID+"]")
+        ids = append(ids, browser.ID)
+    }
+    return labels, ids
+}
+
+// showBrowsersDialog 显示浏览器安装管理对话框：查看已发现的安装、手动注册/删除安装以及设置全局默认浏览器。
+// onChange 在注册表或默认浏览器发生变化后调用。
+func showBrowsersDialog(w fyne.Window, registry *chrome.Registry, onChange func()) {
+    var browsers []*config.Browser
+
+    var browserList *widget.List
+    browserList = widget.NewList(
+        func() int { return len(browsers) },
+        func() fyne.CanvasObject {
+            nameLabel := widget.NewLabel("浏览器名称")
+            pathLabel := widget.NewLabel("可执行文件")
+            pathLabel.TextStyle.Italic = true
+            defaultButton := widget.NewButton("设为默认", nil)
+            removeButton := widget.NewButton("删除", nil)
+            controls := container.NewHBox(defaultButton, removeButton)
+            return container.NewBorder(nil, nil, nil, controls, container.NewVBox(nameLabel, pathLabel))
+        },
+        func(id widget.ListItemID, item fyne.CanvasObject) {
+            if id >= len(browsers) {
+                return
+            }
+            browser := browsers[id]
+
+            borderLayout := item.(*fyne.Container)
+            contentVBox := borderLayout.Objects[0].(*fyne.Container)
+            controlsHBox := borderLayout.Objects[1].(*fyne.Container)
+            nameLabel := contentVBox.Objects[0].(*widget.Label)
+            pathLabel := contentVBox.Objects[1].(*widget.Label)
+            defaultButton := controlsHBox.Objects[0].(*widget.Button)
+            removeButton := controlsHBox.Objects[1].(*widget.Button)
+
+            source := "手动注册"
+            if browser.Discovered {
+                source = "自动发现"
+            }
+            name := browser.Name + " [" + browser.ID + "] · " + source
+            if registry.Settings().DefaultBrowser == browser.ID {
+                name += " · 默认"
+            }
+            nameLabel.SetText(name)
+            pathLabel.SetText(browser.Path)
+
+            defaultButton.OnTapped = func() {
+                err := registry.UpdateSettings(func(settings *config.Settings) error {
+                    return config.SetDefaultBrowser(settings, browser.ID)
+                })
+                if err != nil {
+                    dialog.ShowError(err, w)
+                    return
+                }
+                log.Printf("默认浏览器已设置为: %s", browser.ID)
+                browserList.Refresh()
+                onChange()
+            }
+            if browser.Discovered {
+                removeButton.Hide()
+            } else {
+                removeButton.Show()
+                removeButton.OnTapped = func() {
+                    err := registry.UpdateSettings(func(settings *config.Settings) error {
+                        return config.RemoveBrowser(settings, browser.ID)
+                    })
+                    if err != nil {
+                        dialog.ShowError(err, w)
+                        return
+                    }
+                    log.Printf("已删除手动注册的浏览器: %s", browser.ID)
+                    browsers = registry.Browsers()
+                    browserList.Refresh()
+                    onChange()
+                }
+            }
+        },
+    )
+    browsers = registry.Browsers()
+
+    idEntry := widget.NewEntry()
+    idEntry.SetPlaceHolder("例如：chrome-for-testing-126")
+    nameEntry := widget.NewEntry()
+    nameEntry.SetPlaceHolder("例如：Chrome for Testing 126")
+    pathEntry := widget.NewEntry()
+    pathEntry.SetPlaceHolder("浏览器可执行文件路径")
+    selectFileButton := widget.NewButton("选择文件", func() {
+        dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+            if err != nil {
+                dialog.ShowError(err, w)
+                return
+            }
+            if reader != nil {
+                pathEntry.SetText(reader.URI().Path())
+                reader.Close()
+            }
+        }, w)
+    })
+
+    addForm := widget.NewForm(
+        widget.NewFormItem("ID:", idEntry),
+        widget.NewFormItem("名称:", nameEntry),
+        widget.NewFormItem("路径:", container.NewBorder(nil, nil, nil, selectFileButton, pathEntry)),
+    )
+    addForm.SubmitText = "注册浏览器"
+    addForm.OnSubmit = func() {
+        err := registry.UpdateSettings(func(settings *config.Settings) error {
+            return config.AddBrowser(settings, idEntry.Text, nameEntry.Text, pathEntry.Text)
+        })
+        if err != nil {
+            dialog.ShowError(err, w)
+            return
+        }
+        log.Printf("已注册浏览器: %s (%s)", idEntry.Text, pathEntry.Text)
+        idEntry.SetText("")
+        nameEntry.SetText("")
+        pathEntry.SetText("")
+        browsers = registry.Browsers()
+        browserList.Refresh()
+        onChange()
+    }
+
+    rescanButton := widget.NewButton("重新扫描", func() {
+        registry.Rescan()
+        browsers = registry.Browsers()
+        browserList.Refresh()
+        onChange()
+    })
+    autoDefaultButton := widget.NewButton("自动选择默认浏览器", func() {
+        err := registry.UpdateSettings(func(settings *config.Settings) error {
+            return config.SetDefaultBrowser(settings, "")
+        })
+        if err != nil {
+            dialog.ShowError(err, w)
+            return
+        }
+        browserList.Refresh()
+        onChange()
+    })
+
+    content := container.NewBorder(
+        container.NewHBox(rescanButton, autoDefaultButton),
+        container.NewVBox(widget.NewSeparator(), widget.NewLabel("手动注册浏览器："), addForm),
+        nil,
+        nil,
+        browserList,
+    )
+    d := dialog.NewCustom("浏览器管理", "关闭", content, w)
+    d.Resize(fyne.NewSize(650, 500))
+    d.Show()
+}
+
+// parseFlagLines 将多行文本解析为启动参数列表，每行一个参数，忽略空行。
+func parseFlagLines(text string) []string {
+    var flags []string
+    for _, line := range strings.Split(text, "\n") {
+        if line = strings.TrimSpace(line); line != "" {
+            flags = append(flags, line)
+        }
+    }
+    return flags
+}
+
+// restartPolicies 是自动重启策略选择框的选项对应的策略，与 restartPolicyLabels 一一对应。
+var (
+    restartPolicies     = []string{config.RestartNever, config.RestartOnFailure, config.RestartAlways}
+    restartPolicyLabels = []string{"不重启", "异常退出时重启", "总是重启"}
+)
+
+// newDevToolsInput 创建远程调试的输入组件：是否开启的复选框和固定端口输入框（留空表示自动分配），
+// 返回两者以及组合后用于表单的组件。
+func newDevToolsInput(enabled bool, port int) (*widget.Check, *widget.Entry, fyne.CanvasObject) {
+    portEntry := widget.NewEntry()
+    portEntry.SetPlaceHolder("端口，留空自动分配")
+    if port > 0 {
+        portEntry.SetText(strconv.Itoa(port))
+    }
+    check := widget.NewCheck("启用", func(checked bool) {
+        if checked {
+            portEntry.Enable()
+        } else {
+            portEntry.Disable()
+        }
+    })
+    check.SetChecked(enabled)
+    if !enabled {
+        portEntry.Disable()
+    }
+    return check, portEntry, container.NewBorder(nil, nil, check, nil, portEntry)
+}
+
+// parseDebugPort 解析远程调试端口输入框的内容，留空时返回 0（自动分配）。
+func parseDebugPort(text string) (int, error) {
+    text = strings.TrimSpace(text)
+    if text == "" {
+        return 0, nil
+    }
+    port, err := strconv.Atoi(text)
+    if err != nil || port <= 0 || port > 65535 {
+        return 0, fmt.Errorf("远程调试端口 '%s' 无效，应为 1-65535 之间的数字", text)
+    }
+    return port, nil
+}
+
+// logLevelOptions 返回浏览器日志级别选择框的选项，下标即 ChromeConfig.LogLevel。
+// 手动编辑配置文件设置的更高级别也会出现在选项中，避免编辑其他属性时被悄悄改掉。
+func logLevelOptions(current int) []string {
+    options := []string{"关闭", "开启 (--enable-logging)", "详细 (--v=1)", "更详细 (--v=2)"}
+    for level := len(options); level <= current; level++ {
+        options = append(options, fmt.Sprintf("--v=%d", level-1))
+    }
+    return options
+}
+
+// showDefaultFlagsDialog 显示全局默认启动参数的编辑对话框，所有配置都会继承这些参数。
+func showDefaultFlagsDialog(w fyne.Window, registry *chrome.Registry) {
+    flagsEntry := widget.NewMultiLineEntry()
+    flagsEntry.SetPlaceHolder("每行一个，例如：--force-dark-mode")
+    flagsEntry.SetText(strings.Join(registry.Settings().DefaultFlags, "\n"))
+    flagsEntry.SetMinRowsVisible(6)
+
+    hint := widget.NewLabel("配置自身的同名参数优先；--enable-features / --disable-features 会与配置的特性列表合并。")
+    hint.Wrapping = fyne.TextWrapWord
+
+    d := dialog.NewCustomConfirm("默认启动参数", "保存", "取消", container.NewBorder(nil, hint, nil, nil, flagsEntry), func(save bool) {
+        if !save {
+            return
+        }
+        err := registry.UpdateSettings(func(settings *config.Settings) error {
+            return config.SetDefaultFlags(settings, parseFlagLines(flagsEntry.Text))
+        })
+        if err != nil {
+            log.Printf("保存默认启动参数失败: %v", err)
+            dialog.ShowError(err, w)
+            return
+        }
+        log.Printf("默认启动参数已更新: %v", registry.Settings().DefaultFlags)
+    }, w)
+    d.Resize(fyne.NewSize(500, 300))
+    d.Show()
+}
+
+// showBackupsDialog 显示配置文件的备份列表，用户可以选择一个备份恢复。
+// onRestore 在恢复成功后调用，用于重新加载配置列表。
+func showBackupsDialog(w fyne.Window, onRestore func()) {
+    backups, err := config.ListBackups()
+    if err != nil {
+        dialog.ShowError(err, w)
+        return
+    }
+    if len(backups) == 0 {
+        dialog.ShowInformation("备份与恢复", "暂无备份。每次保存配置时都会自动备份，最多保留 "+strconv.Itoa(config.MaxBackups)+" 个。", w)
+        return
+    }
+
+    var d dialog.Dialog
+    backupList := widget.NewList(
+        func() int { return len(backups) },
+        func() fyne.CanvasObject {
+            return container.NewBorder(nil, nil, nil, widget.NewButton("恢复", nil), widget.NewLabel("备份时间"))
+        },
+        func(id widget.ListItemID, item fyne.CanvasObject) {
+            backup := backups[id]
+            borderLayout := item.(*fyne.Container)
+            label := borderLayout.Objects[0].(*widget.Label)
+            restoreButton := borderLayout.Objects[1].(*widget.Button)
+
+            entries := "无法解析"
+            if backup.Entries >= 0 {
+                entries = strconv.Itoa(backup.Entries) + " 个配置"
+            }
+            label.SetText(backup.Time.Format("2006-01-02 15:04:05") + " · " + entries)
+            restoreButton.OnTapped = func() {
+                message := "确定要用 " + backup.Time.Format("2006-01-02 15:04:05") + " 的备份替换当前配置吗？恢复后仍可以再恢复到其他备份。"
+                dialog.ShowConfirm("确认恢复", message, func(confirm bool) {
+                    if !confirm {
+                        return
+                    }
+                    if _, err := config.RestoreBackup(backup.Path); err != nil {
+                        log.Printf("恢复备份 %s 失败: %v", backup.Path, err)
+                        dialog.ShowError(err, w)
+                        return
+                    }
+                    log.Printf("已从备份恢复配置: %s", backup.Path)
+                    d.Hide()
+                    onRestore()
+                }, w)
+            }
+        },
+    )
+
+    d = dialog.NewCustom("备份与恢复", "关闭", backupList, w)
+    d.Resize(fyne.NewSize(450, 400))
+    d.Show()
+}
+
+// showEditConfigDialog 显示编辑配置的对话框，可以修改名称、数据目录、浏览器和启动参数。
+// 修改数据目录时可以选择把现有的数据目录移动到新位置，实例运行中时不允许移动。
+// onSaved 在配置保存成功后调用。
+func showEditConfigDialog(w fyne.Window, registry *chrome.Registry, instance *chrome.Instance, onSaved func()) {
+    cfg := instance.Config()
+
+    nameEntry := widget.NewEntry()
+    nameEntry.SetText(cfg.Name)
+    workdirEntry := widget.NewEntry()
+    workdirEntry.SetText(cfg.UserDataDir)
+    moveCheck := widget.NewCheck("将现有数据目录移动到新位置", nil)
+    moveCheck.Disable()
+    workdirEntry.OnChanged = func(text string) {
+        if text != cfg.UserDataDir {
+            moveCheck.Enable()
+        } else {
+            moveCheck.SetChecked(false)
+            moveCheck.Disable()
+        }
+    }
+    selectDirButton := widget.NewButton("选择目录", func() {
+        dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+            if err != nil {
+                dialog.ShowError(err, w)
+                return
+            }
+            if uri != nil {
+                workdirEntry.SetText(uri.Path())
+            }
+        }, w)
+    })
+
+    browserLabels, browserIDs := browserOptions(registry)
+    if !slices.Contains(browserIDs, cfg.Browser) {
+        // 保留引用了已不可用浏览器的设置，避免编辑其他属性时被悄悄改掉
+        browserLabels = append(browserLabels, cfg.Browser+" (不可用)")
+        browserIDs = append(browserIDs, cfg.Browser)
+    }
+    browserSelect := widget.NewSelect(browserLabels, nil)
+    browserSelect.SetSelectedIndex(slices.Index(browserIDs, cfg.Browser))
+
+    flagsEntry := widget.NewMultiLineEntry()
+    flagsEntry.SetText(strings.Join(cfg.Flags, "\n"))
+    flagsEntry.SetMinRowsVisible(3)
+
+    logLevelSelect := widget.NewSelect(logLevelOptions(cfg.LogLevel), nil)
+    logLevelSelect.SetSelectedIndex(cfg.LogLevel)
+
+    restartSelect := widget.NewSelect(restartPolicyLabels, nil)
+    restartSelect.SetSelectedIndex(max(slices.Index(restartPolicies, cfg.RestartPolicy()), 0))
+
+    devtoolsCheck, debugPortEntry, devtoolsInputWidget := newDevToolsInput(cfg.DevTools, cfg.DebugPort)
+
+    items := []*widget.FormItem{
+        widget.NewFormItem("配置名称:", nameEntry),
+        widget.NewFormItem("数据目录:", container.NewBorder(nil, nil, nil, selectDirButton, workdirEntry)),
+        widget.NewFormItem("", moveCheck),
+        widget.NewFormItem("浏览器:", browserSelect),
+        widget.NewFormItem("启动参数:", flagsEntry),
+        widget.NewFormItem("浏览器日志:", logLevelSelect),
+        widget.NewFormItem("自动重启:", restartSelect),
+        widget.NewFormItem("远程调试:", devtoolsInputWidget),
+    }
+    d := dialog.NewForm("编辑配置", "保存", "取消", items, func(save bool) {
+        if !save {
+            return
+        }
+        debugPort, err := parseDebugPort(debugPortEntry.Text)
+        if err != nil {
+            dialog.ShowError(err, w)
+            return
+        }
+        updated := &config.ChromeConfig{
+            Name:        nameEntry.Text,
+            UserDataDir: workdirEntry.Text,
+            Browser:     browserIDs[browserSelect.SelectedIndex()],
+            Flags:       parseFlagLines(flagsEntry.Text),
+            LogLevel:    logLevelSelect.SelectedIndex(),
+            Restart:     restartPolicies[restartSelect.SelectedIndex()],
+            DevTools:    devtoolsCheck.Checked,
+            DebugPort:   debugPort,
+            Workspaces:  cfg.Workspaces,
+        }
+        moveData := moveCheck.Checked && updated.UserDataDir != cfg.UserDataDir
+        if moveData && instance.IsRunning() {
+            dialog.ShowError(fmt.Errorf("配置 %s 正在运行，无法移动数据目录，请先停止实例", cfg.Name), w)
+            return
+        }
+
+        currentConfigs, err := config.LoadConfigs()
+        if err != nil {
+            log.Printf("编辑配置 %s 失败: %v", cfg, err)
+            dialog.ShowError(err, w)
+            return
+        }
+        if _, err := config.UpdateConfig(cfg.ID, updated, moveData, currentConfigs); err != nil {
+            log.Printf("编辑配置 %s 失败: %v", cfg, err)
+            dialog.ShowError(err, w)
+            return
+        }
+        log.Printf("配置 %s 已更新为: %s (dir: %s, 移动数据: %v)", cfg, updated, updated.UserDataDir, moveData)
+        onSaved()
+    }, w)
+    d.Resize(fyne.NewSize(600, 400))
+    d.Show()
+}
+
+// showCleanStaleLockDialog 提示用户数据目录中残留着已失效的 SingletonLock，确认后清理并重新启动实例。
+func showCleanStaleLockDialog(w fyne.Window, manager *chrome.Manager, instance *chrome.Instance, lock *chrome.SingletonLock) {
+    cfg := instance.Config()
+    message := fmt.Sprintf("数据目录中残留着上次未正常退出时的锁文件：\n%s\n（由主机 %s 上的进程 %d 创建，该进程已不存在或不在本机）\n\n浏览器会因此认为配置正被使用。是否清理后启动？", lock.Path, lock.Hostname, lock.PID)
+    dialog.ShowConfirm("清理残留的锁", message, func(confirm bool) {
+        if !confirm {
+            return
+        }
+        if _, err := instance.CleanStaleLock(); err != nil {
+            log.Printf("清理 %s 的锁失败: %v", cfg, err)
+            dialog.ShowError(err, w)
+            return
+        }
+        log.Printf("已清理 %s 的残留锁: %v", cfg, lock)
+        if err := manager.Start(cfg.ID); err != nil {
+            log.Printf("启动 %s 失败: %v", cfg, err)
+            dialog.ShowError(err, w)
+        }
+    }, w)
+}
+
+// logLine 是日志查看器中的一行及其严重级别。
+type logLine struct {
+    text     string
+    severity chrome.Severity
+}
+
+const (
+    logBacklog      = 256 << 10 // 打开日志查看器时最多读取文件末尾的字节数
+    logMaxLines     = 5000      // 日志查看器最多保留的行数，超过后丢弃最早的行
+    logPollInterval = 500 * time.Millisecond
+)
+
+// showLogViewer 显示实例的浏览器输出日志，实时追加新写入的内容，并可以按严重级别过滤。
+// 没有级别前缀的行（例如多行日志的后续行）沿用上一行的级别，这样过滤时不会把一条日志拆开。
+func showLogViewer(w fyne.Window, instance *chrome.Instance) {
+    cfg := instance.Config()
+    path := chrome.LogPath(cfg.ID)
+    tail := chrome.NewLogTail(path, logBacklog)
+
+    var lines []logLine    // 已读取的所有行
+    var filtered []logLine // 满足过滤条件的行
+    minSeverity := chrome.SeverityUnknown
+    lastSeverity := chrome.SeverityUnknown
+
+    lineList := widget.NewList(
+        func() int { return len(filtered) },
+        func() fyne.CanvasObject {
+            label := widget.NewLabel("")
+            label.TextStyle.Monospace = true
+            return label
+        },
+        func(id widget.ListItemID, item fyne.CanvasObject) {
+            label := item.(*widget.Label)
+            label.SetText(filtered[id].text)
+            label.Importance = widget.MediumImportance
+            switch {
+            case filtered[id].severity >= chrome.SeverityError:
+                label.Importance = widget.DangerImportance
+            case filtered[id].severity == chrome.SeverityWarning:
+                label.Importance = widget.WarningImportance
+            }
+            label.Refresh()
+        },
+    )
+    followCheck := widget.NewCheck("跟随最新", nil)
+    followCheck.SetChecked(true)
+
+    applyFilter := func() {
+        filtered = filtered[:0]
+        for _, line := range lines {
+            if line.severity >= minSeverity {
+                filtered = append(filtered, line)
+            }
+        }
+        lineList.Refresh()
+        if followCheck.Checked {
+            lineList.ScrollToBottom()
+        }
+    }
+    severities := []chrome.Severity{chrome.SeverityUnknown, chrome.SeverityInfo, chrome.SeverityWarning, chrome.SeverityError}
+    severitySelect := widget.NewSelect([]string{"全部", "INFO 及以上", "WARNING 及以上", "ERROR 及以上"}, nil)
+    severitySelect.OnChanged = func(_ string) {
+        minSeverity = severities[severitySelect.SelectedIndex()]
+        applyFilter()
+    }
+    severitySelect.SetSelectedIndex(0)
+
+    appendLines := func(texts []string) {
+        for _, text := range texts {
+            severity := chrome.ParseSeverity(text)
+            if severity == chrome.SeverityUnknown {
+                severity = lastSeverity
+            }
+            lastSeverity = severity
+            line := logLine{text: text, severity: severity}
+            lines = append(lines, line)
+            if severity >= minSeverity {
+                filtered = append(filtered, line)
+            }
+        }
+        if len(lines) > logMaxLines {
+            lines = append([]logLine(nil), lines[len(lines)-logMaxLines:]...)
+            applyFilter()
+            return
+        }
+        lineList.Refresh()
+        if followCheck.Checked {
+            lineList.ScrollToBottom()
+        }
+    }
+
+    // 在后台轮询日志文件，读取到的新行交给界面线程追加，对话框关闭后停止
+    stop := make(chan struct{})
+    go func() {
+        ticker := time.NewTicker(logPollInterval)
+        defer ticker.Stop()
+        for {
+            texts, err := tail.Read()
+            if err != nil {
+                log.Printf("读取日志 %s 失败: %v", path, err)
+            } else if len(texts) > 0 {
+                fyne.Do(func() { appendLines(texts) })
+            }
+            select {
+            case <-stop:
+                return
+            case <-ticker.C:
+            }
+        }
+    }()
+
+    pathLabel := widget.NewLabel(path)
+    pathLabel.TextStyle.Italic = true
+    pathLabel.Truncation = fyne.TextTruncateEllipsis
+    toolbar := container.NewBorder(nil, nil, nil, container.NewHBox(severitySelect, followCheck), pathLabel)
+
+    d := dialog.NewCustom("日志 - "+cfg.Name, "关闭", container.NewBorder(toolbar, nil, nil, nil, lineList), w)
+    d.SetOnClosed(func() { close(stop) })
+    d.Resize(fyne.NewSize(900, 600))
+    d.Show()
+}
+
+// cdpTimeout 是界面中每次通过远程调试端点操作标签页的超时时间。
+const cdpTimeout = 5 * time.Second
+
+// showDevToolsDialog 显示实例的远程调试端点，可以复制 WebSocket 地址交给 Puppeteer/Playwright 等工具连接。
+// 浏览器刚启动时可能还没有开启端点，此时在后台等待一段时间。
+// 端点可用后，展开“标签页”可以查看、激活和关闭已打开的标签页，或在新标签页中打开网址。
+func showDevToolsDialog(w fyne.Window, instance *chrome.Instance) {
+    cfg := instance.Config()
+    wsEntry := widget.NewEntry()
+    httpEntry := widget.NewEntry()
+    wsEntry.Disable()
+    httpEntry.Disable()
+    statusLabel := widget.NewLabel("正在读取调试端点…")
+    statusLabel.Wrapping = fyne.TextWrapWord
+    copyButton := func(entry *widget.Entry) *widget.Button {
+        return widget.NewButton("复制", func() {
+            if entry.Text != "" {
+                fyne.CurrentApp().Clipboard().SetContent(entry.Text)
+            }
+        })
+    }
+
+    form := widget.NewForm(
+        widget.NewFormItem("WebSocket:", container.NewBorder(nil, nil, nil, copyButton(wsEntry), wsEntry)),
+        widget.NewFormItem("HTTP:", container.NewBorder(nil, nil, nil, copyButton(httpEntry), httpEntry)),
+    )
+
+    // 标签页列表，所有操作都在后台进行，完成后重新读取列表
+    var tabs []cdp.TargetInfo
+    var tabsItem *widget.AccordionItem
+    var accordion *widget.Accordion
+    var refreshTabs func()
+    tabAction := func(name string, action func(ctx context.Context) error) {
+        go func() {
+            ctx, cancel := context.WithTimeout(context.Background(), cdpTimeout)
+            defer cancel()
+            if err := action(ctx); err != nil {
+                log.Printf("%s %s 的标签页失败: %v", name, cfg, err)
+                fyne.Do(func() { dialog.ShowError(err, w) })
+            }
+            fyne.Do(refreshTabs)
+        }()
+    }
+    tabList := widget.NewList(
+        func() int { return len(tabs) },
+        func() fyne.CanvasObject {
+            titleLabel := widget.NewLabel("标题")
+            titleLabel.Truncation = fyne.TextTruncateEllipsis
+            urlLabel := widget.NewLabel("网址")
+            urlLabel.TextStyle.Italic = true
+            urlLabel.Truncation = fyne.TextTruncateEllipsis
+            controls := container.NewHBox(widget.NewButton("激活", nil), widget.NewButton("关闭", nil))
+            return container.NewBorder(nil, nil, nil, controls, container.NewVBox(titleLabel, urlLabel))
+        },
+        func(id widget.ListItemID, item fyne.CanvasObject) {
+            tab := tabs[id]
+            borderLayout := item.(*fyne.Container)
+            contentVBox := borderLayout.Objects[0].(*fyne.Container)
+            controlsHBox := borderLayout.Objects[1].(*fyne.Container)
+            contentVBox.Objects[0].(*widget.Label).SetText(tab.Title)
+            contentVBox.Objects[1].(*widget.Label).SetText(tab.URL)
+            controlsHBox.Objects[0].(*widget.Button).OnTapped = func() {
+                tabAction("激活", func(ctx context.Context) error { return instance.ActivateTab(ctx, tab.TargetID) })
+            }
+            controlsHBox.Objects[1].(*widget.Button).OnTapped = func() {
+                tabAction("关闭", func(ctx context.Context) error { return instance.CloseTab(ctx, tab.TargetID) })
+            }
+        },
+    )
+    urlEntry := widget.NewEntry()
+    urlEntry.SetPlaceHolder("在新标签页中打开网址，例如 https://example.com")
+    openButton := widget.NewButton("打开", func() {
+        url := strings.TrimSpace(urlEntry.Text)
+        if url == "" {
+            return
+        }
+        tabAction("打开", func(ctx context.Context) error {
+            _, err := instance.OpenTab(ctx, url)
+            return err
+        })
+        urlEntry.SetText("")
+    })
+    refreshButton := widget.NewButton("刷新", func() { refreshTabs() })
+    tabsToolbar := container.NewBorder(nil, nil, nil, container.NewHBox(openButton, refreshButton), urlEntry)
+    // 列表没有最小高度，用一个透明的矩形撑开展开后的区域
+    spacer := canvas.NewRectangle(color.Transparent)
+    spacer.SetMinSize(fyne.NewSize(600, 260))
+    tabsItem = widget.NewAccordionItem("标签页", container.NewBorder(tabsToolbar, nil, nil, nil, container.NewStack(spacer, tabList)))
+    accordion = widget.NewAccordion(tabsItem)
+    accordion.Hide()
+
+    refreshTabs = func() {
+        go func() {
+            ctx, cancel := context.WithTimeout(context.Background(), cdpTimeout)
+            defer cancel()
+            result, err := instance.Tabs(ctx)
+            fyne.Do(func() {
+                if err != nil {
+                    log.Printf("读取 %s 的标签页失败: %v", cfg, err)
+                    tabsItem.Title = "标签页（读取失败: " + err.Error() + "）"
+                } else {
+                    tabs = result
+                    tabsItem.Title = fmt.Sprintf("标签页 (%d)", len(tabs))
+                }
+                accordion.Refresh()
+                tabList.Refresh()
+            })
+        }()
+    }
+
+    d := dialog.NewCustom("远程调试 - "+cfg.Name, "关闭", container.NewVBox(statusLabel, form, accordion), w)
+    d.Resize(fyne.NewSize(700, 520))
+    d.Show()
+
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+        endpoint, err := instance.WaitDevTools(ctx)
+        fyne.Do(func() {
+            if err != nil {
+                log.Printf("读取 %s 的调试端点失败: %v", cfg, err)
+                statusLabel.SetText("无法读取调试端点: " + err.Error())
+                return
+            }
+            statusLabel.SetText(fmt.Sprintf("%s · 端口 %d", endpoint.Browser, endpoint.Port))
+            wsEntry.SetText(endpoint.WebSocketDebuggerURL)
+            httpEntry.SetText(endpoint.HTTPURL)
+            accordion.Show()
+            refreshTabs()
+        })
+    }()
+}
+
+// workspaceTimeout 是保存或打开工作区的超时时间，打开时可能需要先启动浏览器并逐个打开标签页。
+const workspaceTimeout = 30 * time.Second
+
+// showWorkspacesDialog 显示配置保存的工作区，可以把正在运行的实例中的窗口和标签页保存为工作区，
+// 或重新打开、导出、删除已保存的工作区，也可以导入其他配置导出的工作区。
+// 打开工作区时实例未运行会先启动。onChange 在工作区列表保存后调用，用于重新加载配置。
+func showWorkspacesDialog(w fyne.Window, instance *chrome.Instance, onChange func()) {
+    cfg := instance.Config()
+    workspaces := cfg.Workspaces
+
+    // 保存或删除工作区后重新加载配置，并从实例的最新配置中读取工作区列表
+    var workspaceList *widget.List
+    save := func(update func([]*config.ChromeConfig) ([]*config.ChromeConfig, error)) bool {
+        currentConfigs, err := config.LoadConfigs()
+        if err == nil {
+            _, err = update(currentConfigs)
+        }
+        if err != nil {
+            log.Printf("保存 %s 的工作区失败: %v", cfg, err)
+            dialog.ShowError(err, w)
+            return false
+        }
+        onChange()
+        workspaces = instance.Config().Workspaces
+        workspaceList.Refresh()
+        return true
+    }
+    // saveWorkspace 保存工作区，已有同名工作区时先确认是否替换
+    saveWorkspace := func(ws *config.Workspace) {
+        doSave := func() {
+            if save(func(configs []*config.ChromeConfig) ([]*config.ChromeConfig, error) {
+                return config.SaveWorkspace(cfg.ID, ws, configs)
+            }) {
+                log.Printf("已保存 %s 的工作区 %s (%d 个窗口, %d 个标签页)", cfg, ws.Name, len(ws.Windows), ws.TabCount())
+            }
+        }
+        if instance.Config().FindWorkspace(ws.Name) == nil {
+            doSave()
+            return
+        }
+        dialog.ShowConfirm("替换工作区", "已有名为 \""+ws.Name+"\" 的工作区，确定要替换吗？", func(confirm bool) {
+            if confirm {
+                doSave()
+            }
+        }, w)
+    }
+
+    workspaceList = widget.NewList(
+        func() int { return len(workspaces) },
+        func() fyne.CanvasObject {
+            nameLabel := widget.NewLabel("工作区名称")
+            nameLabel.Truncation = fyne.TextTruncateEllipsis
+            detailLabel := widget.NewLabel("窗口和标签页")
+            detailLabel.TextStyle.Italic = true
+            controls := container.NewHBox(widget.NewButton("打开", nil), widget.NewButton("导出", nil), widget.NewButton("删除", nil))
+            return container.NewBorder(nil, nil, nil, controls, container.NewVBox(nameLabel, detailLabel))
+        },
+        func(id widget.ListItemID, item fyne.CanvasObject) {
+            ws := workspaces[id]
+            borderLayout := item.(*fyne.Container)
+            contentVBox := borderLayout.Objects[0].(*fyne.Container)
+            controlsHBox := borderLayout.Objects[1].(*fyne.Container)
+            contentVBox.Objects[0].(*widget.Label).SetText(ws.Name)
+            contentVBox.Objects[1].(*widget.Label).SetText(fmt.Sprintf("%d 个窗口 · %d 个标签页 · %s", len(ws.Windows), ws.TabCount(), ws.Created.Format("2006-01-02 15:04")))
+            openButton := controlsHBox.Objects[0].(*widget.Button)
+            openButton.OnTapped = func() {
+                openButton.Disable()
+                log.Printf("打开 %s 的工作区 %s", cfg, ws.Name)
+                go func() {
+                    ctx, cancel := context.WithTimeout(context.Background(), workspaceTimeout)
+                    defer cancel()
+                    err := instance.OpenWorkspace(ctx, ws)
+                    fyne.Do(func() {
+                        openButton.Enable()
+                        if err != nil {
+                            log.Printf("打开 %s 的工作区 %s 失败: %v", cfg, ws.Name, err)
+                            dialog.ShowError(err, w)
+                        }
+                    })
+                }()
+            }
+            controlsHBox.Objects[1].(*widget.Button).OnTapped = func() {
+                dialog.ShowFileSave(func(writer fyne.URIWriteCloser, err error) {
+                    if err != nil {
+                        dialog.ShowError(err, w)
+                        return
+                    }
+                    if writer == nil {
+                        return
+                    }
+                    writer.Close()
+                    if err := config.ExportWorkspace(ws, writer.URI().Path()); err != nil {
+                        log.Printf("导出 %s 的工作区 %s 失败: %v", cfg, ws.Name, err)
+                        dialog.ShowError(err, w)
+                        return
+                    }
+                    log.Printf("已导出 %s 的工作区 %s 到 %s", cfg, ws.Name, writer.URI().Path())
+                }, w)
+            }
+            controlsHBox.Objects[2].(*widget.Button).OnTapped = func() {
+                dialog.ShowConfirm("确认删除", "确定要删除工作区 \""+ws.Name+"\" 吗？", func(confirm bool) {
+                    if confirm && save(func(configs []*config.ChromeConfig) ([]*config.ChromeConfig, error) {
+                        return config.RemoveWorkspace(cfg.ID, ws.Name, configs)
+                    }) {
+                        log.Printf("已删除 %s 的工作区 %s", cfg, ws.Name)
+                    }
+                }, w)
+            }
+        },
+    )
+
+    nameEntry := widget.NewEntry()
+    nameEntry.SetPlaceHolder("工作区名称")
+    captureButton := widget.NewButton("保存当前标签页", nil)
+    captureButton.OnTapped = func() {
+        name := strings.TrimSpace(nameEntry.Text)
+        if name == "" {
+            dialog.ShowError(fmt.Errorf("工作区名称不能为空"), w)
+            return
+        }
+        captureButton.Disable()
+        go func() {
+            ctx, cancel := context.WithTimeout(context.Background(), cdpTimeout)
+            defer cancel()
+            ws, err := instance.CaptureWorkspace(ctx, name)
+            fyne.Do(func() {
+                captureButton.Enable()
+                if err != nil {
+                    log.Printf("读取 %s 的标签页失败: %v", cfg, err)
+                    dialog.ShowError(err, w)
+                    return
+                }
+                saveWorkspace(ws)
+                nameEntry.SetText("")
+            })
+        }()
+    }
+    importButton := widget.NewButton("导入", func() {
+        dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+            if err != nil {
+                dialog.ShowError(err, w)
+                return
+            }
+            if reader == nil {
+                return
+            }
+            defer reader.Close()
+            data, err := io.ReadAll(reader)
+            if err == nil {
+                var ws *config.Workspace
+                if ws, err = config.ParseWorkspace(data); err == nil {
+                    saveWorkspace(ws)
+                    return
+                }
+            }
+            log.Printf("导入工作区 %s 失败: %v", reader.URI().Path(), err)
+            dialog.ShowError(err, w)
+        }, w)
+    })
+    toolbar := container.NewBorder(nil, nil, nil, container.NewHBox(captureButton, importButton), nameEntry)
+
+    // 列表没有最小高度，用一个透明的矩形撑开对话框
+    spacer := canvas.NewRectangle(color.Transparent)
+    spacer.SetMinSize(fyne.NewSize(550, 300))
+    d := dialog.NewCustom("工作区 - "+cfg.Name, "关闭", container.NewBorder(toolbar, nil, nil, nil, container.NewStack(spacer, workspaceList)), w)
+    d.Resize(fyne.NewSize(650, 450))
+    d.Show()
+}
+
+// matchKindLabels 是链接路由规则的匹配方式在界面中的名称，与 config.MatchKinds 一一对应。
+var matchKindLabels = []string{"域名", "正则表达式", "来源程序"}
+
+// profileOptions 返回配置选择框的显示文本和对应的配置 ID，包括默认实例。
+func profileOptions(manager *chrome.Manager) ([]string, []string) {
+    var labels, ids []string
+    for _, instance := range manager.Instances() {
+        cfg := instance.Config()
+        labels = append(labels, cfg.Name+" ["+cfg.ID+"]")
+        ids = append(ids, cfg.ID)
+    }
+    return labels, ids
+}
+
+// showRouterDialog 显示链接路由的编辑对话框：按顺序匹配的规则、后备配置、是否让用户选择，以及注册为默认浏览器。
+func showRouterDialog(w fyne.Window, manager *chrome.Manager) {
+    registry := manager.Registry()
+    current := registry.Settings().RouterOrEmpty()
+    rules := make([]*config.RouteRule, len(current.Rules))
+    for i, rule := range current.Rules {
+        copied := *rule
+        rules[i] = &copied
+    }
+    labels, ids := profileOptions(manager)
+    profileLabel := func(id string) string {
+        if i := slices.Index(ids, id); i >= 0 {
+            return labels[i]
+        }
+        return ""
+    }
+    fallbackSelect := widget.NewSelect(labels, nil)
+    chooserCheck := widget.NewCheck("没有规则匹配时让我选择配置", nil)
+    // router 返回对话框中正在编辑的路由
+    router := func() *config.Router {
+        r := &config.Router{Rules: rules, Chooser: chooserCheck.Checked}
+        if i := fallbackSelect.SelectedIndex(); i >= 0 && ids[i] != config.DefaultChromeConfigID {
+            r.Fallback = ids[i]
+        }
+        return r
+    }
+
+    ruleBox := container.NewVBox()
+    var refreshRules func()
+    refreshRules = func() {
+        ruleBox.RemoveAll()
+        if len(rules) == 0 {
+            ruleBox.Add(widget.NewLabel("暂无规则，所有链接都在后备配置中打开。"))
+        }
+        for i, rule := range rules {
+            kindSelect := widget.NewSelect(matchKindLabels, func(label string) {
+                rule.Match = config.MatchKinds[slices.Index(matchKindLabels, label)]
+            })
+            if k := slices.Index(config.MatchKinds, rule.Match); k >= 0 {
+                kindSelect.SetSelectedIndex(k)
+            }
+            patternEntry := widget.NewEntry()
+            patternEntry.SetPlaceHolder("*.example.com")
+            patternEntry.SetText(rule.Pattern)
+            patternEntry.OnChanged = func(text string) { rule.Pattern = strings.TrimSpace(text) }
+            profileSelect := widget.NewSelect(labels, func(label string) {
+                rule.Profile = ids[slices.Index(labels, label)]
+            })
+            if label := profileLabel(rule.Profile); label != "" {
+                profileSelect.SetSelected(label)
+            }
+            upButton := widget.NewButton("↑", func() {
+                rules[i-1], rules[i] = rules[i], rules[i-1]
+                refreshRules()
+            })
+            if i == 0 {
+                upButton.Disable()
+            }
+            downButton := widget.NewButton("↓", func() {
+                rules[i], rules[i+1] = rules[i+1], rules[i]
+                refreshRules()
+            })
+            if i == len(rules)-1 {
+                downButton.Disable()
+            }
+            removeButton := widget.NewButton("删除", func() {
+                rules = slices.Delete(rules, i, i+1)
+                refreshRules()
+            })
+            controls := container.NewHBox(profileSelect, upButton, downButton, removeButton)
+            ruleBox.Add(container.NewBorder(nil, nil, kindSelect, controls, patternEntry))
+        }
+        ruleBox.Refresh()
+    }
+    refreshRules()
+    addButton := widget.NewButton("添加规则", func() {
+        rules = append(rules, &config.RouteRule{Match: config.MatchDomain})
+        refreshRules()
+    })
+
+    fallbackSelect.SetSelected(profileLabel(config.DefaultChromeConfigID))
+    if label := profileLabel(current.Fallback); label != "" {
+        fallbackSelect.SetSelected(label)
+    }
+    chooserCheck.SetChecked(current.Chooser)
+
+    // 用正在编辑的规则测试网址会在哪个配置中打开
+    testResult := widget.NewLabel("")
+    testEntry := widget.NewEntry()
+    testEntry.SetPlaceHolder("输入网址测试规则，例如 https://mail.example.com")
+    testEntry.OnChanged = func(text string) {
+        if strings.TrimSpace(text) == "" {
+            testResult.SetText("")
+            return
+        }
+        r := router()
+        if err := config.ValidateRouter(r); err != nil {
+            testResult.SetText("规则无效: " + err.Error())
+            return
+        }
+        var configs []*config.ChromeConfig
+        for _, instance := range manager.Instances() {
+            configs = append(configs, instance.Config())
+        }
+        cfg, rule := r.Route(configs, strings.TrimSpace(text), "")
+        switch {
+        case r.NeedsChooser(rule):
+            testResult.SetText("没有规则匹配，让用户选择配置")
+        case cfg == nil:
+            testResult.SetText("没有可用的配置")
+        case rule < 0:
+            testResult.SetText("没有规则匹配，在后备配置 " + cfg.String() + " 中打开")
+        default:
+            testResult.SetText(fmt.Sprintf("匹配第 %d 条规则，在 %s 中打开", rule+1, cfg))
+        }
+    }
+
+    hint := widget.NewLabel("规则从上到下依次匹配，第一条匹配的规则决定打开链接的配置。域名和来源程序使用通配符（*.example.com 同时匹配 example.com），正则表达式匹配完整的网址。")
+    hint.Wrapping = fyne.TextWrapWord
+
+    var defaultBrowserRow fyne.CanvasObject = container.NewVBox()
+    if runtime.GOOS == "linux" {
+        defaultBrowserLabel := widget.NewLabel("")
+        var defaultBrowserButton *widget.Button
+        refreshDefaultBrowser := func() {
+            if xdg.IsDefaultBrowser() {
+                defaultBrowserLabel.SetText("本程序是默认浏览器")
+                defaultBrowserButton.SetText("取消默认浏览器")
+            } else {
+                defaultBrowserLabel.SetText("本程序不是默认浏览器")
+                defaultBrowserButton.SetText("设为默认浏览器")
+            }
+        }
+        defaultBrowserButton = widget.NewButton("", func() {
+            var err error
+            if xdg.IsDefaultBrowser() {
+                err = xdg.UnregisterBrowser()
+            } else if executable, exeErr := os.Executable(); exeErr != nil {
+                err = exeErr
+            } else {
+                err = xdg.RegisterBrowser(executable)
+            }
+            if err != nil {
+                log.Printf("修改默认浏览器失败: %v", err)
+                dialog.ShowError(err, w)
+            }
+            refreshDefaultBrowser()
+        })
+        refreshDefaultBrowser()
+        defaultBrowserRow = container.NewBorder(nil, nil, nil, defaultBrowserButton, defaultBrowserLabel)
+    }
+
+    form := container.NewVBox(
+        hint,
+        container.NewBorder(nil, nil, nil, addButton, widget.NewLabel("规则：")),
+        ruleBox,
+        widget.NewSeparator(),
+        container.NewBorder(nil, nil, widget.NewLabel("后备配置："), nil, fallbackSelect),
+        chooserCheck,
+        testEntry,
+        testResult,
+        widget.NewSeparator(),
+        defaultBrowserRow,
+    )
+    d := dialog.NewCustomConfirm("链接路由", "保存", "取消", container.NewVScroll(form), func(save bool) {
+        if !save {
+            return
+        }
+        err := registry.UpdateSettings(func(settings *config.Settings) error {
+            return config.SetRouter(settings, router())
+        })
+        if err != nil {
+            log.Printf("保存链接路由失败: %v", err)
+            dialog.ShowError(err, w)
+            return
+        }
+        log.Printf("链接路由已更新: %d 条规则", len(rules))
+    }, w)
+    d.Resize(fyne.NewSize(750, 550))
+    d.Show()
+}
+
+// showChooserDialog 在没有规则匹配时让用户选择打开网址的配置，可以记住选择，为网址的域名添加规则。
+func showChooserDialog(w fyne.Window, manager *chrome.Manager, urls []string) {
+    w.Show()
+    w.RequestFocus()
+
+    labels, ids := profileOptions(manager)
+    profileSelect := widget.NewSelect(labels, nil)
+    router := manager.Registry().Settings().RouterOrEmpty()
+    profileSelect.SetSelectedIndex(max(slices.Index(ids, config.DefaultChromeConfigID), 0))
+    if i := slices.Index(ids, router.Fallback); i >= 0 {
+        profileSelect.SetSelectedIndex(i)
+    }
+
+    urlLabel := widget.NewLabel(strings.Join(urls, "\n"))
+    urlLabel.Wrapping = fyne.TextWrapBreak
+    rememberCheck := widget.NewCheck("记住选择：以后这些域名的链接都在此配置中打开", nil)
+
+    dialog.ShowCustomConfirm("选择打开链接的配置", "打开", "取消", container.NewVBox(urlLabel, profileSelect, rememberCheck), func(open bool) {
+        if !open || profileSelect.SelectedIndex() < 0 {
+            log.Printf("已取消打开链接: %v", urls)
+            return
+        }
+        instance := manager.Get(ids[profileSelect.SelectedIndex()])
+        if instance == nil {
+            return
+        }
+        if rememberCheck.Checked {
+            var hosts []string
+            err := manager.Registry().UpdateSettings(func(settings *config.Settings) error {
+                updated := settings.RouterOrEmpty()
+                for _, rawURL := range urls {
+                    rule := config.DomainRule(rawURL, instance.Config().ID)
+                    if rule == nil || slices.Contains(hosts, rule.Pattern) {
+                        continue
+                    }
+                    hosts = append(hosts, rule.Pattern)
+                    updated = updated.WithRule(rule)
+                }
+                return config.SetRouter(settings, updated)
+            })
+            if err != nil {
+                log.Printf("保存链接路由失败: %v", err)
+                dialog.ShowError(err, w)
+            } else {
+                log.Printf("已为 %v 添加链接路由规则: %s", hosts, instance.Config())
+            }
+        }
+        go func() {
+            err := instance.Open(urls...)
+            if err != nil {
+                log.Printf("在 %s 中打开链接失败: %v", instance.Config(), err)
+                fyne.Do(func() { dialog.ShowError(err, w) })
+            }
+        }()
+    }, w)
+}
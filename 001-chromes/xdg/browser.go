@@ -0,0 +1,43 @@
+package xdg
+
+// BrowserDesktopID 是把管理器注册为默认浏览器时使用的 .desktop 文件名。
+const BrowserDesktopID = "chromes-router.desktop"
+
+// BrowserMimeTypes 是默认浏览器负责的 MIME 类型和网址协议。
+var BrowserMimeTypes = []string{
+    "x-scheme-handler/http",
+    "x-scheme-handler/https",
+    "text/html",
+    "application/xhtml+xml",
+}
+
+// RegisterBrowser 把 executable 注册为当前用户的默认浏览器：
+// 写入以 "open -- %U" 调用它的 .desktop 文件，并在 mimeapps.list 中设为网址和网页的默认程序。
+func RegisterBrowser(executable string) error {
+    entry := &Entry{
+        Name:       "Chromes 链接路由",
+        Comment:    "按规则在对应的 Chrome 配置中打开链接",
+        Exec:       []string{executable, "open", "--", "%U"},
+        Icon:       "web-browser",
+        MimeTypes:  BrowserMimeTypes,
+        Categories: []string{"Network", "WebBrowser"},
+        NoDisplay:  true, // 只作为默认程序使用，不出现在应用菜单中
+    }
+    if _, err := WriteEntry(BrowserDesktopID, entry); err != nil {
+        return err
+    }
+    return SetDefaults(BrowserDesktopID, BrowserMimeTypes)
+}
+
+// UnregisterBrowser 取消默认浏览器的注册，删除 .desktop 文件和 mimeapps.list 中对应的设置。
+func UnregisterBrowser() error {
+    if err := UnsetDefaults(BrowserDesktopID, BrowserMimeTypes); err != nil {
+        return err
+    }
+    return RemoveEntry(BrowserDesktopID)
+}
+
+// IsDefaultBrowser 返回管理器是否为当前用户 http 网址的默认程序。
+func IsDefaultBrowser() bool {
+    return IsDefault(BrowserDesktopID, BrowserMimeTypes[0])
+}
@@ -0,0 +1,73 @@
+package xdg
+
+import (
+    "fmt"
+    "hash/fnv"
+    "html"
+    "math"
+    "strings"
+    "unicode"
+
+    "chromes/config"
+)
+
+// ProfileIcon 返回配置的启动器图标：以配置 ID 决定颜色的圆角方块，上面是配置名称的首字母。
+// 颜色只取决于 ID，重命名后颜色不变，只有文字随名称更新。
+func ProfileIcon(cfg *config.ChromeConfig) []byte {
+    initials := Initials(cfg.Name)
+    fontSize := 128
+    if len([]rune(initials)) > 1 {
+        fontSize = 104
+    }
+    return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256" viewBox="0 0 256 256">
+  <rect x="8" y="8" width="240" height="240" rx="48" fill="%s"/>
+  <text x="128" y="128" dy=".35em" text-anchor="middle" font-family="sans-serif" font-weight="bold" font-size="%d" fill="#ffffff">%s</text>
+</svg>
+`, ProfileColor(cfg.ID), fontSize, html.EscapeString(initials)))
+}
+
+// Initials 返回名称的首字母：多个单词时取前两个单词的首字符，否则取第一个字符，均转为大写。
+func Initials(name string) string {
+    words := strings.FieldsFunc(name, func(r rune) bool {
+        return unicode.IsSpace(r) || r == '-' || r == '_' || r == '.'
+    })
+    if len(words) == 0 {
+        return "?"
+    }
+    var initials []rune
+    for _, word := range words[:min(len(words), 2)] {
+        initials = append(initials, unicode.ToUpper([]rune(word)[0]))
+    }
+    return string(initials)
+}
+
+// ProfileColor 返回由配置 ID 的哈希值决定色相的颜色（"#rrggbb"），饱和度和亮度固定以保证白色文字清晰。
+func ProfileColor(id string) string {
+    h := fnv.New32a()
+    h.Write([]byte(id))
+    r, g, b := hslToRGB(float64(h.Sum32()%360), 0.55, 0.45)
+    return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// hslToRGB 把色相（0-360）、饱和度和亮度（0-1）转换为 RGB。
+func hslToRGB(hue, saturation, lightness float64) (uint8, uint8, uint8) {
+    c := (1 - math.Abs(2*lightness-1)) * saturation
+    x := c * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+    m := lightness - c/2
+    var r, g, b float64
+    switch {
+    case hue < 60:
+        r, g, b = c, x, 0
+    case hue < 120:
+        r, g, b = x, c, 0
+    case hue < 180:
+        r, g, b = 0, c, x
+    case hue < 240:
+        r, g, b = 0, x, c
+    case hue < 300:
+        r, g, b = x, 0, c
+    default:
+        r, g, b = c, 0, x
+    }
+    return uint8(math.Round((r + m) * 255)), uint8(math.Round((g + m) * 255)), uint8(math.Round((b + m) * 255))
+}
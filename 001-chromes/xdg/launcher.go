@@ -0,0 +1,113 @@
+package xdg
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "chromes/config"
+)
+
+// launcherPrefix 是配置启动器的 .desktop 文件名和图标文件名的前缀，用于识别由本程序生成的启动器。
+const launcherPrefix = "chromes-profile-"
+
+// LauncherID 返回配置的启动器的 .desktop 文件名。文件名只包含 ID，配置重命名后仍是同一个文件。
+func LauncherID(cfg *config.ChromeConfig) string {
+    return launcherPrefix + cfg.ID + ".desktop"
+}
+
+// IconsDir 返回启动器图标所在的目录。
+func IconsDir() string {
+    return filepath.Join(DataHome(), "chromes", "icons")
+}
+
+// launcherEntry 返回通过 executable 的 launch 命令启动配置的 .desktop 内容。
+func launcherEntry(cfg *config.ChromeConfig, executable string, iconPath string) *Entry {
+    return &Entry{
+        Name:           cfg.Name,
+        Comment:        fmt.Sprintf("以配置 %s 启动 Chrome（Chromes）", cfg.Name),
+        Exec:           []string{executable, "launch", cfg.ID},
+        Icon:           iconPath,
+        StartupWMClass: cfg.WindowClass(),
+        Categories:     []string{"Network", "WebBrowser"},
+    }
+}
+
+// SyncLaunchers 使当前用户应用目录中的启动器与 configs 一致：为每个配置（默认实例除外）生成
+// 通过 executable 启动它的 .desktop 文件和首字母图标，内容有变化（例如重命名）时更新，并删除已删除配置的启动器。
+// 内容没有变化的文件不会被重写。
+func SyncLaunchers(configs []*config.ChromeConfig, executable string) error {
+    if err := checkSupported(); err != nil {
+        return err
+    }
+    dir := ApplicationsDir()
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return fmt.Errorf("failed to create %s: %w", dir, err)
+    }
+    if err := os.MkdirAll(IconsDir(), 0755); err != nil {
+        return fmt.Errorf("failed to create %s: %w", IconsDir(), err)
+    }
+
+    var errs []error
+    changed := false
+    keep := make(map[string]bool)
+    for _, cfg := range configs {
+        if cfg.IsDefault {
+            continue
+        }
+        id := LauncherID(cfg)
+        keep[id] = true
+        iconPath := launcherIconPath(id)
+        for path, data := range map[string][]byte{
+            iconPath:               ProfileIcon(cfg),
+            filepath.Join(dir, id): []byte(launcherEntry(cfg, executable, iconPath).String()),
+        } {
+            written, err := writeIfChanged(path, data)
+            if err != nil {
+                errs = append(errs, err)
+            }
+            changed = changed || written
+        }
+    }
+
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        errs = append(errs, err)
+    }
+    for _, entry := range entries {
+        id := entry.Name()
+        if !strings.HasPrefix(id, launcherPrefix) || !strings.HasSuffix(id, ".desktop") || keep[id] {
+            continue
+        }
+        for _, path := range []string{filepath.Join(dir, id), launcherIconPath(id)} {
+            if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+                errs = append(errs, err)
+            }
+        }
+        changed = true
+    }
+
+    if changed {
+        updateDesktopDatabase(dir)
+    }
+    return errors.Join(errs...)
+}
+
+// launcherIconPath 返回启动器对应的图标文件路径。
+func launcherIconPath(id string) string {
+    return filepath.Join(IconsDir(), strings.TrimSuffix(id, ".desktop")+".svg")
+}
+
+// writeIfChanged 在文件内容与 data 不同时写入，返回是否写入了文件。
+func writeIfChanged(path string, data []byte) (bool, error) {
+    if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, data) {
+        return false, nil
+    }
+    if err := config.WriteFileAtomic(path, data, 0644); err != nil {
+        return false, fmt.Errorf("failed to write %s: %w", path, err)
+    }
+    return true, nil
+}
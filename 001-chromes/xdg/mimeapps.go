@@ -0,0 +1,131 @@
+package xdg
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "slices"
+    "strings"
+
+    "chromes/config"
+)
+
+// defaultApplications 是 mimeapps.list 中保存默认程序的组。
+const defaultApplications = "[Default Applications]"
+
+// MimeAppsPath 返回当前用户的 mimeapps.list 路径。
+func MimeAppsPath() string {
+    return filepath.Join(ConfigHome(), "mimeapps.list")
+}
+
+// SetDefaults 在 mimeapps.list 中把 desktopID 设为 mimeTypes 的默认程序，其他内容保持不变。
+func SetDefaults(desktopID string, mimeTypes []string) error {
+    return updateMimeApps(func(lines []string) []string {
+        return setDefaults(lines, mimeTypes, desktopID)
+    })
+}
+
+// UnsetDefaults 从 mimeapps.list 中 mimeTypes 的程序列表里删除 desktopID，列表为空的行整行删除，
+// 之后由桌面环境按系统的设置选择默认程序。
+func UnsetDefaults(desktopID string, mimeTypes []string) error {
+    return updateMimeApps(func(lines []string) []string {
+        updated := lines[:0]
+        for _, line := range lines {
+            key, value, ok := strings.Cut(line, "=")
+            if ok && slices.Contains(mimeTypes, strings.TrimSpace(key)) {
+                ids := slices.DeleteFunc(strings.Split(value, ";"), func(id string) bool {
+                    id = strings.TrimSpace(id)
+                    return id == "" || id == desktopID
+                })
+                if len(ids) == 0 {
+                    continue
+                }
+                line = key + "=" + strings.Join(ids, ";") + ";"
+            }
+            updated = append(updated, line)
+        }
+        return updated
+    })
+}
+
+// IsDefault 返回 desktopID 是否为 mimeType 的默认程序。只检查当前用户的 mimeapps.list。
+func IsDefault(desktopID string, mimeType string) bool {
+    data, err := os.ReadFile(MimeAppsPath())
+    if err != nil {
+        return false
+    }
+    inDefaults := false
+    for _, line := range strings.Split(string(data), "\n") {
+        line = strings.TrimSpace(line)
+        if strings.HasPrefix(line, "[") {
+            inDefaults = line == defaultApplications
+            continue
+        }
+        key, value, ok := strings.Cut(line, "=")
+        if inDefaults && ok && strings.TrimSpace(key) == mimeType {
+            first, _, _ := strings.Cut(value, ";")
+            return strings.TrimSpace(first) == desktopID
+        }
+    }
+    return false
+}
+
+// updateMimeApps 读取 mimeapps.list 的各行，交给 update 修改后写回。
+func updateMimeApps(update func(lines []string) []string) error {
+    if err := checkSupported(); err != nil {
+        return err
+    }
+    path := MimeAppsPath()
+    data, err := os.ReadFile(path)
+    if err != nil && !os.IsNotExist(err) {
+        return fmt.Errorf("failed to read %s: %w", path, err)
+    }
+    var lines []string
+    if text := strings.TrimRight(string(data), "\n"); text != "" {
+        lines = strings.Split(text, "\n")
+    }
+    lines = update(lines)
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return err
+    }
+    if err := config.WriteFileAtomic(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+        return fmt.Errorf("failed to write %s: %w", path, err)
+    }
+    return nil
+}
+
+// setDefaults 在 [Default Applications] 组中设置 mimeTypes 的默认程序，组不存在时追加到末尾。
+func setDefaults(lines []string, mimeTypes []string, desktopID string) []string {
+    start := slices.IndexFunc(lines, func(line string) bool { return strings.TrimSpace(line) == defaultApplications })
+    if start < 0 {
+        if len(lines) > 0 {
+            lines = append(lines, "")
+        }
+        lines = append(lines, defaultApplications)
+        start = len(lines) - 1
+    }
+    end := start + 1
+    for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), "[") {
+        end++
+    }
+
+    for _, mimeType := range mimeTypes {
+        entry := mimeType + "=" + desktopID + ";"
+        i := slices.IndexFunc(lines[start+1:end], func(line string) bool {
+            key, _, ok := strings.Cut(line, "=")
+            return ok && strings.TrimSpace(key) == mimeType
+        })
+        if i >= 0 {
+            lines[start+1+i] = entry
+            continue
+        }
+        // 插入到组中最后一个非空行之后，保留组之间的空行
+        at := end
+        for at > start+1 && strings.TrimSpace(lines[at-1]) == "" {
+            at--
+        }
+        lines = slices.Insert(lines, at, entry)
+        end++
+    }
+    return lines
+}
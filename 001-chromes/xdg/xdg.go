@@ -0,0 +1,154 @@
+// Package xdg 按 freedesktop.org 的规范在 Linux 桌面上注册程序：
+// 写入 .desktop 启动文件（Desktop Entry Specification）并修改 mimeapps.list 中的默认程序（MIME Applications Associations）。
+package xdg
+
+import (
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "runtime"
+    "strings"
+
+    "chromes/config"
+)
+
+// DataHome 返回 $XDG_DATA_HOME，未设置时为 ~/.local/share。
+func DataHome() string {
+    if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+        return dir
+    }
+    home, _ := os.UserHomeDir()
+    return filepath.Join(home, ".local", "share")
+}
+
+// ConfigHome 返回 $XDG_CONFIG_HOME，未设置时为 ~/.config。
+func ConfigHome() string {
+    if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+        return dir
+    }
+    home, _ := os.UserHomeDir()
+    return filepath.Join(home, ".config")
+}
+
+// ApplicationsDir 返回当前用户的 .desktop 文件目录。
+func ApplicationsDir() string {
+    return filepath.Join(DataHome(), "applications")
+}
+
+// Supported 返回当前系统的桌面是否使用 XDG 规范（Linux 和 BSD 等）。
+func Supported() bool {
+    switch runtime.GOOS {
+    case "windows", "darwin", "ios", "android", "js", "wasip1", "plan9":
+        return false
+    }
+    return true
+}
+
+// checkSupported 在不使用 XDG 规范的系统上返回错误。
+func checkSupported() error {
+    if !Supported() {
+        return fmt.Errorf("desktop integration is not supported on %s", runtime.GOOS)
+    }
+    return nil
+}
+
+// Entry 是一个 .desktop 文件中 [Desktop Entry] 组的内容。
+type Entry struct {
+    Name           string
+    Comment        string
+    Exec           []string // 命令行，每项是一个参数，字段代码（如 %U）原样写入
+    Icon           string
+    StartupWMClass string
+    MimeTypes      []string
+    Categories     []string
+    NoDisplay      bool
+}
+
+// String 返回 .desktop 文件的内容。
+func (e *Entry) String() string {
+    var b strings.Builder
+    b.WriteString("[Desktop Entry]\nType=Application\nVersion=1.0\n")
+    fmt.Fprintf(&b, "Name=%s\n", escapeValue(e.Name))
+    if e.Comment != "" {
+        fmt.Fprintf(&b, "Comment=%s\n", escapeValue(e.Comment))
+    }
+    args := make([]string, len(e.Exec))
+    for i, arg := range e.Exec {
+        args[i] = quoteExecArg(arg)
+    }
+    fmt.Fprintf(&b, "Exec=%s\n", escapeValue(strings.Join(args, " ")))
+    if e.Icon != "" {
+        fmt.Fprintf(&b, "Icon=%s\n", escapeValue(e.Icon))
+    }
+    if e.StartupWMClass != "" {
+        fmt.Fprintf(&b, "StartupWMClass=%s\n", escapeValue(e.StartupWMClass))
+    }
+    if len(e.MimeTypes) > 0 {
+        fmt.Fprintf(&b, "MimeType=%s;\n", strings.Join(e.MimeTypes, ";"))
+    }
+    if len(e.Categories) > 0 {
+        fmt.Fprintf(&b, "Categories=%s;\n", strings.Join(e.Categories, ";"))
+    }
+    if e.NoDisplay {
+        b.WriteString("NoDisplay=true\n")
+    }
+    b.WriteString("Terminal=false\n")
+    return b.String()
+}
+
+// escapeValue 转义字符串值中的换行、制表符和反斜杠。
+func escapeValue(s string) string {
+    return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(s)
+}
+
+// quoteExecArg 按 Exec 键的规则给参数加引号：含有保留字符时用双引号包围，并转义其中的 "、`、$ 和 \。
+// 以 % 开头的字段代码不加引号。
+func quoteExecArg(arg string) string {
+    if strings.HasPrefix(arg, "%") && len(arg) == 2 {
+        return arg
+    }
+    if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`") {
+        return strings.ReplaceAll(arg, "%", "%%")
+    }
+    escaped := strings.NewReplacer(`"`, `\"`, "`", "\\`", `$`, `\$`, `\`, `\\`).Replace(arg)
+    return `"` + strings.ReplaceAll(escaped, "%", "%%") + `"`
+}
+
+// WriteEntry 把 .desktop 文件写入当前用户的应用目录，id 是文件名（例如 "chromes-router.desktop"），返回文件路径。
+// 写入后尽力刷新桌面数据库，使 MimeType 等信息立即生效。
+func WriteEntry(id string, entry *Entry) (string, error) {
+    if err := checkSupported(); err != nil {
+        return "", err
+    }
+    dir := ApplicationsDir()
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return "", fmt.Errorf("failed to create %s: %w", dir, err)
+    }
+    path := filepath.Join(dir, id)
+    if err := config.WriteFileAtomic(path, []byte(entry.String()), 0644); err != nil {
+        return "", fmt.Errorf("failed to write %s: %w", path, err)
+    }
+    updateDesktopDatabase(dir)
+    return path, nil
+}
+
+// RemoveEntry 删除当前用户应用目录中的 .desktop 文件，文件不存在时不报错。
+func RemoveEntry(id string) error {
+    if err := checkSupported(); err != nil {
+        return err
+    }
+    dir := ApplicationsDir()
+    if err := os.Remove(filepath.Join(dir, id)); err != nil && !os.IsNotExist(err) {
+        return err
+    }
+    updateDesktopDatabase(dir)
+    return nil
+}
+
+// updateDesktopDatabase 运行 update-desktop-database 刷新 MIME 类型缓存，命令不存在或失败时忽略。
+func updateDesktopDatabase(dir string) {
+    if path, err := exec.LookPath("update-desktop-database"); err == nil {
+        exec.Command(path, dir).Run()
+    }
+}